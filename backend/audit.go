@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"amestris-backend/config"
+	"amestris-backend/handlers"
+	"amestris-backend/models"
+	"amestris-backend/repository/postgres"
+)
+
+func runAudit(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 || args[0] != "verify" {
+		return fmt.Errorf("uso: main audit verify")
+	}
+
+	store := postgres.NewStore(models.DB)
+	audit := handlers.NewAuditHandler(store.Audits, store.Experiments, store.Transmutations)
+
+	created, err := audit.RunChecks(ctx, cfg.Audit)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Verificación de auditoría completada: %d registros generados\n", created)
+	return nil
+}
@@ -0,0 +1,776 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// Config agrupa toda la configuración del backend. Se carga con Load a partir
+// de valores por defecto, un archivo opcional (YAML o TOML) y variables de
+// entorno, en ese orden de prioridad creciente.
+type Config struct {
+	Server       ServerConfig       `yaml:"server" toml:"server"`
+	Database     DatabaseConfig     `yaml:"database" toml:"database"`
+	JWT          JWTConfig          `yaml:"jwt" toml:"jwt"`
+	CORS         CORSConfig         `yaml:"cors" toml:"cors"`
+	Audit        AuditConfig        `yaml:"audit" toml:"audit"`
+	Security     SecurityConfig     `yaml:"security" toml:"security"`
+	Log          LogConfig          `yaml:"log" toml:"log"`
+	RateLimit    RateLimitConfig    `yaml:"rate_limit" toml:"rate_limit"`
+	Registration RegistrationConfig `yaml:"registration" toml:"registration"`
+	OIDC         OIDCConfig         `yaml:"oidc" toml:"oidc"`
+}
+
+// ServerConfig define los puertos del servidor. MetricsAddr sirve /metrics
+// aparte de la API para no exponerlo públicamente; vacío lo desactiva.
+type ServerConfig struct {
+	Addr             string   `yaml:"addr" toml:"addr"`
+	MetricsAddr      string   `yaml:"metrics_addr" toml:"metrics_addr"`
+	ShutdownTimeout  Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	ReadinessTimeout Duration `yaml:"readiness_timeout" toml:"readiness_timeout"`
+}
+
+type DatabaseConfig struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	Name     string `yaml:"name" toml:"name"`
+	SSLMode  string `yaml:"sslmode" toml:"sslmode"`
+	TimeZone string `yaml:"timezone" toml:"timezone"`
+}
+
+// JWTConfig define la emisión de tokens. TTL es la vida del access token y
+// RefreshTTL la de cada token de renovación (se rota en cada uso).
+//
+// Con Keys los tokens se firman con claves asimétricas publicadas en
+// /.well-known/jwks.json y Secret no se usa; sin Keys se firman con HS256 y
+// Secret. KeyGrace es cuánto se siguen aceptando los tokens de una clave
+// después de que otra la sustituya.
+type JWTConfig struct {
+	Secret     string   `yaml:"secret" toml:"secret"`
+	TTL        Duration `yaml:"ttl" toml:"ttl"`
+	RefreshTTL Duration `yaml:"refresh_ttl" toml:"refresh_ttl"`
+	Keys       []JWTKey `yaml:"keys" toml:"keys"`
+	KeyGrace   Duration `yaml:"key_grace" toml:"key_grace"`
+}
+
+// JWTKey es una clave privada PEM (RSA para RS256, Ed25519 para EdDSA) que
+// firma a partir de ActiveFrom, hasta que se activa otra posterior. Sin
+// ActiveFrom está activa desde siempre. En variables de entorno se escribe
+// "id=archivo[@fecha RFC 3339]" y las claves se separan por comas.
+type JWTKey struct {
+	ID         string    `yaml:"id" toml:"id"`
+	File       string    `yaml:"file" toml:"file"`
+	ActiveFrom time.Time `yaml:"active_from" toml:"active_from"`
+}
+
+func (k *JWTKey) UnmarshalText(text []byte) error {
+	id, rest, ok := strings.Cut(string(text), "=")
+	if !ok {
+		return fmt.Errorf("formato esperado id=archivo[@fecha]")
+	}
+
+	key := JWTKey{ID: strings.TrimSpace(id)}
+	file, activeFrom, scheduled := strings.Cut(rest, "@")
+	key.File = strings.TrimSpace(file)
+	if scheduled {
+		parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(activeFrom))
+		if err != nil {
+			return err
+		}
+		key.ActiveFrom = parsed
+	}
+
+	*k = key
+	return nil
+}
+
+// CORSConfig define la política CORS. AllowedOrigins admite orígenes exactos
+// ("https://app.amestris.gov"), comodines de subdominio
+// ("https://*.amestris.gov") o "*" para cualquiera.
+type CORSConfig struct {
+	AllowedOrigins   []string `yaml:"allowed_origins" toml:"allowed_origins"`
+	AllowedMethods   []string `yaml:"allowed_methods" toml:"allowed_methods"`
+	AllowedHeaders   []string `yaml:"allowed_headers" toml:"allowed_headers"`
+	ExposedHeaders   []string `yaml:"exposed_headers" toml:"exposed_headers"`
+	AllowCredentials bool     `yaml:"allow_credentials" toml:"allow_credentials"`
+	MaxAge           Duration `yaml:"max_age" toml:"max_age"`
+}
+
+type AuditConfig struct {
+	Interval       Duration `yaml:"interval" toml:"interval"`
+	FrequentWindow Duration `yaml:"frequent_window" toml:"frequent_window"`
+	FrequentLimit  int      `yaml:"frequent_limit" toml:"frequent_limit"`
+}
+
+// RateLimitConfig define el almacenamiento de los límites ("memory" o
+// "postgres") y la regla de cada grupo de rutas. Un grupo con Requests 0
+// queda sin límite.
+type RateLimitConfig struct {
+	Backend   string        `yaml:"backend" toml:"backend"`
+	Login     RateLimitRule `yaml:"login" toml:"login"`
+	Transmute RateLimitRule `yaml:"transmute" toml:"transmute"`
+}
+
+// RateLimitRule permite Requests peticiones por Period, con ráfagas de hasta
+// Burst (por defecto Requests). En variables de entorno se escribe
+// "requests/periodo[,burst]", por ejemplo "5/1m" o "30/1m,10".
+type RateLimitRule struct {
+	Requests int      `yaml:"requests" toml:"requests"`
+	Period   Duration `yaml:"period" toml:"period"`
+	Burst    int      `yaml:"burst" toml:"burst"`
+}
+
+func (r *RateLimitRule) UnmarshalText(text []byte) error {
+	spec, burst, hasBurst := strings.Cut(string(text), ",")
+	requests, period, ok := strings.Cut(spec, "/")
+	if !ok {
+		return fmt.Errorf("formato esperado requests/periodo[,burst]")
+	}
+
+	var rule RateLimitRule
+	var err error
+	if rule.Requests, err = strconv.Atoi(strings.TrimSpace(requests)); err != nil {
+		return err
+	}
+	if err := rule.Period.UnmarshalText([]byte(strings.TrimSpace(period))); err != nil {
+		return err
+	}
+	if hasBurst {
+		if rule.Burst, err = strconv.Atoi(strings.TrimSpace(burst)); err != nil {
+			return err
+		}
+	}
+
+	*r = rule
+	return nil
+}
+
+// RegistrationConfig controla el alta de usuarios. Con Open el registro sin
+// invitación está permitido pero la cuenta queda pendiente de aprobación y
+// con DefaultRole. Los enlaces de invitación son InviteURL seguido del token.
+type RegistrationConfig struct {
+	Open        bool     `yaml:"open" toml:"open"`
+	DefaultRole string   `yaml:"default_role" toml:"default_role"`
+	InviteTTL   Duration `yaml:"invite_ttl" toml:"invite_ttl"`
+	InviteURL   string   `yaml:"invite_url" toml:"invite_url"`
+}
+
+// OIDCConfig activa el inicio de sesión con el proveedor de identidad de la
+// organización (authorization code con PKCE). RedirectURL es el callback del
+// backend registrado en el proveedor y FrontendURL la página del frontend
+// que recibe los tokens.
+//
+// Los usuarios se crean en su primer inicio de sesión. Su rol sale del claim
+// RoleClaim según RoleMapping (gana la primera coincidencia) o, si ninguna
+// coincide, DefaultRole; sin rol no pueden entrar. Si AlchemistClaim está
+// definido, su valor es el nombre del alquimista que se vincula al usuario.
+type OIDCConfig struct {
+	Enabled        bool              `yaml:"enabled" toml:"enabled"`
+	Issuer         string            `yaml:"issuer" toml:"issuer"`
+	ClientID       string            `yaml:"client_id" toml:"client_id"`
+	ClientSecret   string            `yaml:"client_secret" toml:"client_secret"`
+	RedirectURL    string            `yaml:"redirect_url" toml:"redirect_url"`
+	FrontendURL    string            `yaml:"frontend_url" toml:"frontend_url"`
+	Scopes         []string          `yaml:"scopes" toml:"scopes"`
+	UsernameClaim  string            `yaml:"username_claim" toml:"username_claim"`
+	RoleClaim      string            `yaml:"role_claim" toml:"role_claim"`
+	RoleMapping    []OIDCRoleMapping `yaml:"role_mapping" toml:"role_mapping"`
+	DefaultRole    string            `yaml:"default_role" toml:"default_role"`
+	AlchemistClaim string            `yaml:"alchemist_claim" toml:"alchemist_claim"`
+}
+
+// OIDCRoleMapping asigna Role a los usuarios cuyo claim de rol contiene
+// Value. En variables de entorno se escribe "valor=rol" y las
+// correspondencias se separan por comas.
+type OIDCRoleMapping struct {
+	Value string `yaml:"value" toml:"value"`
+	Role  string `yaml:"role" toml:"role"`
+}
+
+// Roles devuelve los roles que puede asignar la configuración OIDC.
+func (o OIDCConfig) Roles() []string {
+	var roles []string
+	for _, mapping := range o.RoleMapping {
+		roles = append(roles, mapping.Role)
+	}
+	if o.DefaultRole != "" {
+		roles = append(roles, o.DefaultRole)
+	}
+	return roles
+}
+
+func (m *OIDCRoleMapping) UnmarshalText(text []byte) error {
+	value, role, ok := strings.Cut(string(text), "=")
+	if !ok {
+		return fmt.Errorf("formato esperado valor=rol")
+	}
+	*m = OIDCRoleMapping{Value: strings.TrimSpace(value), Role: strings.TrimSpace(role)}
+	return nil
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level" toml:"level"`
+	Format string `yaml:"format" toml:"format"`
+}
+
+// SecurityConfig agrupa las políticas de autenticación. LocalLogin permite
+// iniciar sesión con usuario y contraseña; se puede desactivar cuando todos
+// entran por OIDC.
+//
+// PasswordHash es el algoritmo de los hashes nuevos (argon2id o bcrypt).
+// Los hashes con otro algoritmo o parámetros se siguen aceptando y se
+// recalculan en el siguiente login correcto.
+type SecurityConfig struct {
+	LocalLogin   bool           `yaml:"local_login" toml:"local_login"`
+	PasswordHash string         `yaml:"password_hash" toml:"password_hash"`
+	Argon2       Argon2Config   `yaml:"argon2" toml:"argon2"`
+	BcryptCost   int            `yaml:"bcrypt_cost" toml:"bcrypt_cost"`
+	Password     PasswordConfig `yaml:"password" toml:"password"`
+	Lockout      LockoutConfig  `yaml:"lockout" toml:"lockout"`
+	MFA          MFAConfig      `yaml:"mfa" toml:"mfa"`
+}
+
+// Argon2Config son los parámetros de argon2id: memoria en KiB, iteraciones
+// y número de hilos.
+type Argon2Config struct {
+	MemoryKiB   int `yaml:"memory_kib" toml:"memory_kib"`
+	Iterations  int `yaml:"iterations" toml:"iterations"`
+	Parallelism int `yaml:"parallelism" toml:"parallelism"`
+}
+
+// MFAConfig define la verificación en dos pasos (TOTP). Los usuarios con un
+// rol de RequiredRoles deben activarla antes de usar la API; los roles se
+// comprueban contra la base de datos al arrancar el servidor. Issuer es el
+// nombre que muestran las apps de autenticación.
+type MFAConfig struct {
+	RequiredRoles []string `yaml:"required_roles" toml:"required_roles"`
+	Issuer        string   `yaml:"issuer" toml:"issuer"`
+}
+
+// LockoutConfig bloquea una cuenta tras Threshold intentos fallidos
+// seguidos. El primer bloqueo dura Duration y cada fallo adicional lo
+// duplica, hasta MaxDuration. Threshold 0 desactiva el bloqueo.
+type LockoutConfig struct {
+	Threshold   int      `yaml:"threshold" toml:"threshold"`
+	Duration    Duration `yaml:"duration" toml:"duration"`
+	MaxDuration Duration `yaml:"max_duration" toml:"max_duration"`
+}
+
+// PasswordConfig es la política que deben cumplir las contraseñas elegidas
+// por los usuarios.
+type PasswordConfig struct {
+	MinLength     int  `yaml:"min_length" toml:"min_length"`
+	RequireUpper  bool `yaml:"require_upper" toml:"require_upper"`
+	RequireLower  bool `yaml:"require_lower" toml:"require_lower"`
+	RequireDigit  bool `yaml:"require_digit" toml:"require_digit"`
+	RequireSymbol bool `yaml:"require_symbol" toml:"require_symbol"`
+}
+
+// Duration permite escribir duraciones como "15m" o "24h" en los archivos
+// de configuración.
+type Duration time.Duration
+
+func (d Duration) Std() time.Duration { return time.Duration(d) }
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// DSN construye la cadena de conexión de Postgres.
+func (d DatabaseConfig) DSN() string {
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=%s",
+		d.Host, d.User, d.Password, d.Name, d.Port, d.SSLMode, d.TimeZone)
+}
+
+// ValidationError enumera todas las claves ausentes o inválidas encontradas
+// al cargar la configuración.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "configuración inválida:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:             ":8080",
+			MetricsAddr:      ":9090",
+			ShutdownTimeout:  Duration(15 * time.Second),
+			ReadinessTimeout: Duration(2 * time.Second),
+		},
+		Database: DatabaseConfig{
+			Host:     "localhost",
+			Port:     5432,
+			SSLMode:  "disable",
+			TimeZone: "UTC",
+		},
+		JWT: JWTConfig{
+			TTL:        Duration(15 * time.Minute),
+			RefreshTTL: Duration(30 * 24 * time.Hour),
+			KeyGrace:   Duration(time.Hour),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Content-Type", "Authorization", "X-Request-ID"},
+			ExposedHeaders: []string{"ETag", "X-Request-ID", "Link", "X-Total-Count"},
+			MaxAge:         Duration(10 * time.Minute),
+		},
+		Audit: AuditConfig{
+			Interval:       Duration(5 * time.Minute),
+			FrequentWindow: Duration(time.Hour),
+			FrequentLimit:  10,
+		},
+		Security: SecurityConfig{
+			LocalLogin:   true,
+			PasswordHash: "argon2id",
+			Argon2:       Argon2Config{MemoryKiB: 64 * 1024, Iterations: 3, Parallelism: 4},
+			BcryptCost:   14,
+			Password: PasswordConfig{
+				MinLength:    10,
+				RequireUpper: true,
+				RequireLower: true,
+				RequireDigit: true,
+			},
+			Lockout: LockoutConfig{
+				Threshold:   5,
+				Duration:    Duration(time.Minute),
+				MaxDuration: Duration(time.Hour),
+			},
+			MFA: MFAConfig{Issuer: "Amestris"},
+		},
+		Log: LogConfig{Level: "info", Format: "json"},
+		RateLimit: RateLimitConfig{
+			Backend:   "memory",
+			Login:     RateLimitRule{Requests: 5, Period: Duration(time.Minute), Burst: 5},
+			Transmute: RateLimitRule{Requests: 30, Period: Duration(time.Minute), Burst: 10},
+		},
+		Registration: RegistrationConfig{
+			Open:        true,
+			DefaultRole: "alchemist",
+			InviteTTL:   Duration(72 * time.Hour),
+			InviteURL:   "http://localhost:3000/register?invite=",
+		},
+		OIDC: OIDCConfig{
+			RedirectURL:   "http://localhost:8080/login/oidc/callback",
+			FrontendURL:   "http://localhost:3000/auth/callback",
+			Scopes:        []string{"openid", "profile", "email"},
+			UsernameClaim: "preferred_username",
+		},
+	}
+}
+
+// Load lee la configuración. Si CONFIG_FILE está definida se carga ese
+// archivo antes de aplicar las variables de entorno.
+func Load() (*Config, error) {
+	cfg := Default()
+	var problems []string
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			problems = append(problems, fmt.Sprintf("CONFIG_FILE: %v", err))
+		}
+	}
+
+	problems = append(problems, applyEnv(cfg)...)
+	problems = append(problems, cfg.validate()...)
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return yaml.Unmarshal(data, cfg)
+	case ".toml":
+		return toml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("extensión no soportada %q (use .yaml, .yml o .toml)", filepath.Ext(path))
+	}
+}
+
+type envBinding struct {
+	key string
+	set func(value string) error
+}
+
+func applyEnv(cfg *Config) []string {
+	bindings := []envBinding{
+		{"HTTP_ADDR", setString(&cfg.Server.Addr)},
+		{"METRICS_ADDR", setString(&cfg.Server.MetricsAddr)},
+		{"SHUTDOWN_TIMEOUT", setDuration(&cfg.Server.ShutdownTimeout)},
+		{"READINESS_TIMEOUT", setDuration(&cfg.Server.ReadinessTimeout)},
+		{"DB_HOST", setString(&cfg.Database.Host)},
+		{"DB_PORT", setInt(&cfg.Database.Port)},
+		{"DB_USER", setString(&cfg.Database.User)},
+		{"DB_PASSWORD", setString(&cfg.Database.Password)},
+		{"DB_NAME", setString(&cfg.Database.Name)},
+		{"DB_SSLMODE", setString(&cfg.Database.SSLMode)},
+		{"DB_TIMEZONE", setString(&cfg.Database.TimeZone)},
+		{"JWT_SECRET", setString(&cfg.JWT.Secret)},
+		{"JWT_TTL", setDuration(&cfg.JWT.TTL)},
+		{"JWT_REFRESH_TTL", setDuration(&cfg.JWT.RefreshTTL)},
+		{"JWT_KEYS", setKeys(&cfg.JWT.Keys)},
+		{"JWT_KEY_GRACE", setDuration(&cfg.JWT.KeyGrace)},
+		{"CORS_ALLOWED_ORIGINS", setList(&cfg.CORS.AllowedOrigins)},
+		{"CORS_ALLOWED_METHODS", setList(&cfg.CORS.AllowedMethods)},
+		{"CORS_ALLOWED_HEADERS", setList(&cfg.CORS.AllowedHeaders)},
+		{"CORS_EXPOSED_HEADERS", setList(&cfg.CORS.ExposedHeaders)},
+		{"CORS_ALLOW_CREDENTIALS", setBool(&cfg.CORS.AllowCredentials)},
+		{"CORS_MAX_AGE", setDuration(&cfg.CORS.MaxAge)},
+		{"AUDIT_INTERVAL", setDuration(&cfg.Audit.Interval)},
+		{"AUDIT_FREQUENT_WINDOW", setDuration(&cfg.Audit.FrequentWindow)},
+		{"AUDIT_FREQUENT_LIMIT", setInt(&cfg.Audit.FrequentLimit)},
+		{"LOCAL_LOGIN", setBool(&cfg.Security.LocalLogin)},
+		{"PASSWORD_HASH", setString(&cfg.Security.PasswordHash)},
+		{"ARGON2_MEMORY_KIB", setInt(&cfg.Security.Argon2.MemoryKiB)},
+		{"ARGON2_ITERATIONS", setInt(&cfg.Security.Argon2.Iterations)},
+		{"ARGON2_PARALLELISM", setInt(&cfg.Security.Argon2.Parallelism)},
+		{"BCRYPT_COST", setInt(&cfg.Security.BcryptCost)},
+		{"PASSWORD_MIN_LENGTH", setInt(&cfg.Security.Password.MinLength)},
+		{"PASSWORD_REQUIRE_UPPER", setBool(&cfg.Security.Password.RequireUpper)},
+		{"PASSWORD_REQUIRE_LOWER", setBool(&cfg.Security.Password.RequireLower)},
+		{"PASSWORD_REQUIRE_DIGIT", setBool(&cfg.Security.Password.RequireDigit)},
+		{"PASSWORD_REQUIRE_SYMBOL", setBool(&cfg.Security.Password.RequireSymbol)},
+		{"LOCKOUT_THRESHOLD", setInt(&cfg.Security.Lockout.Threshold)},
+		{"LOCKOUT_DURATION", setDuration(&cfg.Security.Lockout.Duration)},
+		{"LOCKOUT_MAX_DURATION", setDuration(&cfg.Security.Lockout.MaxDuration)},
+		{"MFA_REQUIRED_ROLES", setList(&cfg.Security.MFA.RequiredRoles)},
+		{"MFA_ISSUER", setString(&cfg.Security.MFA.Issuer)},
+		{"LOG_LEVEL", setString(&cfg.Log.Level)},
+		{"LOG_FORMAT", setString(&cfg.Log.Format)},
+		{"RATE_LIMIT_BACKEND", setString(&cfg.RateLimit.Backend)},
+		{"RATE_LIMIT_LOGIN", setRule(&cfg.RateLimit.Login)},
+		{"RATE_LIMIT_TRANSMUTE", setRule(&cfg.RateLimit.Transmute)},
+		{"REGISTRATION_OPEN", setBool(&cfg.Registration.Open)},
+		{"REGISTRATION_DEFAULT_ROLE", setString(&cfg.Registration.DefaultRole)},
+		{"REGISTRATION_INVITE_TTL", setDuration(&cfg.Registration.InviteTTL)},
+		{"REGISTRATION_INVITE_URL", setString(&cfg.Registration.InviteURL)},
+		{"OIDC_ENABLED", setBool(&cfg.OIDC.Enabled)},
+		{"OIDC_ISSUER", setString(&cfg.OIDC.Issuer)},
+		{"OIDC_CLIENT_ID", setString(&cfg.OIDC.ClientID)},
+		{"OIDC_CLIENT_SECRET", setString(&cfg.OIDC.ClientSecret)},
+		{"OIDC_REDIRECT_URL", setString(&cfg.OIDC.RedirectURL)},
+		{"OIDC_FRONTEND_URL", setString(&cfg.OIDC.FrontendURL)},
+		{"OIDC_SCOPES", setList(&cfg.OIDC.Scopes)},
+		{"OIDC_USERNAME_CLAIM", setString(&cfg.OIDC.UsernameClaim)},
+		{"OIDC_ROLE_CLAIM", setString(&cfg.OIDC.RoleClaim)},
+		{"OIDC_ROLE_MAPPING", setRoleMapping(&cfg.OIDC.RoleMapping)},
+		{"OIDC_DEFAULT_ROLE", setString(&cfg.OIDC.DefaultRole)},
+		{"OIDC_ALCHEMIST_CLAIM", setString(&cfg.OIDC.AlchemistClaim)},
+	}
+
+	var problems []string
+	for _, b := range bindings {
+		value, ok := os.LookupEnv(b.key)
+		if !ok {
+			continue
+		}
+		if err := b.set(strings.TrimSpace(value)); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: valor inválido %q: %v", b.key, value, err))
+		}
+	}
+	return problems
+}
+
+func setString(dst *string) func(string) error {
+	return func(v string) error {
+		*dst = v
+		return nil
+	}
+}
+
+func setInt(dst *int) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*dst = n
+		return nil
+	}
+}
+
+func setBool(dst *bool) func(string) error {
+	return func(v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*dst = b
+		return nil
+	}
+}
+
+func setRule(dst *RateLimitRule) func(string) error {
+	return func(v string) error {
+		return dst.UnmarshalText([]byte(v))
+	}
+}
+
+func setKeys(dst *[]JWTKey) func(string) error {
+	return func(v string) error {
+		var keys []JWTKey
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			var key JWTKey
+			if err := key.UnmarshalText([]byte(item)); err != nil {
+				return err
+			}
+			keys = append(keys, key)
+		}
+		*dst = keys
+		return nil
+	}
+}
+
+func setRoleMapping(dst *[]OIDCRoleMapping) func(string) error {
+	return func(v string) error {
+		var mappings []OIDCRoleMapping
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			var mapping OIDCRoleMapping
+			if err := mapping.UnmarshalText([]byte(item)); err != nil {
+				return err
+			}
+			mappings = append(mappings, mapping)
+		}
+		*dst = mappings
+		return nil
+	}
+}
+
+func setDuration(dst *Duration) func(string) error {
+	return func(v string) error {
+		return dst.UnmarshalText([]byte(v))
+	}
+}
+
+func setList(dst *[]string) func(string) error {
+	return func(v string) error {
+		var items []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*dst = items
+		return nil
+	}
+}
+
+func (c *Config) validate() []string {
+	var problems []string
+	missing := func(key string) {
+		problems = append(problems, key+": requerido")
+	}
+
+	if c.Server.Addr == "" {
+		missing("HTTP_ADDR (server.addr)")
+	} else if c.Server.MetricsAddr == c.Server.Addr {
+		problems = append(problems, "METRICS_ADDR (server.metrics_addr): debe ser distinta de HTTP_ADDR")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		problems = append(problems, "SHUTDOWN_TIMEOUT (server.shutdown_timeout): debe ser mayor que cero")
+	}
+	if c.Server.ReadinessTimeout <= 0 {
+		problems = append(problems, "READINESS_TIMEOUT (server.readiness_timeout): debe ser mayor que cero")
+	}
+	if c.Database.Host == "" {
+		missing("DB_HOST (database.host)")
+	}
+	if c.Database.Port <= 0 || c.Database.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("DB_PORT (database.port): puerto fuera de rango: %d", c.Database.Port))
+	}
+	if c.Database.User == "" {
+		missing("DB_USER (database.user)")
+	}
+	if c.Database.Name == "" {
+		missing("DB_NAME (database.name)")
+	}
+	switch {
+	case len(c.JWT.Keys) > 0:
+		for i, key := range c.JWT.Keys {
+			if key.ID == "" || key.File == "" {
+				problems = append(problems, fmt.Sprintf("JWT_KEYS (jwt.keys[%d]): id y file son obligatorios", i))
+			}
+		}
+		if c.JWT.KeyGrace < c.JWT.TTL {
+			problems = append(problems, "JWT_KEY_GRACE (jwt.key_grace): debe ser al menos JWT_TTL")
+		}
+	case c.JWT.Secret == "":
+		missing("JWT_SECRET (jwt.secret) o JWT_KEYS (jwt.keys)")
+	case len(c.JWT.Secret) < 16:
+		problems = append(problems, "JWT_SECRET (jwt.secret): debe tener al menos 16 caracteres")
+	}
+	if c.JWT.TTL <= 0 {
+		problems = append(problems, "JWT_TTL (jwt.ttl): debe ser mayor que cero")
+	}
+	if c.JWT.RefreshTTL <= c.JWT.TTL {
+		problems = append(problems, "JWT_REFRESH_TTL (jwt.refresh_ttl): debe ser mayor que JWT_TTL")
+	}
+	if len(c.CORS.AllowedOrigins) == 0 {
+		missing("CORS_ALLOWED_ORIGINS (cors.allowed_origins)")
+	}
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			if c.CORS.AllowCredentials {
+				problems = append(problems, "CORS_ALLOWED_ORIGINS (cors.allowed_origins): \"*\" no se puede combinar con CORS_ALLOW_CREDENTIALS")
+			}
+			continue
+		}
+		if strings.Count(origin, "*") > 1 || (strings.Contains(origin, "*") && !strings.Contains(origin, "://*.")) {
+			problems = append(problems, fmt.Sprintf("CORS_ALLOWED_ORIGINS (cors.allowed_origins): comodín inválido %q (use esquema://*.dominio)", origin))
+		}
+	}
+	if len(c.CORS.AllowedMethods) == 0 {
+		missing("CORS_ALLOWED_METHODS (cors.allowed_methods)")
+	}
+	if c.CORS.MaxAge < 0 {
+		problems = append(problems, "CORS_MAX_AGE (cors.max_age): no puede ser negativo")
+	}
+	if c.Audit.Interval <= 0 {
+		problems = append(problems, "AUDIT_INTERVAL (audit.interval): debe ser mayor que cero")
+	}
+	if c.Audit.FrequentWindow <= 0 {
+		problems = append(problems, "AUDIT_FREQUENT_WINDOW (audit.frequent_window): debe ser mayor que cero")
+	}
+	if c.Audit.FrequentLimit <= 0 {
+		problems = append(problems, "AUDIT_FREQUENT_LIMIT (audit.frequent_limit): debe ser mayor que cero")
+	}
+	if c.Security.PasswordHash != "argon2id" && c.Security.PasswordHash != "bcrypt" {
+		problems = append(problems, "PASSWORD_HASH (security.password_hash): debe ser argon2id o bcrypt")
+	}
+	if argon := c.Security.Argon2; argon.Parallelism < 1 || argon.Parallelism > 255 {
+		problems = append(problems, "ARGON2_PARALLELISM (security.argon2.parallelism): debe estar entre 1 y 255")
+	} else if argon.MemoryKiB < 8*argon.Parallelism {
+		problems = append(problems, fmt.Sprintf("ARGON2_MEMORY_KIB (security.argon2.memory_kib): debe ser al menos %d", 8*argon.Parallelism))
+	}
+	if c.Security.Argon2.Iterations < 1 {
+		problems = append(problems, "ARGON2_ITERATIONS (security.argon2.iterations): debe ser mayor que cero")
+	}
+	if c.Security.BcryptCost < bcrypt.MinCost || c.Security.BcryptCost > bcrypt.MaxCost {
+		problems = append(problems, fmt.Sprintf("BCRYPT_COST (security.bcrypt_cost): debe estar entre %d y %d", bcrypt.MinCost, bcrypt.MaxCost))
+	}
+	if c.Security.Password.MinLength < 8 {
+		problems = append(problems, "PASSWORD_MIN_LENGTH (security.password.min_length): debe ser al menos 8")
+	}
+	if lockout := c.Security.Lockout; lockout.Threshold < 0 {
+		problems = append(problems, "LOCKOUT_THRESHOLD (security.lockout.threshold): no puede ser negativo")
+	} else if lockout.Threshold > 0 && (lockout.Duration <= 0 || lockout.MaxDuration < lockout.Duration) {
+		problems = append(problems, "LOCKOUT_DURATION (security.lockout.duration): debe ser mayor que cero y no superar LOCKOUT_MAX_DURATION")
+	}
+	if c.Security.MFA.Issuer == "" {
+		missing("MFA_ISSUER (security.mfa.issuer)")
+	}
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("LOG_LEVEL (log.level): nivel desconocido %q (debug, info, warn, error)", c.Log.Level))
+	}
+	switch strings.ToLower(c.Log.Format) {
+	case "json", "text":
+	default:
+		problems = append(problems, fmt.Sprintf("LOG_FORMAT (log.format): formato desconocido %q (json, text)", c.Log.Format))
+	}
+	switch c.RateLimit.Backend {
+	case "memory", "postgres":
+	default:
+		problems = append(problems, fmt.Sprintf("RATE_LIMIT_BACKEND (rate_limit.backend): backend desconocido %q (memory, postgres)", c.RateLimit.Backend))
+	}
+	rules := []struct {
+		key  string
+		rule RateLimitRule
+	}{
+		{"RATE_LIMIT_LOGIN (rate_limit.login)", c.RateLimit.Login},
+		{"RATE_LIMIT_TRANSMUTE (rate_limit.transmute)", c.RateLimit.Transmute},
+	}
+	for _, r := range rules {
+		if r.rule.Requests < 0 || r.rule.Burst < 0 || (r.rule.Requests > 0 && r.rule.Period <= 0) {
+			problems = append(problems, r.key+": requests y burst no pueden ser negativos y el periodo debe ser mayor que cero")
+		}
+	}
+	if c.Registration.Open && c.Registration.DefaultRole == "" {
+		problems = append(problems, "REGISTRATION_DEFAULT_ROLE (registration.default_role): es obligatorio con el registro abierto")
+	}
+	if c.Registration.InviteTTL <= 0 {
+		problems = append(problems, "REGISTRATION_INVITE_TTL (registration.invite_ttl): debe ser mayor que cero")
+	}
+	problems = append(problems, c.OIDC.validate()...)
+	if !c.Security.LocalLogin && !c.OIDC.Enabled {
+		problems = append(problems, "LOCAL_LOGIN (security.local_login): no se puede desactivar sin OIDC_ENABLED")
+	}
+
+	return problems
+}
+
+// validate comprueba la configuración OIDC solo si está activada. Los roles
+// se comprueban contra la base de datos al arrancar el servidor.
+func (o OIDCConfig) validate() []string {
+	if !o.Enabled {
+		return nil
+	}
+
+	var problems []string
+	required := []struct{ key, value string }{
+		{"OIDC_ISSUER (oidc.issuer)", o.Issuer},
+		{"OIDC_CLIENT_ID (oidc.client_id)", o.ClientID},
+		{"OIDC_REDIRECT_URL (oidc.redirect_url)", o.RedirectURL},
+		{"OIDC_FRONTEND_URL (oidc.frontend_url)", o.FrontendURL},
+		{"OIDC_USERNAME_CLAIM (oidc.username_claim)", o.UsernameClaim},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			problems = append(problems, r.key+": requerido")
+		}
+	}
+	if !slices.Contains(o.Scopes, "openid") {
+		problems = append(problems, "OIDC_SCOPES (oidc.scopes): debe incluir openid")
+	}
+	if len(o.RoleMapping) > 0 && o.RoleClaim == "" {
+		problems = append(problems, "OIDC_ROLE_CLAIM (oidc.role_claim): requerido con OIDC_ROLE_MAPPING")
+	}
+	for i, mapping := range o.RoleMapping {
+		if mapping.Value == "" || mapping.Role == "" {
+			problems = append(problems, fmt.Sprintf("OIDC_ROLE_MAPPING (oidc.role_mapping[%d]): value y role son obligatorios", i))
+		}
+	}
+	if len(o.RoleMapping) == 0 && o.DefaultRole == "" {
+		problems = append(problems, "OIDC_DEFAULT_ROLE (oidc.default_role): requerido si no hay OIDC_ROLE_MAPPING")
+	}
+	return problems
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setRequired define las variables obligatorias con valores válidos.
+func setRequired(t *testing.T) {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("DB_USER", "amestris")
+	t.Setenv("DB_NAME", "amestris")
+	t.Setenv("JWT_SECRET", "secreto-de-prueba-0123")
+}
+
+// writeConfig guarda content en un archivo con la extensión dada y lo
+// apunta con CONFIG_FILE.
+func writeConfig(t *testing.T, ext, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config"+ext)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name  string
+		ext   string
+		file  string
+		env   map[string]string
+		check func(t *testing.T, cfg *Config)
+	}{
+		{
+			name: "valores por defecto",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Database.Host != "localhost" || cfg.Database.Port != 5432 || cfg.JWT.TTL.Std() != 15*time.Minute {
+					t.Errorf("defaults: %+v %s", cfg.Database, cfg.JWT.TTL.Std())
+				}
+			},
+		},
+		{
+			name: "el archivo YAML sustituye los valores por defecto",
+			ext:  ".yaml",
+			file: "database:\n  host: db-archivo\n  port: 6543\njwt:\n  ttl: 5m\n",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Database.Host != "db-archivo" || cfg.Database.Port != 6543 || cfg.JWT.TTL.Std() != 5*time.Minute {
+					t.Errorf("archivo: %+v %s", cfg.Database, cfg.JWT.TTL.Std())
+				}
+			},
+		},
+		{
+			name: "el entorno tiene prioridad sobre el archivo TOML",
+			ext:  ".toml",
+			file: "[database]\nhost = \"db-archivo\"\nport = 6543\n",
+			env:  map[string]string{"DB_HOST": "db-entorno"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Database.Host != "db-entorno" {
+					t.Errorf("DB_HOST = %q, esperado el del entorno", cfg.Database.Host)
+				}
+				if cfg.Database.Port != 6543 {
+					t.Errorf("DB_PORT = %d, esperado el del archivo", cfg.Database.Port)
+				}
+			},
+		},
+		{
+			name: "las listas del entorno se separan por comas",
+			env:  map[string]string{"CORS_ALLOWED_ORIGINS": " https://a.example , ,https://b.example"},
+			check: func(t *testing.T, cfg *Config) {
+				if want := []string{"https://a.example", "https://b.example"}; !slices.Equal(cfg.CORS.AllowedOrigins, want) {
+					t.Errorf("CORS_ALLOWED_ORIGINS = %q, esperado %q", cfg.CORS.AllowedOrigins, want)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequired(t)
+			if tt.file != "" {
+				writeConfig(t, tt.ext, tt.file)
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			cfg, err := Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoadListsEveryProblem(t *testing.T) {
+	writeConfig(t, ".yaml", "database:\n  port: 0\n")
+	for _, key := range []string{"DB_USER", "DB_NAME", "JWT_SECRET"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("BCRYPT_COST", "mucho")
+	t.Setenv("JWT_TTL", "-1m")
+
+	_, err := Load()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("error %v, esperado *ValidationError", err)
+	}
+	for _, want := range []string{
+		"BCRYPT_COST: valor inválido",
+		"DB_PORT (database.port)",
+		"DB_USER (database.user): requerido",
+		"DB_NAME (database.name): requerido",
+		"JWT_SECRET (jwt.secret)",
+		"JWT_TTL (jwt.ttl)",
+	} {
+		if !slices.ContainsFunc(verr.Problems, func(p string) bool { return strings.HasPrefix(p, want) }) {
+			t.Errorf("falta %q en %q", want, verr.Problems)
+		}
+		if !strings.Contains(err.Error(), "\n  - "+want) {
+			t.Errorf("el mensaje no enumera %q:\n%s", want, err)
+		}
+	}
+}
+
+func TestLoadRejectsUnknownExtension(t *testing.T) {
+	setRequired(t)
+	writeConfig(t, ".json", "{}")
+
+	_, err := Load()
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Problems) != 1 || !strings.HasPrefix(verr.Problems[0], "CONFIG_FILE:") {
+		t.Errorf("error %v, esperado solo el problema de CONFIG_FILE", err)
+	}
+}
+
+func TestValidateCORSOrigins(t *testing.T) {
+	tests := []struct {
+		origins     []string
+		credentials bool
+		problem     string
+	}{
+		{[]string{"*"}, false, ""},
+		{[]string{"*"}, true, "no se puede combinar con CORS_ALLOW_CREDENTIALS"},
+		{[]string{"https://amestris.example", "https://*.central.example"}, true, ""},
+		{[]string{"https://*central.example"}, false, "comodín inválido"},
+		{[]string{"https://east.*.example"}, false, "comodín inválido"},
+		{[]string{"https://*.*.example"}, false, "comodín inválido"},
+		{[]string{"*.central.example"}, false, "comodín inválido"},
+		{nil, false, "CORS_ALLOWED_ORIGINS (cors.allowed_origins): requerido"},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.Database.User, cfg.Database.Name, cfg.JWT.Secret = "amestris", "amestris", "secreto-de-prueba-0123"
+		cfg.CORS.AllowedOrigins = tt.origins
+		cfg.CORS.AllowCredentials = tt.credentials
+
+		problems := cfg.validate()
+		switch {
+		case tt.problem == "" && len(problems) > 0:
+			t.Errorf("%q (credenciales %v): problemas inesperados %q", tt.origins, tt.credentials, problems)
+		case tt.problem != "" && (len(problems) != 1 || !strings.Contains(problems[0], tt.problem)):
+			t.Errorf("%q (credenciales %v): %q, esperado %q", tt.origins, tt.credentials, problems, tt.problem)
+		}
+	}
+}
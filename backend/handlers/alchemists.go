@@ -1,41 +1,84 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 
 	"amestris-backend/models"
+	"amestris-backend/query"
+	"amestris-backend/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-func GetAlchemists(c *gin.Context) {
-	var alchemists []models.Alchemist
-	if err := models.DB.Find(&alchemists).Error; err != nil {
+type AlchemistHandler struct {
+	alchemists repository.AlchemistRepository
+	users      repository.UserRepository
+	audits     repository.AuditRepository
+	passwords  Passwords
+}
+
+func NewAlchemistHandler(alchemists repository.AlchemistRepository, users repository.UserRepository, audits repository.AuditRepository,
+	passwords Passwords) *AlchemistHandler {
+	return &AlchemistHandler{alchemists: alchemists, users: users, audits: audits, passwords: passwords}
+}
+
+// alchemistQuery son los filtros y órdenes admitidos en GET /api/alchemists.
+var alchemistQuery = &query.Schema[models.Alchemist]{
+	Fields: map[string]query.Field[models.Alchemist]{
+		"id":         {Column: "id", Type: query.Int, Sort: true, Value: func(a models.Alchemist) any { return a.ID }},
+		"name":       {Column: "name", Type: query.Text, Sort: true, Value: func(a models.Alchemist) any { return a.Name }},
+		"status":     {Column: "status", Type: query.Text, Filter: true, Sort: true, Value: func(a models.Alchemist) any { return a.Status }},
+		"rank":       {Column: "rank", Type: query.Text, Filter: true, Sort: true, Value: func(a models.Alchemist) any { return a.Rank }},
+		"specialty":  {Column: "specialty", Type: query.Text, Filter: true, Sort: true, Value: func(a models.Alchemist) any { return a.Specialty }},
+		"automail":   {Column: "automail", Type: query.Bool, Filter: true, Value: func(a models.Alchemist) any { return a.Automail }},
+		"created_at": {Column: "created_at", Type: query.Time, Sort: true, Value: func(a models.Alchemist) any { return a.CreatedAt }},
+	},
+	DefaultSort: "id",
+}
+
+func (h *AlchemistHandler) GetAlchemists(c *gin.Context) {
+	list, ok := parseList(c, alchemistQuery)
+	if !ok {
+		return
+	}
+	page, err := h.alchemists.Page(c.Request.Context(), list)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo alquimistas"})
 		return
 	}
-	c.JSON(http.StatusOK, alchemists)
+	respondPage(c, page)
 }
 
-func GetAlchemist(c *gin.Context) {
-	id := c.Param("id")
-	var alchemist models.Alchemist
-	if err := models.DB.Preload("User").First(&alchemist, id).Error; err != nil {
+func (h *AlchemistHandler) GetAlchemist(c *gin.Context) {
+	id, ok := parseID(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alquimista no encontrado"})
+		return
+	}
+
+	alchemist, err := h.alchemists.Get(c.Request.Context(), id)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Alquimista no encontrado"})
 		return
 	}
 	c.JSON(http.StatusOK, alchemist)
 }
 
-func CreateAlchemist(c *gin.Context) {
+func (h *AlchemistHandler) CreateAlchemist(c *gin.Context) {
 	var alchemist models.Alchemist
 	if err := c.BindJSON(&alchemist); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
 		return
 	}
+	if !h.checkSupervisor(c, nil, alchemist.SupervisorID) {
+		return
+	}
 
-	if err := models.DB.Create(&alchemist).Error; err != nil {
+	if err := h.alchemists.Create(c.Request.Context(), &alchemist); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando alquimista"})
 		return
 	}
@@ -43,21 +86,29 @@ func CreateAlchemist(c *gin.Context) {
 	c.JSON(http.StatusCreated, alchemist)
 }
 
-func UpdateAlchemist(c *gin.Context) {
-	id := c.Param("id")
-	var alchemist models.Alchemist
+func (h *AlchemistHandler) UpdateAlchemist(c *gin.Context) {
+	id, ok := parseID(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alquimista no encontrado"})
+		return
+	}
 
-	if err := models.DB.First(&alchemist, id).Error; err != nil {
+	alchemist, err := h.alchemists.Get(c.Request.Context(), id)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Alquimista no encontrado"})
 		return
 	}
 
-	if err := c.BindJSON(&alchemist); err != nil {
+	previousSupervisor := alchemist.SupervisorID
+	if err := c.BindJSON(alchemist); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
 		return
 	}
+	if !h.checkSupervisor(c, previousSupervisor, alchemist.SupervisorID) {
+		return
+	}
 
-	if err := models.DB.Save(&alchemist).Error; err != nil {
+	if err := h.alchemists.Update(c.Request.Context(), alchemist); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando alquimista"})
 		return
 	}
@@ -65,8 +116,29 @@ func UpdateAlchemist(c *gin.Context) {
 	c.JSON(http.StatusOK, alchemist)
 }
 
+// checkSupervisor comprueba un cambio de supervisor: solo lo puede hacer
+// quien tiene missions:write:all, porque el supervisor decide sobre las
+// misiones del alquimista, y el usuario debe existir. Si no, responde y
+// devuelve false.
+func (h *AlchemistHandler) checkSupervisor(c *gin.Context, previous, next *uint) bool {
+	if previous == next || (previous != nil && next != nil && *previous == *next) {
+		return true
+	}
+	if !can(c, "missions:write:all") {
+		denyAccess(c, h.audits, "alchemist", "asignar un supervisor")
+		return false
+	}
+	if next != nil {
+		if _, err := h.users.Get(c.Request.Context(), *next); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Supervisor no encontrado"})
+			return false
+		}
+	}
+	return true
+}
+
 // Registrar nuevo alquimista con usuario automático
-func RegisterAlchemist(c *gin.Context) {
+func (h *AlchemistHandler) RegisterAlchemist(c *gin.Context) {
 	var request struct {
 		Name      string `json:"name" binding:"required"`
 		Title     string `json:"title" binding:"required"`
@@ -81,9 +153,10 @@ func RegisterAlchemist(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
+
 	// Verificar que no exista un alquimista con el mismo nombre
-	var existing models.Alchemist
-	if err := models.DB.Where("name = ?", request.Name).First(&existing).Error; err == nil {
+	if _, err := h.alchemists.FindByName(ctx, request.Name); err == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Ya existe un alquimista con ese nombre"})
 		return
 	}
@@ -97,41 +170,90 @@ func RegisterAlchemist(c *gin.Context) {
 		Automail:  request.Automail,
 	}
 
-	if err := models.DB.Create(&alchemist).Error; err != nil {
+	if err := h.alchemists.Create(ctx, &alchemist); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error registrando alquimista: " + err.Error()})
 		return
 	}
 
-	// Crear usuario automáticamente
-	hashedPassword, _ := HashPassword("password123")
-	username := strings.ToLower(strings.ReplaceAll(request.Name, " ", "_"))
+	// Crear usuario automáticamente con una contraseña de un solo uso
+	oneTimePassword := h.passwords.Policy.Generate()
+	hashedPassword, err := h.passwords.Hash(oneTimePassword)
+	if err != nil {
+		h.alchemists.Delete(ctx, alchemist.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error procesando contraseña"})
+		return
+	}
+	username, err := uniqueUsername(ctx, h.users, request.Name)
+	if err != nil {
+		h.alchemists.Delete(ctx, alchemist.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generando nombre de usuario"})
+		return
+	}
 
 	user := models.User{
-		Username:    username,
-		Password:    hashedPassword,
-		Role:        "alchemist",
-		AlchemistID: &alchemist.ID,
+		Username:           username,
+		Password:           hashedPassword,
+		Role:               "alchemist",
+		AlchemistID:        &alchemist.ID,
+		MustChangePassword: true,
 	}
 
-	if err := models.DB.Create(&user).Error; err != nil {
+	if err := h.users.Create(ctx, &user); err != nil {
 		// Si falla crear el usuario, eliminar el alquimista
-		models.DB.Delete(&alchemist)
+		h.alchemists.Delete(ctx, alchemist.ID)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando usuario: " + err.Error()})
 		return
 	}
 
 	// Log de auditoría
-	adminID, _ := c.Get("userID")
-	CreateAuditLog(adminID.(uint), "ALCHEMIST_REGISTER", "alchemist",
+	auditRequest(c, h.audits, "ALCHEMIST_REGISTER", "alchemist",
 		"Nuevo alquimista registrado: "+alchemist.Name+" - "+alchemist.Title)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message":   "Alquimista registrado exitosamente",
 		"alchemist": alchemist,
 		"user_credentials": gin.H{
-			"username": username,
-			"password": "password123",
-			"role":     "alchemist",
+			"username":             username,
+			"password":             oneTimePassword,
+			"role":                 "alchemist",
+			"must_change_password": true,
 		},
 	})
 }
+
+var usernameReplacer = strings.NewReplacer(
+	"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n",
+)
+
+// uniqueUsername deriva un nombre de usuario del nombre del alquimista
+// ("Edward Elric" -> "edward_elric") y le añade un sufijo numérico si ya
+// está en uso.
+func uniqueUsername(ctx context.Context, users repository.UserRepository, name string) (string, error) {
+	var b strings.Builder
+	for _, r := range usernameReplacer.Replace(strings.ToLower(strings.TrimSpace(name))) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '_':
+			b.WriteRune('_')
+		}
+	}
+	base := strings.Trim(b.String(), "_")
+	if base == "" {
+		base = "alquimista"
+	}
+
+	for i := 1; ; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = fmt.Sprintf("%s_%d", base, i)
+		}
+		_, err := users.FindByUsername(ctx, candidate)
+		if errors.Is(err, repository.ErrNotFound) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
@@ -1,45 +1,106 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"sync/atomic"
 	"time"
 
+	"amestris-backend/config"
+	"amestris-backend/logging"
+	"amestris-backend/metrics"
 	"amestris-backend/models"
+	"amestris-backend/query"
+	"amestris-backend/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-func CreateAuditLog(alchemistID uint, action, resource, details string) {
+type AuditHandler struct {
+	audits         repository.AuditRepository
+	experiments    repository.ExperimentRepository
+	transmutations repository.TransmutationRepository
+
+	// Marcas de tiempo (UnixNano) del worker de fondo, para /readyz.
+	workerStarted atomic.Int64
+	lastSuccess   atomic.Int64
+}
+
+func NewAuditHandler(audits repository.AuditRepository, experiments repository.ExperimentRepository, transmutations repository.TransmutationRepository) *AuditHandler {
+	return &AuditHandler{audits: audits, experiments: experiments, transmutations: transmutations}
+}
+
+// createAuditLog guarda un evento. userID es 0 para los eventos del sistema
+// y alchemistID es nil si el evento no se refiere a ningún alquimista.
+func createAuditLog(ctx context.Context, audits repository.AuditRepository, userID uint, alchemistID *uint, action, resource, details string) {
 	audit := models.AuditLog{
+		UserID:      optionalID(userID),
 		AlchemistID: alchemistID,
 		Action:      action,
 		Resource:    resource,
 		Details:     details,
 		Severity:    getSeverityLevel(action),
+		RequestID:   logging.RequestID(ctx),
 	}
-	models.DB.Create(&audit)
+	if err := audits.Create(ctx, &audit); err != nil {
+		slog.ErrorContext(ctx, "error guardando registro de auditoría",
+			"action", action, "resource", resource, "error", err)
+		return
+	}
+	metrics.AuditLogs.WithLabelValues(audit.Severity, audit.Action).Inc()
 }
 
-func GetAuditLogs(c *gin.Context) {
-	userRole, _ := c.Get("role")
+// optionalID convierte un ID sin asignar (0) en nil.
+func optionalID(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
 
-	var audits []models.AuditLog
-	query := models.DB.Preload("Alchemist").Order("created_at DESC")
+// Log registra un evento de auditoría generado fuera de los handlers, por
+// ejemplo desde un middleware. userID es 0 si la petición es anónima.
+func (h *AuditHandler) Log(ctx context.Context, userID uint, action, resource, details string) {
+	createAuditLog(ctx, h.audits, userID, nil, action, resource, details)
+}
+
+// auditQuery son los filtros y órdenes admitidos en GET /api/audit-logs.
+var auditQuery = &query.Schema[models.AuditLog]{
+	Fields: map[string]query.Field[models.AuditLog]{
+		"id":           {Column: "id", Type: query.Int, Sort: true, Value: func(a models.AuditLog) any { return a.ID }},
+		"action":       {Column: "action", Type: query.Text, Filter: true, Value: func(a models.AuditLog) any { return a.Action }},
+		"resource":     {Column: "resource", Type: query.Text, Filter: true, Value: func(a models.AuditLog) any { return a.Resource }},
+		"severity":     {Column: "severity", Type: query.Text, Filter: true, Value: func(a models.AuditLog) any { return a.Severity }},
+		"checked":      {Column: "checked", Type: query.Bool, Filter: true, Value: func(a models.AuditLog) any { return a.Checked }},
+		"user_id":      {Column: "user_id", Type: query.Int, Filter: true, Value: func(a models.AuditLog) any { return a.UserID }},
+		"alchemist_id": {Column: "alchemist_id", Type: query.Int, Filter: true, Value: func(a models.AuditLog) any { return a.AlchemistID }},
+		"created_at":   {Column: "created_at", Type: query.Time, Sort: true, Value: func(a models.AuditLog) any { return a.CreatedAt }},
+	},
+	DefaultSort: "-created_at,-id",
+}
+
+func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
+	list, ok := parseList(c, auditQuery)
+	if !ok {
+		return
+	}
 
-	if userRole == "supervisor" {
-		query = query.Where("severity IN ?", []string{"warning", "danger"})
-	} else if userRole == "alchemist" {
-		userID, _ := c.Get("userID")
-		query = query.Where("alchemist_id = ?", userID)
+	switch {
+	case can(c, "audit:read:all"):
+	case can(c, "audit:read:alerts"):
+		list.Where("severity", "warning", "danger")
+	default:
+		list.Where("user_id", c.GetUint("userID"))
 	}
 
-	if err := query.Limit(100).Find(&audits).Error; err != nil {
+	page, err := h.audits.Page(c.Request.Context(), list)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo logs"})
 		return
 	}
-
-	c.JSON(http.StatusOK, audits)
+	respondPage(c, page)
 }
 
 func getSeverityLevel(action string) string {
@@ -53,28 +114,76 @@ func getSeverityLevel(action string) string {
 	}
 }
 
-func StartBackgroundAudits() {
-	go func() {
-		for {
-			time.Sleep(5 * time.Minute)
+// RunChecks ejecuta una vez las verificaciones automáticas y devuelve el
+// número de registros de auditoría generados.
+func (h *AuditHandler) RunChecks(ctx context.Context, cfg config.AuditConfig) (int, error) {
+	created := 0
 
-			// Verificar experimentos de alto riesgo
-			var highRiskExperiments []models.ExperimentRequest
-			models.DB.Where("risk_level = ? AND status = ?", "high", "approved").Find(&highRiskExperiments)
+	// Verificar experimentos de alto riesgo
+	highRiskExperiments, err := h.experiments.ListByRiskAndStatus(ctx, "high", "approved")
+	if err != nil {
+		return created, err
+	}
 
-			for _, exp := range highRiskExperiments {
-				CreateAuditLog(exp.AlchemistID, "RISK_MONITOR", "experiment",
-					fmt.Sprintf("Experimento de alto riesgo monitoreado: %s", exp.Title))
-			}
+	for _, exp := range highRiskExperiments {
+		createAuditLog(ctx, h.audits, 0, &exp.AlchemistID, "RISK_MONITOR", "experiment",
+			fmt.Sprintf("Experimento de alto riesgo monitoreado: %s", exp.Title))
+		created++
+	}
+
+	// Verificar transmutaciones frecuentes
+	recentTransmutations, err := h.transmutations.ListSince(ctx, time.Now().Add(-cfg.FrequentWindow.Std()))
+	if err != nil {
+		return created, err
+	}
+
+	if len(recentTransmutations) > cfg.FrequentLimit {
+		createAuditLog(ctx, h.audits, 0, optionalID(recentTransmutations[0].AlchemistID), "FREQUENT_ACTIVITY", "transmutation",
+			"Actividad de transmutación inusualmente frecuente detectada")
+		created++
+	}
+
+	return created, nil
+}
+
+// RunBackgroundAudits ejecuta RunChecks cada cfg.Interval hasta que se
+// cancele ctx. Una verificación en curso se completa antes de salir.
+func (h *AuditHandler) RunBackgroundAudits(ctx context.Context, cfg config.AuditConfig) {
+	h.workerStarted.Store(time.Now().UnixNano())
+	defer h.workerStarted.Store(0)
 
-			// Verificar transmutaciones frecuentes
-			var recentTransmutations []models.TransmutationLog
-			models.DB.Where("created_at > ?", time.Now().Add(-1*time.Hour)).Find(&recentTransmutations)
+	ticker := time.NewTicker(cfg.Interval.Std())
+	defer ticker.Stop()
 
-			if len(recentTransmutations) > 10 {
-				CreateAuditLog(recentTransmutations[0].AlchemistID, "FREQUENT_ACTIVITY", "transmutation",
-					"Actividad de transmutación inusualmente frecuente detectada")
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			start := time.Now()
+			_, err := h.RunChecks(context.WithoutCancel(ctx), cfg)
+			metrics.BackgroundAuditDuration.Observe(time.Since(start).Seconds())
+
+			if err != nil {
+				metrics.BackgroundAuditFailures.Inc()
+				slog.ErrorContext(ctx, "error en verificación automática de auditoría", "error", err)
+				continue
 			}
+			h.lastSuccess.Store(time.Now().UnixNano())
+			metrics.BackgroundAuditLastSuccess.SetToCurrentTime()
 		}
-	}()
+	}
+}
+
+// WorkerStatus informa si el worker de fondo está en marcha y cuándo terminó
+// su última verificación exitosa (cero si aún no ha terminado ninguna).
+func (h *AuditHandler) WorkerStatus() (running bool, startedAt, lastSuccess time.Time) {
+	started := h.workerStarted.Load()
+	if started == 0 {
+		return false, time.Time{}, time.Time{}
+	}
+	if last := h.lastSuccess.Load(); last != 0 {
+		lastSuccess = time.Unix(0, last)
+	}
+	return true, time.Unix(0, started), lastSuccess
 }
@@ -1,41 +1,47 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
+	"amestris-backend/config"
 	"amestris-backend/models"
+	"amestris-backend/repository"
+	"amestris-backend/signing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Role     string `json:"role"`
-	Username string `json:"username"`
+	UserID             uint   `json:"user_id"`
+	Role               string `json:"role"`
+	Username           string `json:"username"`
+	SessionID          string `json:"sid"`
+	MustChangePassword bool   `json:"mcp,omitempty"`
+	MFASetupRequired   bool   `json:"mfa_setup,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
-	return string(bytes), err
-}
-
-func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
-}
-
-func GenerateJWT(user models.User) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+// GenerateJWT firma un access token de corta duración para la sesión dada.
+// Devuelve también los claims, cuyo ID (jti) permite revocarlo.
+func (h *AuthHandler) GenerateJWT(user models.User, sessionID string) (string, *Claims, error) {
+	expirationTime := time.Now().Add(h.accessTTL)
 
 	claims := &Claims{
-		UserID:   user.ID,
-		Role:     user.Role,
-		Username: user.Username,
+		UserID:             user.ID,
+		Role:               user.Role,
+		Username:           user.Username,
+		SessionID:          sessionID,
+		MustChangePassword: user.MustChangePassword,
+		MFASetupRequired:   h.mfaSetupRequired(user),
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        randomToken(16),
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
@@ -43,100 +49,317 @@ func GenerateJWT(user models.User) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(models.JWTSecret)
+	signed, err := h.keys.Sign(claims, signing.TypeAccess)
+	return signed, claims, err
+}
+
+type AuthHandler struct {
+	users         repository.UserRepository
+	refreshTokens repository.RefreshTokenRepository
+	revokedTokens repository.RevokedTokenRepository
+	recoveryCodes repository.RecoveryCodeRepository
+	audits        repository.AuditRepository
+
+	keys       *signing.KeySet
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	passwords  Passwords
+	lockout    config.LockoutConfig
+	mfa        config.MFAConfig
+	localLogin bool
+}
+
+func NewAuthHandler(users repository.UserRepository, refreshTokens repository.RefreshTokenRepository,
+	revokedTokens repository.RevokedTokenRepository, recoveryCodes repository.RecoveryCodeRepository,
+	audits repository.AuditRepository, settings Settings) *AuthHandler {
+	return &AuthHandler{users: users, refreshTokens: refreshTokens, revokedTokens: revokedTokens,
+		recoveryCodes: recoveryCodes, audits: audits,
+		keys: settings.Keys, accessTTL: settings.AccessTTL, refreshTTL: settings.RefreshTTL,
+		passwords: settings.Passwords, lockout: settings.Lockout, mfa: settings.MFA, localLogin: settings.LocalLogin}
+}
+
+// checkLocalLogin responde 403 si el login con contraseña está desactivado.
+func (h *AuthHandler) checkLocalLogin(c *gin.Context) bool {
+	if h.localLogin {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "El inicio de sesión con contraseña está desactivado; use el proveedor de identidad"})
+	return false
 }
 
-func Login(c *gin.Context) {
+func (h *AuthHandler) Login(c *gin.Context) {
+	if !h.checkLocalLogin(c) {
+		return
+	}
+
 	var loginReq models.LoginRequest
 	if err := c.BindJSON(&loginReq); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
 		return
 	}
 
-	var user models.User
-	if err := models.DB.Where("username = ?", loginReq.Username).First(&user).Error; err != nil {
+	ctx := c.Request.Context()
+	user, err := h.users.FindByUsername(ctx, loginReq.Username)
+	// Las cuentas de servicio solo se autentican con API keys y las creadas
+	// por OIDC, con el proveedor
+	if err != nil || user.ServiceAccount || user.OIDCSubject != "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Credenciales inválidas"})
 		return
 	}
 
-	if !CheckPasswordHash(loginReq.Password, user.Password) {
+	now := time.Now()
+	if !checkNotLocked(c, user, now) {
+		return
+	}
+
+	ok, rehash := h.passwords.Hasher.Verify(loginReq.Password, user.Password)
+	if !ok {
+		h.recordLoginFailure(ctx, user, now)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Credenciales inválidas"})
 		return
 	}
 
-	token, err := GenerateJWT(user)
+	if !checkActive(c, user) {
+		return
+	}
+	if rehash {
+		h.rehashPassword(ctx, user, loginReq.Password)
+	}
+
+	// Con segundo factor, el login se completa en /login/mfa
+	if user.TOTPEnabled {
+		challenge, err := h.issueMFAChallenge(*user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generando token"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa_token":    challenge,
+			"expires_in":   int(mfaChallengeTTL.Seconds()),
+		})
+		return
+	}
+
+	response, ok := h.completeLogin(c, user, now)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// rehashPassword recalcula el hash de la contraseña con el algoritmo y los
+// parámetros actuales. Solo escribe la contraseña, para no deshacer cambios
+// hechos sobre el usuario desde que se cargó. Un fallo no impide el login:
+// se reintenta en el siguiente.
+func (h *AuthHandler) rehashPassword(ctx context.Context, user *models.User, plain string) {
+	hashed, err := h.passwords.Hash(plain)
+	if err == nil {
+		err = h.users.UpdatePassword(ctx, user.ID, hashed)
+	}
+	if err == nil {
+		user.Password = hashed
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "error actualizando el hash de la contraseña", "user_id", user.ID, "error", err)
+	}
+}
+
+// checkNotLocked responde 423 si la cuenta está bloqueada por intentos
+// fallidos.
+func checkNotLocked(c *gin.Context, user *models.User, now time.Time) bool {
+	if user.LockedUntil == nil || !now.Before(*user.LockedUntil) {
+		return true
+	}
+	retryAfter := int(math.Ceil(user.LockedUntil.Sub(now).Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusLocked, gin.H{
+		"error":       "Cuenta bloqueada temporalmente por intentos fallidos",
+		"retry_after": retryAfter,
+	})
+	return false
+}
+
+// checkActive responde 403 si la cuenta está deshabilitada o pendiente de
+// aprobación.
+func checkActive(c *gin.Context, user *models.User) bool {
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cuenta deshabilitada"})
+		return false
+	}
+	if user.Pending {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cuenta pendiente de aprobación"})
+		return false
+	}
+	return true
+}
+
+// completeLogin abre una sesión nueva tras verificar todas las credenciales
+// y devuelve la respuesta del login.
+func (h *AuthHandler) completeLogin(c *gin.Context, user *models.User, now time.Time) (gin.H, bool) {
+	response, err := h.openSession(c.Request.Context(), user, now)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generando token"})
+		return nil, false
+	}
+	return response, true
+}
+
+// openSession emite los tokens de una sesión nueva y registra el inicio de
+// sesión.
+func (h *AuthHandler) openSession(ctx context.Context, user *models.User, now time.Time) (gin.H, error) {
+	response, err := h.issueTokens(ctx, *user, randomToken(16))
+	if err != nil {
+		return nil, err
+	}
+	if err := h.users.RecordLoginSuccess(ctx, user.ID, now); err != nil {
+		slog.ErrorContext(ctx, "error registrando inicio de sesión", "user_id", user.ID, "error", err)
+	}
+
+	response["message"] = "Login exitoso"
+	response["user"] = gin.H{
+		"id":                   user.ID,
+		"username":             user.Username,
+		"role":                 user.Role,
+		"must_change_password": user.MustChangePassword,
+		"mfa_enabled":          user.TOTPEnabled,
+		"mfa_setup_required":   h.mfaSetupRequired(*user),
+	}
+	return response, nil
+}
+
+// lockoutFor devuelve cuánto bloquear una cuenta tras attempts intentos
+// fallidos seguidos, o 0 si no se alcanza el umbral.
+func (h *AuthHandler) lockoutFor(attempts int) time.Duration {
+	threshold, maxWait := h.lockout.Threshold, h.lockout.MaxDuration.Std()
+	if threshold <= 0 || attempts < threshold {
+		return 0
+	}
+	wait := h.lockout.Duration.Std()
+	for i := threshold; i < attempts && wait < maxWait; i++ {
+		wait *= 2
+	}
+	return min(wait, maxWait)
+}
+
+// recordLoginFailure cuenta el intento fallido y bloquea la cuenta al
+// alcanzar el umbral, dejando constancia en auditoría.
+func (h *AuthHandler) recordLoginFailure(ctx context.Context, user *models.User, at time.Time) {
+	attempts, err := h.users.RecordLoginFailure(ctx, user.ID, at)
+	if err != nil {
+		slog.ErrorContext(ctx, "error registrando intento fallido", "user_id", user.ID, "error", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Login exitoso",
-		"token":   token,
-		"user": gin.H{
-			"id":       user.ID,
-			"username": user.Username,
-			"role":     user.Role,
-		},
-	})
+	wait := h.lockoutFor(attempts)
+	if wait == 0 {
+		return
+	}
+	until := at.Add(wait)
+	if err := h.users.SetLockedUntil(ctx, user.ID, &until); err != nil {
+		slog.ErrorContext(ctx, "error bloqueando cuenta", "user_id", user.ID, "error", err)
+		return
+	}
+	createAuditLog(ctx, h.audits, user.ID, user.AlchemistID, "UNAUTHORIZED_ACCESS", "user",
+		fmt.Sprintf("Cuenta %s bloqueada %s tras %d intentos fallidos", user.Username, wait, attempts))
 }
 
-func Register(c *gin.Context) {
-	var user models.User
-	if err := c.BindJSON(&user); err != nil {
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// ChangePassword cambia la contraseña del usuario autenticado, cierra todas
+// sus sesiones y devuelve tokens nuevos para la sesión actual.
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var req changePasswordRequest
+	if err := c.BindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
 		return
 	}
 
-	var existingUser models.User
-	if err := models.DB.Where("username = ?", user.Username).First(&existingUser).Error; err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "El usuario ya existe"})
+	ctx := c.Request.Context()
+	user, err := h.users.Get(ctx, c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+		return
+	}
+
+	if !checkActive(c, user) {
+		return
+	}
+	if !h.passwords.Matches(req.CurrentPassword, user.Password) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La contraseña actual no es correcta"})
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La nueva contraseña debe ser distinta de la actual"})
+		return
+	}
+	if !h.passwords.check(c, req.NewPassword) {
 		return
 	}
 
-	hashedPassword, err := HashPassword(user.Password)
+	hashedPassword, err := h.passwords.Hash(req.NewPassword)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error procesando contraseña"})
 		return
 	}
 	user.Password = hashedPassword
+	user.MustChangePassword = false
+	if err := h.users.Update(ctx, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando usuario"})
+		return
+	}
 
-	if err := models.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando usuario"})
+	if _, err := revokeSessions(ctx, h.refreshTokens, h.revokedTokens, user.ID, ""); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revocando sesiones"})
+		return
+	}
+	response, err := h.issueTokens(ctx, *user, c.GetString("sessionID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generando token"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Usuario registrado exitosamente",
-		"user": gin.H{
-			"id":       user.ID,
-			"username": user.Username,
-			"role":     user.Role,
-		},
-	})
+	createAuditLog(ctx, h.audits, user.ID, user.AlchemistID, "PASSWORD_CHANGE", "user",
+		fmt.Sprintf("Contraseña cambiada por %s", user.Username))
+
+	response["message"] = "Contraseña actualizada"
+	c.JSON(http.StatusOK, response)
 }
 
-func GetProfile(c *gin.Context) {
+func (h *AuthHandler) GetProfile(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuario no autenticado"})
 		return
 	}
 
-	var user models.User
-	if err := models.DB.Preload("Alchemist").First(&user, userID).Error; err != nil {
+	user, err := h.users.Get(c.Request.Context(), userID.(uint))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"user": gin.H{
-			"id":         user.ID,
-			"username":   user.Username,
-			"role":       user.Role,
-			"alchemist":  user.Alchemist,
-			"created_at": user.CreatedAt,
+			"id":                   user.ID,
+			"username":             user.Username,
+			"role":                 user.Role,
+			"alchemist":            user.Alchemist,
+			"must_change_password": user.MustChangePassword,
+			"mfa_enabled":          user.TOTPEnabled,
+			"mfa_required":         h.mfaRequired(user.Role) && user.OIDCSubject == "",
+			"last_login_at":        user.LastLoginAt,
+			"created_at":           user.CreatedAt,
 		},
 	})
 }
+
+// JWKS publica las claves públicas con que se verifican los access tokens,
+// incluidas las programadas para firmar más adelante.
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS(time.Now()))
+}
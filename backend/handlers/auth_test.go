@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"amestris-backend/config"
+	"amestris-backend/password"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLockoutForDoublesUpToMax(t *testing.T) {
+	env := newTestEnv(t)
+	env.h.Auth.lockout = config.LockoutConfig{
+		Threshold:   3,
+		Duration:    config.Duration(time.Minute),
+		MaxDuration: config.Duration(10 * time.Minute),
+	}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 0},
+		{2, 0},
+		{3, time.Minute},
+		{4, 2 * time.Minute},
+		{5, 4 * time.Minute},
+		{6, 8 * time.Minute},
+		{7, 10 * time.Minute},
+		{50, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := env.h.Auth.lockoutFor(tt.attempts); got != tt.want {
+			t.Errorf("lockoutFor(%d) = %s, esperado %s", tt.attempts, got, tt.want)
+		}
+	}
+
+	env.h.Auth.lockout.Threshold = 0
+	if got := env.h.Auth.lockoutFor(100); got != 0 {
+		t.Errorf("con el bloqueo desactivado lockoutFor = %s", got)
+	}
+}
+
+func TestLoginLocksAccountAtThreshold(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser("edward_elric", "alchemist", nil)
+	env.addUser("admin", "admin", nil)
+	admin := env.login("admin")
+	threshold := env.settings.Lockout.Threshold
+
+	wrong := gin.H{"username": "edward_elric", "password": "contraseña-incorrecta"}
+	for i := 1; i <= threshold; i++ {
+		if w := env.request(http.MethodPost, "/login", "", wrong); w.Code != http.StatusUnauthorized {
+			t.Fatalf("intento %d: %d, esperado 401", i, w.Code)
+		}
+		if i == threshold-1 && env.audits("UNAUTHORIZED_ACCESS") != 0 {
+			t.Fatal("bloqueo auditado antes del umbral")
+		}
+	}
+	if n := env.audits("UNAUTHORIZED_ACCESS"); n != 1 {
+		t.Errorf("%d bloqueos auditados, esperado 1", n)
+	}
+
+	// Bloqueada, ni la contraseña correcta sirve
+	w := env.request(http.MethodPost, "/login", "", gin.H{"username": "edward_elric", "password": testPassword})
+	if w.Code != http.StatusLocked {
+		t.Fatalf("cuenta bloqueada: %d, esperado 423", w.Code)
+	}
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || retryAfter <= 0 || retryAfter > int(env.settings.Lockout.Duration.Std().Seconds()) {
+		t.Errorf("Retry-After = %q", w.Header().Get("Retry-After"))
+	}
+
+	// Un administrador la desbloquea
+	if w := env.request(http.MethodPost, fmt.Sprintf("/api/admin/users/%d/unlock", user.ID), admin, nil); w.Code != http.StatusOK {
+		t.Fatalf("desbloqueo: %d %s", w.Code, w.Body)
+	}
+	if got, _ := env.store.Users.Get(ctx, user.ID); got.LockedUntil != nil || got.FailedLogins != 0 {
+		t.Errorf("tras desbloquear: locked_until=%v failed_logins=%d", got.LockedUntil, got.FailedLogins)
+	}
+	env.login("edward_elric")
+}
+
+func TestResetPasswordUnlocksAccount(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser("edward_elric", "alchemist", nil)
+	env.addUser("admin", "admin", nil)
+	admin := env.login("admin")
+
+	until := time.Now().Add(time.Hour)
+	for range env.settings.Lockout.Threshold {
+		env.store.Users.RecordLoginFailure(ctx, user.ID, time.Now())
+	}
+	if err := env.store.Users.SetLockedUntil(ctx, user.ID, &until); err != nil {
+		t.Fatal(err)
+	}
+
+	w := env.request(http.MethodPost, fmt.Sprintf("/api/admin/users/%d/reset-password", user.ID), admin, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("reset-password: %d %s", w.Code, w.Body)
+	}
+	if got, _ := env.store.Users.Get(ctx, user.ID); got.LockedUntil != nil || got.FailedLogins != 0 {
+		t.Errorf("tras restablecer: locked_until=%v failed_logins=%d", got.LockedUntil, got.FailedLogins)
+	}
+	w = env.request(http.MethodPost, "/login", "", gin.H{"username": "edward_elric", "password": decode(t, w)["password"]})
+	if w.Code != http.StatusOK {
+		t.Errorf("login con la contraseña nueva: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestLoginRehashesLegacyPassword(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser("edward_elric", "alchemist", nil)
+
+	// El algoritmo actual pasa a ser argon2id; bcrypt queda como antiguo
+	env.h.Auth.passwords.Hasher = password.Hasher{
+		Current: password.Argon2id{MemoryKiB: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+		Legacy:  []password.Scheme{password.Bcrypt{Cost: 4}},
+	}
+	env.login("edward_elric")
+
+	got, _ := env.store.Users.Get(ctx, user.ID)
+	if !env.h.Auth.passwords.Hasher.Current.Matches(got.Password) {
+		t.Errorf("hash no recalculado: %s", got.Password)
+	}
+	env.login("edward_elric")
+}
+
+func TestRehashKeepsConcurrentChanges(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.addUser("edward_elric", "alchemist", nil)
+
+	// Copia cargada por el login antes de que un administrador desactive la
+	// cuenta
+	stale, _ := env.store.Users.FindByUsername(ctx, "edward_elric")
+	fresh, _ := env.store.Users.FindByUsername(ctx, "edward_elric")
+	fresh.Disabled = true
+	fresh.Role = "supervisor"
+	if err := env.store.Users.Update(ctx, fresh); err != nil {
+		t.Fatal(err)
+	}
+
+	env.h.Auth.rehashPassword(ctx, stale, testPassword)
+
+	got, _ := env.store.Users.Get(ctx, stale.ID)
+	if !got.Disabled || got.Role != "supervisor" {
+		t.Errorf("el rehash deshizo cambios: disabled=%v role=%s", got.Disabled, got.Role)
+	}
+	if got.Password != stale.Password || !env.settings.Passwords.Matches(testPassword, got.Password) {
+		t.Error("no se guardó el hash nuevo")
+	}
+}
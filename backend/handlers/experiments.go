@@ -3,14 +3,32 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"slices"
 
+	"amestris-backend/metrics"
 	"amestris-backend/models"
+	"amestris-backend/query"
+	"amestris-backend/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-func CreateExperimentRequest(c *gin.Context) {
-	userID, _ := c.Get("userID")
+type ExperimentHandler struct {
+	experiments repository.ExperimentRepository
+	audits      repository.AuditRepository
+}
+
+func NewExperimentHandler(experiments repository.ExperimentRepository, audits repository.AuditRepository) *ExperimentHandler {
+	return &ExperimentHandler{experiments: experiments, audits: audits}
+}
+
+// CreateExperimentRequest crea una solicitud a nombre del alquimista
+// vinculado al usuario.
+func (h *ExperimentHandler) CreateExperimentRequest(c *gin.Context) {
+	alchemist, ok := requireAlchemist(c)
+	if !ok {
+		return
+	}
 
 	var experiment models.ExperimentRequest
 	if err := c.BindJSON(&experiment); err != nil {
@@ -18,44 +36,84 @@ func CreateExperimentRequest(c *gin.Context) {
 		return
 	}
 
-	experiment.AlchemistID = userID.(uint)
+	experiment.AlchemistID = alchemist.ID
+	experiment.Alchemist = *alchemist
 	experiment.Status = "pending"
 
-	if err := models.DB.Create(&experiment).Error; err != nil {
+	if err := h.experiments.Create(c.Request.Context(), &experiment); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando solicitud"})
 		return
 	}
 
 	// Log de auditoría
-	CreateAuditLog(experiment.AlchemistID, "EXPERIMENT_REQUEST", "experiment",
+	auditRequest(c, h.audits, "EXPERIMENT_REQUEST", "experiment",
 		fmt.Sprintf("Nueva solicitud: %s - Riesgo: %s", experiment.Title, experiment.RiskLevel))
 
 	c.JSON(http.StatusCreated, experiment)
 }
 
-func GetExperimentRequests(c *gin.Context) {
-	userRole, _ := c.Get("role")
-	userID, _ := c.Get("userID")
+// experimentQuery son los filtros y órdenes admitidos en GET
+// /api/experiments.
+var experimentQuery = &query.Schema[models.ExperimentRequest]{
+	Fields: map[string]query.Field[models.ExperimentRequest]{
+		"id":           {Column: "id", Type: query.Int, Sort: true, Value: func(e models.ExperimentRequest) any { return e.ID }},
+		"title":        {Column: "title", Type: query.Text, Sort: true, Value: func(e models.ExperimentRequest) any { return e.Title }},
+		"status":       {Column: "status", Type: query.Text, Filter: true, Sort: true, Value: func(e models.ExperimentRequest) any { return e.Status }},
+		"risk_level":   {Column: "risk_level", Type: query.Text, Filter: true, Sort: true, Value: func(e models.ExperimentRequest) any { return e.RiskLevel }},
+		"alchemist_id": {Column: "alchemist_id", Type: query.Int, Filter: true, Value: func(e models.ExperimentRequest) any { return e.AlchemistID }},
+		"created_at":   {Column: "created_at", Type: query.Time, Sort: true, Value: func(e models.ExperimentRequest) any { return e.CreatedAt }},
+		"updated_at":   {Column: "updated_at", Type: query.Time, Sort: true, Value: func(e models.ExperimentRequest) any { return e.UpdatedAt }},
+	},
+	DefaultSort: "id",
+}
 
-	var experiments []models.ExperimentRequest
-	query := models.DB.Preload("Alchemist")
+func (h *ExperimentHandler) GetExperimentRequests(c *gin.Context) {
+	list, ok := parseList(c, experimentQuery)
+	if !ok {
+		return
+	}
 
-	// Solo supervisores y admin ven todas las solicitudes
-	if userRole == "alchemist" {
-		query = query.Where("alchemist_id = ?", userID)
+	// Sin permiso para verlas todas solo se ven las solicitudes propias
+	if !canViewAllExperiments(c) {
+		alchemist, ok := requireAlchemist(c)
+		if !ok {
+			return
+		}
+		list.Where("alchemist_id", alchemist.ID)
 	}
 
-	if err := query.Find(&experiments).Error; err != nil {
+	page, err := h.experiments.Page(c.Request.Context(), list)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo solicitudes"})
 		return
 	}
-
-	c.JSON(http.StatusOK, experiments)
+	respondPage(c, page)
 }
 
-func UpdateExperimentStatus(c *gin.Context) {
-	id := c.Param("id")
+// GetExperimentRequest devuelve una solicitud a su autor o a un revisor.
+func (h *ExperimentHandler) GetExperimentRequest(c *gin.Context) {
+	id, ok := parseID(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Solicitud no encontrada"})
+		return
+	}
 
+	experiment, err := h.experiments.Get(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Solicitud no encontrada"})
+		return
+	}
+	if !canViewExperiment(c, experiment) {
+		denyAccess(c, h.audits, "experiment", fmt.Sprintf("ver la solicitud %d", experiment.ID))
+		return
+	}
+
+	c.JSON(http.StatusOK, experiment)
+}
+
+// UpdateExperimentStatus permite a los revisores decidir sobre una solicitud
+// y a su autor retirarla; ver canReviewExperiment.
+func (h *ExperimentHandler) UpdateExperimentStatus(c *gin.Context) {
 	var updateData struct {
 		Status string `json:"status"`
 		Notes  string `json:"notes"`
@@ -65,18 +123,40 @@ func UpdateExperimentStatus(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
 		return
 	}
+	if !slices.Contains(models.ExperimentStatuses, updateData.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Estado de solicitud desconocido: %s", updateData.Status)})
+		return
+	}
 
-	var experiment models.ExperimentRequest
-	if err := models.DB.First(&experiment, id).Error; err != nil {
+	id, ok := parseID(c.Param("id"))
+	if !ok {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Solicitud no encontrada"})
 		return
 	}
 
+	experiment, err := h.experiments.Get(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Solicitud no encontrada"})
+		return
+	}
+	if !canReviewExperiment(c, experiment, updateData.Status) {
+		denyAccess(c, h.audits, "experiment", fmt.Sprintf("cambiar a %q la solicitud %d", updateData.Status, experiment.ID))
+		return
+	}
+
+	previousStatus := experiment.Status
 	experiment.Status = updateData.Status
-	models.DB.Save(&experiment)
+	if err := h.experiments.Update(c.Request.Context(), experiment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando solicitud"})
+		return
+	}
+
+	if experiment.Status == "approved" && previousStatus != "approved" {
+		metrics.ExperimentApprovals.WithLabelValues(experiment.RiskLevel).Inc()
+	}
 
 	// Log de auditoría
-	CreateAuditLog(experiment.AlchemistID, "EXPERIMENT_UPDATE", "experiment",
+	auditRequest(c, h.audits, "EXPERIMENT_UPDATE", "experiment",
 		fmt.Sprintf("Solicitud %s actualizada a: %s - %s", experiment.Title, updateData.Status, updateData.Notes))
 
 	c.JSON(http.StatusOK, experiment)
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"amestris-backend/config"
+	"amestris-backend/oidc"
+	"amestris-backend/password"
+	"amestris-backend/repository"
+	"amestris-backend/signing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Settings son las dependencias de los handlers que salen de la
+// configuración: claves de firma, duraciones y políticas. NewSettings las
+// construye al arrancar y New las reparte entre los constructores.
+type Settings struct {
+	Keys         *signing.KeySet
+	AccessTTL    time.Duration
+	RefreshTTL   time.Duration
+	Passwords    Passwords
+	Lockout      config.LockoutConfig
+	MFA          config.MFAConfig
+	Registration config.RegistrationConfig
+	LocalLogin   bool
+	OIDC         config.OIDCConfig
+	// OIDCProvider es nil si el inicio de sesión con OIDC está desactivado.
+	OIDCProvider *oidc.Provider
+}
+
+// NewSettings carga las claves JWT y construye el resto de dependencias a
+// partir de la configuración.
+func NewSettings(cfg *config.Config) (Settings, error) {
+	keys, err := loadSigningKeys(cfg.JWT)
+	if err != nil {
+		return Settings{}, fmt.Errorf("claves JWT: %w", err)
+	}
+	settings := Settings{
+		Keys:         keys,
+		AccessTTL:    cfg.JWT.TTL.Std(),
+		RefreshTTL:   cfg.JWT.RefreshTTL.Std(),
+		Passwords:    NewPasswords(cfg.Security),
+		Lockout:      cfg.Security.Lockout,
+		MFA:          cfg.Security.MFA,
+		Registration: cfg.Registration,
+		LocalLogin:   cfg.Security.LocalLogin,
+		OIDC:         cfg.OIDC,
+	}
+	if cfg.OIDC.Enabled {
+		settings.OIDCProvider = oidc.New(oidc.Config{
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDC.RedirectURL,
+			Scopes:       cfg.OIDC.Scopes,
+		})
+	}
+	return settings, nil
+}
+
+// Passwords calcula los hashes con el algoritmo configurado y aplica la
+// política de contraseñas.
+type Passwords struct {
+	Hasher password.Hasher
+	Policy password.Policy
+}
+
+// NewPasswords calcula los hashes nuevos con el algoritmo configurado y
+// acepta también los del otro, que se recalculan al iniciar sesión.
+func NewPasswords(cfg config.SecurityConfig) Passwords {
+	argon := password.DefaultArgon2id
+	argon.MemoryKiB = uint32(cfg.Argon2.MemoryKiB)
+	argon.Iterations = uint32(cfg.Argon2.Iterations)
+	argon.Parallelism = uint8(cfg.Argon2.Parallelism)
+	bcrypt := password.Bcrypt{Cost: cfg.BcryptCost}
+
+	hasher := password.Hasher{Current: argon, Legacy: []password.Scheme{bcrypt}}
+	if cfg.PasswordHash == "bcrypt" {
+		hasher = password.Hasher{Current: bcrypt, Legacy: []password.Scheme{argon}}
+	}
+	return Passwords{Hasher: hasher, Policy: password.Policy(cfg.Password)}
+}
+
+func (p Passwords) Hash(plain string) (string, error) {
+	return p.Hasher.Hash(plain)
+}
+
+func (p Passwords) Matches(plain, hash string) bool {
+	ok, _ := p.Hasher.Verify(plain, hash)
+	return ok
+}
+
+// check comprueba la política de contraseñas y, si no se cumple, responde
+// 400 con la lista de requisitos incumplidos.
+func (p Passwords) check(c *gin.Context, plain string) bool {
+	if problems := p.Policy.Check(plain); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    "La contraseña no cumple la política",
+			"problems": problems,
+		})
+		return false
+	}
+	return true
+}
+
+// loadSigningKeys carga las claves de jwt.keys o, si no hay, usa el secreto
+// compartido con HS256.
+func loadSigningKeys(cfg config.JWTConfig) (*signing.KeySet, error) {
+	if len(cfg.Keys) == 0 {
+		return signing.NewHMAC([]byte(cfg.Secret)), nil
+	}
+
+	keys := make([]signing.Key, 0, len(cfg.Keys))
+	for _, spec := range cfg.Keys {
+		key, err := signing.LoadKey(spec.ID, spec.File, spec.ActiveFrom)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	set, err := signing.NewKeySet(keys, cfg.KeyGrace.Std())
+	if err != nil {
+		return nil, err
+	}
+
+	active, ok := set.Signer(time.Now())
+	if !ok {
+		return nil, errors.New("ninguna clave está activa todavía")
+	}
+	slog.Info("claves JWT cargadas", "keys", len(keys), "active_kid", active.ID, "alg", active.Algorithm())
+	return set, nil
+}
+
+// TaskRunner lanza trabajo en segundo plano que debe completarse antes de
+// apagar el servidor. Lo implementa lifecycle.Supervisor.
+type TaskRunner interface {
+	Go(name string, fn func(ctx context.Context)) bool
+}
+
+// Handlers agrupa los handlers HTTP de cada agregado.
+type Handlers struct {
+	Auth            *AuthHandler
+	OIDC            *OIDCHandler
+	Users           *UserHandler
+	Registrations   *RegistrationHandler
+	ServiceAccounts *ServiceAccountHandler
+	Roles           *RoleHandler
+	Alchemists      *AlchemistHandler
+	Missions        *MissionHandler
+	Experiments     *ExperimentHandler
+	Materials       *MaterialHandler
+	Transmutations  *TransmutationHandler
+	Audit           *AuditHandler
+	Search          *SearchHandler
+}
+
+// New construye todos los handlers sobre los repositorios del store.
+func New(store *repository.Store, tasks TaskRunner, settings Settings) *Handlers {
+	auth := NewAuthHandler(store.Users, store.RefreshTokens, store.RevokedTokens, store.RecoveryCodes, store.Audits, settings)
+	return &Handlers{
+		Auth:            auth,
+		OIDC:            NewOIDCHandler(auth, store.Users, store.Alchemists, store.Roles, store.Audits, settings),
+		Users:           NewUserHandler(store.Users, store.Alchemists, store.RefreshTokens, store.RevokedTokens, store.RecoveryCodes, store.Roles, store.Audits, settings.Passwords),
+		Registrations:   NewRegistrationHandler(store.Users, store.Alchemists, store.Invitations, store.Roles, store.Audits, settings.Passwords, settings.Registration),
+		ServiceAccounts: NewServiceAccountHandler(store.Users, store.APIKeys, store.Roles, store.Audits),
+		Roles:           NewRoleHandler(store.Roles, store.Users, store.Audits),
+		Alchemists:      NewAlchemistHandler(store.Alchemists, store.Users, store.Audits, settings.Passwords),
+		Missions:        NewMissionHandler(store.Missions, store.Alchemists, store.Audits),
+		Experiments:     NewExperimentHandler(store.Experiments, store.Audits),
+		Materials:       NewMaterialHandler(store.Materials, store.Audits),
+		Transmutations:  NewTransmutationHandler(store.Transmutations, store.Alchemists, tasks),
+		Audit:           NewAuditHandler(store.Audits, store.Experiments, store.Transmutations),
+		Search:          NewSearchHandler(store.Search),
+	}
+}
+
+// parseID convierte el parámetro :id de la ruta.
+func parseID(raw string) (uint, bool) {
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"amestris-backend/config"
+	"amestris-backend/middleware"
+	"amestris-backend/models"
+	"amestris-backend/repository"
+	"amestris-backend/repository/memory"
+	"amestris-backend/signing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testPassword cumple la política por defecto.
+const testPassword = "Alquimia-de-prueba-1"
+
+// syncTasks ejecuta las tareas de fondo en el momento, como durante el
+// apagado, para que los tests vean su resultado.
+type syncTasks struct{}
+
+func (syncTasks) Go(name string, fn func(ctx context.Context)) bool { return false }
+
+// testEnv son los handlers sobre los repositorios en memoria, con las rutas
+// montadas como en serve.go.
+type testEnv struct {
+	t        *testing.T
+	store    *repository.Store
+	settings Settings
+	h        *Handlers
+	router   *gin.Engine
+}
+
+func testSettings() Settings {
+	cfg := config.Default()
+	// bcrypt con el coste mínimo para que los tests sean rápidos
+	cfg.Security.PasswordHash = "bcrypt"
+	cfg.Security.BcryptCost = 4
+	return Settings{
+		Keys:         signing.NewHMAC([]byte("secreto-de-prueba")),
+		AccessTTL:    cfg.JWT.TTL.Std(),
+		RefreshTTL:   cfg.JWT.RefreshTTL.Std(),
+		Passwords:    NewPasswords(cfg.Security),
+		Lockout:      cfg.Security.Lockout,
+		MFA:          cfg.Security.MFA,
+		Registration: cfg.Registration,
+		LocalLogin:   true,
+		OIDC:         cfg.OIDC,
+	}
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	return newTestEnvWith(t, testSettings())
+}
+
+func newTestEnvWith(t *testing.T, settings Settings) *testEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore()
+	h := New(store, syncTasks{}, settings)
+	router := gin.New()
+
+	authenticate := middleware.AuthMiddleware(settings.Keys, store.RevokedTokens, nil)
+	authenticateKey := middleware.AuthMiddleware(settings.Keys, store.RevokedTokens, h.ServiceAccounts)
+	principal := middleware.LoadPrincipal(h.Roles)
+
+	router.POST("/login", h.Auth.Login)
+	router.POST("/login/mfa", h.Auth.LoginMFA)
+	router.GET("/login/oidc", h.OIDC.Start)
+	router.GET("/login/oidc/callback", h.OIDC.Callback)
+	router.POST("/register", h.Registrations.Register)
+	router.POST("/token/refresh", h.Auth.RefreshToken)
+	router.POST("/logout", authenticate, h.Auth.Logout)
+
+	profile := router.Group("/api/profile", authenticate, principal)
+	profile.GET("", h.Auth.GetProfile)
+	profile.POST("/mfa/enroll", h.Auth.EnrollMFA)
+	profile.POST("/mfa/confirm", h.Auth.ConfirmMFA)
+
+	service := router.Group("/api", authenticateKey, middleware.PasswordChanged(), middleware.MFAEnrolled(), principal)
+	service.GET("/missions", middleware.RequireScope("missions:read"), h.Missions.GetMissions)
+	service.GET("/experiments", middleware.RequireScope("experiments:read"), h.Experiments.GetExperimentRequests)
+	service.GET("/search", h.Search.Search)
+	service.POST("/transmute", middleware.RequireScope("transmute"), h.Transmutations.HandleTransmutation)
+
+	auth := router.Group("/api", authenticate, middleware.PasswordChanged(), middleware.MFAEnrolled(), principal)
+	auth.PUT("/alchemists/:id", middleware.RequirePermission("alchemists:write"), h.Alchemists.UpdateAlchemist)
+	auth.PUT("/missions/:id/status", h.Missions.UpdateMissionStatus)
+	auth.PUT("/experiments/:id/status", h.Experiments.UpdateExperimentStatus)
+	users := auth.Group("/admin/users", middleware.RequirePermission("users:manage"))
+	users.PUT("/:id/role", h.Users.UpdateUserRole)
+	users.POST("/:id/reset-password", h.Users.ResetUserPassword)
+	users.POST("/:id/unlock", h.Users.UnlockUser)
+	invitations := auth.Group("/admin/invitations", middleware.RequirePermission("invitations:manage"))
+	invitations.POST("", h.Registrations.CreateInvitation)
+	serviceAccounts := auth.Group("/admin/service-accounts", middleware.RequirePermission("service-accounts:manage"))
+	serviceAccounts.POST("", h.ServiceAccounts.CreateServiceAccount)
+	serviceAccounts.POST("/:id/keys", h.ServiceAccounts.CreateAPIKey)
+	serviceAccounts.DELETE("/:id/keys/:keyId", h.ServiceAccounts.RevokeAPIKey)
+	roles := auth.Group("/admin", middleware.RequirePermission("roles:manage"))
+	roles.POST("/roles", h.Roles.CreateRole)
+	roles.PUT("/roles/:name", h.Roles.UpdateRole)
+	roles.DELETE("/roles/:name", h.Roles.DeleteRole)
+
+	return &testEnv{t: t, store: store, settings: settings, h: h, router: router}
+}
+
+func (e *testEnv) addAlchemist(name string) *models.Alchemist {
+	e.t.Helper()
+	alchemist := &models.Alchemist{Name: name, Status: "Activo"}
+	if err := e.store.Alchemists.Create(context.Background(), alchemist); err != nil {
+		e.t.Fatal(err)
+	}
+	return alchemist
+}
+
+// addUser crea un usuario activo con testPassword.
+func (e *testEnv) addUser(username, role string, alchemist *models.Alchemist) *models.User {
+	e.t.Helper()
+	hashed, err := e.settings.Passwords.Hash(testPassword)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	user := &models.User{Username: username, Password: hashed, Role: role}
+	if alchemist != nil {
+		user.AlchemistID = &alchemist.ID
+	}
+	if err := e.store.Users.Create(context.Background(), user); err != nil {
+		e.t.Fatal(err)
+	}
+	return user
+}
+
+// login inicia sesión con testPassword y devuelve el access token.
+func (e *testEnv) login(username string) string {
+	e.t.Helper()
+	w := e.request(http.MethodPost, "/login", "", gin.H{"username": username, "password": testPassword})
+	if w.Code != http.StatusOK {
+		e.t.Fatalf("login de %s: %d %s", username, w.Code, w.Body)
+	}
+	return decode(e.t, w)["token"].(string)
+}
+
+func (e *testEnv) request(method, path, token string, body any) *httptest.ResponseRecorder {
+	e.t.Helper()
+	reader := bytes.NewReader(nil)
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, req)
+	return w
+}
+
+// audits devuelve cuántos registros de auditoría hay con la acción dada.
+func (e *testEnv) audits(action string) int64 {
+	e.t.Helper()
+	list, err := auditQuery.Parse(url.Values{"action": {action}})
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	page, err := e.store.Audits.Page(context.Background(), list)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return page.Total
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("respuesta no JSON: %s", w.Body)
+	}
+	return body
+}
+
+func TestLoginAndAuthenticatedRequest(t *testing.T) {
+	env := newTestEnv(t)
+	edward := env.addAlchemist("Edward Elric")
+	env.addUser("edward_elric", "alchemist", edward)
+
+	if w := env.request(http.MethodGet, "/api/missions", "", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("sin token: %d, esperado 401", w.Code)
+	}
+
+	token := env.login("edward_elric")
+	w := env.request(http.MethodGet, "/api/missions", token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("con token: %d %s", w.Code, w.Body)
+	}
+
+	w = env.request(http.MethodGet, "/api/profile", token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("perfil: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestLoginRejectsWrongPassword(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser("roy_mustang", "supervisor", nil)
+
+	w := env.request(http.MethodPost, "/login", "", gin.H{"username": "roy_mustang", "password": "incorrecta"})
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("contraseña incorrecta: %d, esperado 401", w.Code)
+	}
+	user, _ := env.store.Users.FindByUsername(context.Background(), "roy_mustang")
+	if user.FailedLogins != 1 {
+		t.Errorf("FailedLogins = %d, esperado 1", user.FailedLogins)
+	}
+
+	w = env.request(http.MethodPost, "/login", "", gin.H{"username": "nadie", "password": testPassword})
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("usuario inexistente: %d, esperado 401", w.Code)
+	}
+}
+
+func TestDisabledUserCannotUseToken(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.addUser("alphonse_elric", "alchemist", env.addAlchemist("Alphonse Elric"))
+	token := env.login("alphonse_elric")
+
+	user.Disabled = true
+	if err := env.store.Users.Update(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := env.request(http.MethodGet, "/api/missions", token, nil); w.Code == http.StatusOK {
+		t.Fatal("un usuario deshabilitado sigue accediendo con su token")
+	}
+	if w := env.request(http.MethodGet, "/api/profile", token, nil); w.Code == http.StatusOK {
+		t.Fatal("un usuario deshabilitado sigue accediendo a su perfil")
+	}
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Pinger comprueba la conexión a la base de datos. Lo implementa *sql.DB.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// MigrationChecker informa la versión del esquema aplicada y la esperada por
+// el binario. Lo implementa *migrations.Migrator.
+type MigrationChecker interface {
+	Current(ctx context.Context) (int, error)
+	Latest() int
+}
+
+type HealthHandler struct {
+	db            Pinger
+	migrations    MigrationChecker
+	audit         *AuditHandler
+	dbTimeout     time.Duration
+	auditInterval time.Duration
+}
+
+func NewHealthHandler(db Pinger, migrations MigrationChecker, audit *AuditHandler, dbTimeout, auditInterval time.Duration) *HealthHandler {
+	return &HealthHandler{
+		db:            db,
+		migrations:    migrations,
+		audit:         audit,
+		dbTimeout:     dbTimeout,
+		auditInterval: auditInterval,
+	}
+}
+
+// Liveness indica solo que el proceso responde.
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "message": "Sistema de Alquimia operativo"})
+}
+
+// Readiness comprueba las dependencias necesarias para atender tráfico y
+// devuelve 503 con el estado de cada componente si alguno falla. La ruta es
+// pública: los errores de la base de datos solo van al log.
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	components := gin.H{
+		"database":          h.checkDatabase(ctx),
+		"migrations":        h.checkMigrations(ctx),
+		"background_audits": h.checkBackgroundAudits(),
+	}
+
+	status, code := "ok", http.StatusOK
+	for _, component := range components {
+		if component.(gin.H)["status"] != "ok" {
+			status, code = "degraded", http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	c.JSON(code, gin.H{"status": status, "components": components})
+}
+
+func (h *HealthHandler) checkDatabase(ctx context.Context) gin.H {
+	ctx, cancel := context.WithTimeout(ctx, h.dbTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := h.db.PingContext(ctx); err != nil {
+		slog.ErrorContext(ctx, "readiness: la base de datos no responde", "error", err)
+		return gin.H{"status": "down"}
+	}
+	return gin.H{"status": "ok", "latency_ms": time.Since(start).Milliseconds()}
+}
+
+func (h *HealthHandler) checkMigrations(ctx context.Context) gin.H {
+	ctx, cancel := context.WithTimeout(ctx, h.dbTimeout)
+	defer cancel()
+
+	expected := h.migrations.Latest()
+	current, err := h.migrations.Current(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "readiness: no se pudo leer la versión del esquema", "error", err)
+		return gin.H{"status": "down", "expected": expected}
+	}
+
+	result := gin.H{"status": "ok", "current": current, "expected": expected}
+	if current != expected {
+		result["status"] = "outdated"
+	}
+	return result
+}
+
+// checkBackgroundAudits exige que el worker haya completado una verificación
+// en los últimos dos intervalos; recién iniciado se le concede el mismo plazo.
+func (h *HealthHandler) checkBackgroundAudits() gin.H {
+	running, startedAt, lastSuccess := h.audit.WorkerStatus()
+	if !running {
+		return gin.H{"status": "down", "error": "el worker de auditoría no está en ejecución"}
+	}
+
+	deadline := 2 * h.auditInterval
+	result := gin.H{"status": "ok", "interval": h.auditInterval.String()}
+
+	if lastSuccess.IsZero() {
+		result["last_success"] = nil
+		if time.Since(startedAt) > deadline {
+			result["status"] = "stale"
+		}
+		return result
+	}
+
+	result["last_success"] = lastSuccess.UTC()
+	if time.Since(lastSuccess) > deadline {
+		result["status"] = "stale"
+	}
+	return result
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errDatabase imita un error del driver, con datos de la conexión.
+var errDatabase = errors.New(`failed to connect to host=db user=amestris database=amestris: password authentication failed`)
+
+type fakeDatabase struct {
+	err     error
+	current int
+}
+
+func (f fakeDatabase) PingContext(ctx context.Context) error { return f.err }
+
+func (f fakeDatabase) Current(ctx context.Context) (int, error) { return f.current, f.err }
+
+func (f fakeDatabase) Latest() int { return 17 }
+
+func readiness(t *testing.T, db fakeDatabase) (int, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	audit := newTestEnv(t).h.Audit
+	health := NewHealthHandler(db, db, audit, time.Second, time.Minute)
+
+	router := gin.New()
+	router.GET("/readyz", health.Readiness)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	return w.Code, w.Body.String()
+}
+
+func TestReadinessHidesDatabaseErrors(t *testing.T) {
+	code, body := readiness(t, fakeDatabase{err: errDatabase})
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("base de datos caída: %d, esperado 503", code)
+	}
+	for _, leaked := range []string{"host=db", "amestris", "password"} {
+		if strings.Contains(body, leaked) {
+			t.Errorf("la respuesta pública contiene %q: %s", leaked, body)
+		}
+	}
+	if !strings.Contains(body, `"expected":17`) {
+		t.Errorf("falta la versión esperada: %s", body)
+	}
+}
+
+func TestReadinessReportsOutdatedSchema(t *testing.T) {
+	code, body := readiness(t, fakeDatabase{current: 16})
+	if code != http.StatusServiceUnavailable || !strings.Contains(body, `"outdated"`) || !strings.Contains(body, `"current":16`) {
+		t.Errorf("esquema atrasado: %d %s", code, body)
+	}
+}
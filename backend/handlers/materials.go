@@ -4,90 +4,129 @@ import (
 	"net/http"
 
 	"amestris-backend/models"
+	"amestris-backend/query"
+	"amestris-backend/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-func GetMaterials(c *gin.Context) {
-	var materials []models.Material
-	if err := models.DB.Find(&materials).Error; err != nil {
+type MaterialHandler struct {
+	materials repository.MaterialRepository
+	audits    repository.AuditRepository
+}
+
+func NewMaterialHandler(materials repository.MaterialRepository, audits repository.AuditRepository) *MaterialHandler {
+	return &MaterialHandler{materials: materials, audits: audits}
+}
+
+// materialQuery son los filtros y órdenes admitidos en GET /api/materials.
+var materialQuery = &query.Schema[models.Material]{
+	Fields: map[string]query.Field[models.Material]{
+		"id":           {Column: "id", Type: query.Int, Sort: true, Value: func(m models.Material) any { return m.ID }},
+		"name":         {Column: "name", Type: query.Text, Sort: true, Value: func(m models.Material) any { return m.Name }},
+		"type":         {Column: "type", Type: query.Text, Filter: true, Sort: true, Value: func(m models.Material) any { return m.Type }},
+		"rarity":       {Column: "rarity", Type: query.Text, Filter: true, Sort: true, Value: func(m models.Material) any { return m.Rarity }},
+		"danger_level": {Column: "danger_level", Type: query.Text, Filter: true, Sort: true, Value: func(m models.Material) any { return m.DangerLevel }},
+		"base_value":   {Column: "base_value", Type: query.Float, Sort: true, Value: func(m models.Material) any { return m.BaseValue }},
+		"created_at":   {Column: "created_at", Type: query.Time, Sort: true, Value: func(m models.Material) any { return m.CreatedAt }},
+	},
+	DefaultSort: "id",
+}
+
+func (h *MaterialHandler) GetMaterials(c *gin.Context) {
+	list, ok := parseList(c, materialQuery)
+	if !ok {
+		return
+	}
+	page, err := h.materials.Page(c.Request.Context(), list)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo materiales"})
 		return
 	}
-	c.JSON(http.StatusOK, materials)
+	respondPage(c, page)
 }
 
-func CreateMaterial(c *gin.Context) {
+func (h *MaterialHandler) CreateMaterial(c *gin.Context) {
 	var material models.Material
 	if err := c.BindJSON(&material); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
 		return
 	}
 
+	ctx := c.Request.Context()
+
 	// Verificar si el material ya existe
-	var existing models.Material
-	if err := models.DB.Where("name = ?", material.Name).First(&existing).Error; err == nil {
+	if _, err := h.materials.FindByName(ctx, material.Name); err == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Ya existe un material con ese nombre"})
 		return
 	}
 
-	if err := models.DB.Create(&material).Error; err != nil {
+	if err := h.materials.Create(ctx, &material); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando material"})
 		return
 	}
 
 	// Log de auditoría
-	userID, _ := c.Get("userID")
-	CreateAuditLog(userID.(uint), "MATERIAL_CREATE", "material",
+	auditRequest(c, h.audits, "MATERIAL_CREATE", "material",
 		"Nuevo material creado: "+material.Name)
 
 	c.JSON(http.StatusCreated, material)
 }
 
-func UpdateMaterial(c *gin.Context) {
-	id := c.Param("id")
-	var material models.Material
+func (h *MaterialHandler) UpdateMaterial(c *gin.Context) {
+	id, ok := parseID(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Material no encontrado"})
+		return
+	}
 
-	if err := models.DB.First(&material, id).Error; err != nil {
+	ctx := c.Request.Context()
+
+	material, err := h.materials.Get(ctx, id)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Material no encontrado"})
 		return
 	}
 
-	if err := c.BindJSON(&material); err != nil {
+	if err := c.BindJSON(material); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
 		return
 	}
 
-	if err := models.DB.Save(&material).Error; err != nil {
+	if err := h.materials.Update(ctx, material); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando material"})
 		return
 	}
 
 	// Log de auditoría
-	userID, _ := c.Get("userID")
-	CreateAuditLog(userID.(uint), "MATERIAL_UPDATE", "material",
+	auditRequest(c, h.audits, "MATERIAL_UPDATE", "material",
 		"Material actualizado: "+material.Name)
 
 	c.JSON(http.StatusOK, material)
 }
 
-func DeleteMaterial(c *gin.Context) {
-	id := c.Param("id")
-	var material models.Material
+func (h *MaterialHandler) DeleteMaterial(c *gin.Context) {
+	id, ok := parseID(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Material no encontrado"})
+		return
+	}
+
+	ctx := c.Request.Context()
 
-	if err := models.DB.First(&material, id).Error; err != nil {
+	material, err := h.materials.Get(ctx, id)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Material no encontrado"})
 		return
 	}
 
-	if err := models.DB.Delete(&material).Error; err != nil {
+	if err := h.materials.Delete(ctx, material.ID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error eliminando material"})
 		return
 	}
 
 	// Log de auditoría
-	userID, _ := c.Get("userID")
-	CreateAuditLog(userID.(uint), "MATERIAL_DELETE", "material",
+	auditRequest(c, h.audits, "MATERIAL_DELETE", "material",
 		"Material eliminado: "+material.Name)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Material eliminado exitosamente"})
@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"amestris-backend/models"
+	"amestris-backend/totp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// mfaChallengeTTL es lo que dura el desafío emitido tras validar la
+	// contraseña de un usuario con verificación en dos pasos.
+	mfaChallengeTTL   = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+// mfaChallengeType es la cabecera typ de los desafíos, que AuthMiddleware
+// rechaza por no ser signing.TypeAccess.
+const mfaChallengeType = "mfa+jwt"
+
+// mfaChallengeClaims identifica al usuario que superó el primer paso del
+// login. No sirve como access token: lleva otro typ.
+type mfaChallengeClaims struct {
+	UserID uint `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+// mfaRequired indica si la política exige segundo factor al rol.
+func (h *AuthHandler) mfaRequired(role string) bool {
+	return slices.Contains(h.mfa.RequiredRoles, role)
+}
+
+// mfaSetupRequired indica si el usuario debe activar el segundo factor antes
+// de usar la API. Los usuarios OIDC no: el segundo factor es cosa del
+// proveedor.
+func (h *AuthHandler) mfaSetupRequired(user models.User) bool {
+	return h.mfaRequired(user.Role) && !user.TOTPEnabled && user.OIDCSubject == ""
+}
+
+func (h *AuthHandler) issueMFAChallenge(user models.User) (string, error) {
+	now := time.Now()
+	claims := &mfaChallengeClaims{
+		UserID: user.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        randomToken(16),
+			ExpiresAt: jwt.NewNumericDate(now.Add(mfaChallengeTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "amestris-alchemy-system",
+		},
+	}
+	return h.keys.Sign(claims, mfaChallengeType)
+}
+
+func (h *AuthHandler) parseMFAChallenge(raw string) (*mfaChallengeClaims, error) {
+	claims := &mfaChallengeClaims{}
+	token, err := h.keys.Parse(raw, claims, mfaChallengeType)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid || claims.UserID == 0 || claims.ID == "" {
+		return nil, errors.New("desafío MFA inválido")
+	}
+	return claims, nil
+}
+
+// normalizeRecoveryCode admite el código con o sin guion y en mayúsculas.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// newRecoveryCodes genera códigos de recuperación con el formato
+// xxxxx-xxxxx y sustituye los anteriores del usuario por sus hashes.
+func (h *AuthHandler) newRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		code := hex.EncodeToString(buf)
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashToken(code)
+	}
+	if err := h.recoveryCodes.Replace(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verifyTOTP valida un código de la app de autenticación. Cada paso solo se
+// acepta una vez para que un código interceptado no pueda reutilizarse.
+func (h *AuthHandler) verifyTOTP(ctx context.Context, user *models.User, code string) (bool, error) {
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if !ok || step <= user.TOTPLastStep {
+		return false, nil
+	}
+	user.TOTPLastStep = step
+	if err := h.users.Update(ctx, user); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// verifySecondFactor acepta un código TOTP o, si no lo es, un código de
+// recuperación sin usar. Indica también si se consumió uno de recuperación.
+func (h *AuthHandler) verifySecondFactor(ctx context.Context, user *models.User, code string) (ok, recovery bool, err error) {
+	if ok, err := h.verifyTOTP(ctx, user, code); ok || err != nil {
+		return ok, false, err
+	}
+	ok, err = h.recoveryCodes.Use(ctx, user.ID, hashToken(normalizeRecoveryCode(code)), time.Now())
+	return ok, ok, err
+}
+
+type mfaLoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// LoginMFA completa el login de un usuario con verificación en dos pasos a
+// partir del desafío devuelto por Login.
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	if !h.checkLocalLogin(c) {
+		return
+	}
+
+	var req mfaLoginRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	challenge, err := h.parseMFAChallenge(req.MFAToken)
+	if err == nil {
+		// Cada desafío completa un único login
+		var used bool
+		if used, err = h.revokedTokens.IsRevoked(ctx, challenge.ID); used {
+			err = errors.New("desafío MFA ya usado")
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Desafío de verificación inválido o caducado"})
+		return
+	}
+
+	user, err := h.users.Get(ctx, challenge.UserID)
+	if err != nil || !user.TOTPEnabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Desafío de verificación inválido o caducado"})
+		return
+	}
+
+	now := time.Now()
+	if !checkNotLocked(c, user, now) || !checkActive(c, user) {
+		return
+	}
+
+	ok, recovery, err := h.verifySecondFactor(ctx, user, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando código"})
+		return
+	}
+	if !ok {
+		h.recordLoginFailure(ctx, user, now)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Código de verificación inválido"})
+		return
+	}
+
+	err = h.revokedTokens.Add(ctx, &models.RevokedToken{JTI: challenge.ID, ExpiresAt: challenge.ExpiresAt.Time})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generando token"})
+		return
+	}
+	response, ok := h.completeLogin(c, user, now)
+	if !ok {
+		return
+	}
+	if recovery {
+		remaining, err := h.recoveryCodes.CountUnused(ctx, user.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, "error contando códigos de recuperación", "user_id", user.ID, "error", err)
+		}
+		response["recovery_codes_remaining"] = remaining
+		createAuditLog(ctx, h.audits, user.ID, user.AlchemistID, "MFA_RECOVERY_USED", "user",
+			fmt.Sprintf("%s inició sesión con un código de recuperación (quedan %d)", user.Username, remaining))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// EnrollMFA genera un secreto TOTP nuevo para el usuario autenticado. No se
+// exige hasta que se confirme con un código válido.
+func (h *AuthHandler) EnrollMFA(c *gin.Context) {
+	ctx := c.Request.Context()
+	user, err := h.users.Get(ctx, c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "La verificación en dos pasos ya está activada"})
+		return
+	}
+
+	user.TOTPSecret = totp.NewSecret()
+	user.TOTPLastStep = 0
+	if err := h.users.Update(ctx, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando usuario"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      user.TOTPSecret,
+		"otpauth_uri": totp.URI(h.mfa.Issuer, user.Username, user.TOTPSecret),
+	})
+}
+
+type mfaCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// ConfirmMFA activa la verificación en dos pasos con el primer código de la
+// app, devuelve los códigos de recuperación (solo esta vez) y renueva las
+// sesiones.
+func (h *AuthHandler) ConfirmMFA(c *gin.Context) {
+	var req mfaCodeRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.users.Get(ctx, c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "La verificación en dos pasos ya está activada"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Primero debe iniciar la activación"})
+		return
+	}
+	if !checkActive(c, user) {
+		return
+	}
+
+	// verifyTOTP solo guarda el usuario si el código es válido
+	user.TOTPEnabled = true
+	ok, err := h.verifyTOTP(ctx, user, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando usuario"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Código de verificación inválido"})
+		return
+	}
+
+	codes, err := h.newRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generando códigos de recuperación"})
+		return
+	}
+
+	// Las demás sesiones se abrieron sin segundo factor
+	if _, err := revokeSessions(ctx, h.refreshTokens, h.revokedTokens, user.ID, ""); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revocando sesiones"})
+		return
+	}
+	response, err := h.issueTokens(ctx, *user, c.GetString("sessionID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generando token"})
+		return
+	}
+
+	createAuditLog(ctx, h.audits, user.ID, user.AlchemistID, "MFA_ENABLE", "user",
+		fmt.Sprintf("Verificación en dos pasos activada por %s", user.Username))
+
+	response["message"] = "Verificación en dos pasos activada"
+	response["recovery_codes"] = codes
+	c.JSON(http.StatusOK, response)
+}
+
+type disableMFARequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// DisableMFA desactiva el segundo factor del usuario autenticado si su rol
+// no lo exige. Pide la contraseña y un código válido.
+func (h *AuthHandler) DisableMFA(c *gin.Context) {
+	var req disableMFARequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.users.Get(ctx, c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+		return
+	}
+	if h.mfaRequired(user.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Su rol exige verificación en dos pasos"})
+		return
+	}
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "La verificación en dos pasos no está activada"})
+		return
+	}
+	if !h.passwords.Matches(req.Password, user.Password) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La contraseña no es correcta"})
+		return
+	}
+
+	ok, _, err := h.verifySecondFactor(ctx, user, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando código"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Código de verificación inválido"})
+		return
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	if err := h.users.Update(ctx, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando usuario"})
+		return
+	}
+	if err := h.recoveryCodes.Replace(ctx, user.ID, nil); err != nil {
+		slog.ErrorContext(ctx, "error borrando códigos de recuperación", "user_id", user.ID, "error", err)
+	}
+
+	createAuditLog(ctx, h.audits, user.ID, user.AlchemistID, "MFA_DISABLE", "user",
+		fmt.Sprintf("Verificación en dos pasos desactivada por %s", user.Username))
+	c.JSON(http.StatusOK, gin.H{"message": "Verificación en dos pasos desactivada"})
+}
+
+// RegenerateRecoveryCodes invalida los códigos de recuperación anteriores y
+// devuelve unos nuevos. Exige un código de la app.
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req mfaCodeRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.users.Get(ctx, c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+		return
+	}
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "La verificación en dos pasos no está activada"})
+		return
+	}
+
+	ok, err := h.verifyTOTP(ctx, user, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando código"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Código de verificación inválido"})
+		return
+	}
+
+	codes, err := h.newRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generando códigos de recuperación"})
+		return
+	}
+
+	createAuditLog(ctx, h.audits, user.ID, user.AlchemistID, "MFA_RECOVERY_CODES", "user",
+		fmt.Sprintf("Códigos de recuperación regenerados por %s", user.Username))
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"amestris-backend/totp"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLoginMFARejectsReplayedCode(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.addUser("roy_mustang", "supervisor", nil)
+	user.TOTPSecret = totp.NewSecret()
+	user.TOTPEnabled = true
+	if err := env.store.Users.Update(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	challenge := func() string {
+		w := env.request(http.MethodPost, "/login", "", gin.H{"username": "roy_mustang", "password": testPassword})
+		body := decode(t, w)
+		if w.Code != http.StatusOK || body["mfa_token"] == nil {
+			t.Fatalf("login sin desafío MFA: %d %s", w.Code, w.Body)
+		}
+		return body["mfa_token"].(string)
+	}
+
+	code, _ := totp.Code(user.TOTPSecret, totp.Step(time.Now()))
+	first := challenge()
+	if w := env.request(http.MethodPost, "/login/mfa", "", gin.H{"mfa_token": first, "code": code}); w.Code != http.StatusOK {
+		t.Fatalf("primer uso del código: %d %s", w.Code, w.Body)
+	}
+
+	// El mismo desafío no completa un segundo login
+	if w := env.request(http.MethodPost, "/login/mfa", "", gin.H{"mfa_token": first, "code": code}); w.Code != http.StatusUnauthorized {
+		t.Errorf("desafío reutilizado: %d, esperado 401", w.Code)
+	}
+
+	// Ni el mismo código con un desafío nuevo, aunque siga en su ventana
+	if w := env.request(http.MethodPost, "/login/mfa", "", gin.H{"mfa_token": challenge(), "code": code}); w.Code != http.StatusUnauthorized {
+		t.Errorf("código reutilizado: %d, esperado 401", w.Code)
+	}
+}
+
+func TestVerifyTOTPAcceptsEachStepOnce(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser("edward_elric", "alchemist", nil)
+	user.TOTPSecret = totp.NewSecret()
+
+	now := totp.Step(time.Now())
+	previous, _ := totp.Code(user.TOTPSecret, now-1)
+	current, _ := totp.Code(user.TOTPSecret, now)
+
+	if ok, err := env.h.Auth.verifyTOTP(ctx, user, current); !ok || err != nil {
+		t.Fatalf("código actual rechazado: %v", err)
+	}
+	if user.TOTPLastStep != now {
+		t.Errorf("TOTPLastStep = %d, esperado %d", user.TOTPLastStep, now)
+	}
+	// Un paso anterior, aunque esté dentro de la ventana, ya no vale
+	if ok, _ := env.h.Auth.verifyTOTP(ctx, user, previous); ok {
+		t.Error("se aceptó un código de un paso anterior al último usado")
+	}
+}
@@ -1,23 +1,60 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"slices"
 
 	"amestris-backend/models"
+	"amestris-backend/query"
+	"amestris-backend/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-func GetMissions(c *gin.Context) {
-	var missions []models.Mission
-	if err := models.DB.Preload("Alchemist").Find(&missions).Error; err != nil {
+type MissionHandler struct {
+	missions   repository.MissionRepository
+	alchemists repository.AlchemistRepository
+	audits     repository.AuditRepository
+}
+
+func NewMissionHandler(missions repository.MissionRepository, alchemists repository.AlchemistRepository, audits repository.AuditRepository) *MissionHandler {
+	return &MissionHandler{missions: missions, alchemists: alchemists, audits: audits}
+}
+
+// missionQuery son los filtros y órdenes admitidos en los listados de
+// misiones.
+var missionQuery = &query.Schema[models.Mission]{
+	Fields: map[string]query.Field[models.Mission]{
+		"id":           {Column: "id", Type: query.Int, Sort: true, Value: func(m models.Mission) any { return m.ID }},
+		"title":        {Column: "title", Type: query.Text, Sort: true, Value: func(m models.Mission) any { return m.Title }},
+		"status":       {Column: "status", Type: query.Text, Filter: true, Sort: true, Value: func(m models.Mission) any { return m.Status }},
+		"priority":     {Column: "priority", Type: query.Text, Filter: true, Sort: true, Value: func(m models.Mission) any { return m.Priority }},
+		"alchemist_id": {Column: "alchemist_id", Type: query.Int, Filter: true, Value: func(m models.Mission) any { return m.AlchemistID }},
+		"created_at":   {Column: "created_at", Type: query.Time, Sort: true, Value: func(m models.Mission) any { return m.CreatedAt }},
+		"updated_at":   {Column: "updated_at", Type: query.Time, Sort: true, Value: func(m models.Mission) any { return m.UpdatedAt }},
+	},
+	DefaultSort: "id",
+}
+
+func (h *MissionHandler) GetMissions(c *gin.Context) {
+	list, ok := parseList(c, missionQuery)
+	if !ok {
+		return
+	}
+	h.respondMissions(c, list)
+}
+
+func (h *MissionHandler) respondMissions(c *gin.Context, list query.List[models.Mission]) {
+	page, err := h.missions.Page(c.Request.Context(), list)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo misiones"})
 		return
 	}
-	c.JSON(http.StatusOK, missions)
+	respondPage(c, page)
 }
 
-func CreateMission(c *gin.Context) {
+func (h *MissionHandler) CreateMission(c *gin.Context) {
 	var mission struct {
 		Title       string `json:"title" binding:"required"`
 		Description string `json:"description" binding:"required"`
@@ -31,9 +68,11 @@ func CreateMission(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
+
 	// Verificar que el alquimista existe
-	var alchemist models.Alchemist
-	if err := models.DB.First(&alchemist, mission.AlchemistID).Error; err != nil {
+	alchemist, err := h.alchemists.Get(ctx, mission.AlchemistID)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Alquimista no encontrado"})
 		return
 	}
@@ -46,17 +85,17 @@ func CreateMission(c *gin.Context) {
 		Priority:    mission.Priority,
 	}
 
-	if err := models.DB.Create(&newMission).Error; err != nil {
+	if err := h.missions.Create(ctx, &newMission); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando misión: " + err.Error()})
 		return
 	}
 
 	// Cargar la relación del alquimista
-	models.DB.Preload("Alchemist").First(&newMission, newMission.ID)
+	alchemist.User = nil
+	newMission.Alchemist = *alchemist
 
 	// Log de auditoría
-	userID, _ := c.Get("userID")
-	CreateAuditLog(userID.(uint), "MISSION_CREATE", "mission",
+	auditRequest(c, h.audits, "MISSION_CREATE", "mission",
 		"Nueva misión creada: "+newMission.Title+" para "+newMission.Alchemist.Name)
 
 	c.JSON(http.StatusCreated, gin.H{
@@ -65,15 +104,28 @@ func CreateMission(c *gin.Context) {
 	})
 }
 
-func UpdateMissionStatus(c *gin.Context) {
-	id := c.Param("id")
-	var mission models.Mission
+// UpdateMissionStatus solo la puede usar el alquimista asignado o quien
+// gestiona misiones; ver canUpdateMission.
+func (h *MissionHandler) UpdateMissionStatus(c *gin.Context) {
+	id, ok := parseID(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Misión no encontrada"})
+		return
+	}
 
-	if err := models.DB.Preload("Alchemist").First(&mission, id).Error; err != nil {
+	ctx := c.Request.Context()
+
+	mission, err := h.missions.Get(ctx, id)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Misión no encontrada"})
 		return
 	}
 
+	if !canUpdateMission(c, mission) {
+		denyAccess(c, h.audits, "mission", fmt.Sprintf("actualizar la misión %d", mission.ID))
+		return
+	}
+
 	var updateData struct {
 		Status string `json:"status"`
 	}
@@ -82,26 +134,35 @@ func UpdateMissionStatus(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
 		return
 	}
+	if !slices.Contains(models.MissionStatuses, updateData.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Estado de misión desconocido: %s", updateData.Status)})
+		return
+	}
 
 	mission.Status = updateData.Status
-	models.DB.Save(&mission)
+	if err := h.missions.Update(ctx, mission); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando misión"})
+		return
+	}
 
 	// Log de auditoría
-	CreateAuditLog(mission.AlchemistID, "MISSION_UPDATE", "mission",
+	auditRequest(c, h.audits, "MISSION_UPDATE", "mission",
 		"Misión actualizada: "+mission.Title+" - Nuevo estado: "+updateData.Status)
 
 	c.JSON(http.StatusOK, mission)
 }
 
-// Nueva función para obtener misiones del usuario actual
-func GetMyMissions(c *gin.Context) {
-	userID, _ := c.Get("userID")
-
-	var missions []models.Mission
-	if err := models.DB.Preload("Alchemist").Where("alchemist_id = ?", userID).Find(&missions).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo misiones"})
+// GetMyMissions devuelve las misiones asignadas al alquimista vinculado al
+// usuario actual.
+func (h *MissionHandler) GetMyMissions(c *gin.Context) {
+	alchemist, ok := requireAlchemist(c)
+	if !ok {
 		return
 	}
-
-	c.JSON(http.StatusOK, missions)
+	list, ok := parseList(c, missionQuery)
+	if !ok {
+		return
+	}
+	list.Where("alchemist_id", alchemist.ID)
+	h.respondMissions(c, list)
 }
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"amestris-backend/config"
+	"amestris-backend/models"
+	"amestris-backend/oidc"
+	"amestris-backend/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// oidcStateTTL es el tiempo que tiene el usuario para volver del
+	// proveedor antes de que caduque la cookie de estado.
+	oidcStateTTL    = 10 * time.Minute
+	oidcStateCookie = "oidc_state"
+	oidcCookiePath  = "/login/oidc"
+)
+
+// oidcStateType es la cabecera typ de la cookie de estado, que
+// AuthMiddleware rechaza por no ser signing.TypeAccess.
+const oidcStateType = "oidc-state+jwt"
+
+// oidcStateClaims guarda en una cookie firmada lo necesario para validar la
+// vuelta del proveedor: state, nonce y el code_verifier de PKCE.
+type oidcStateClaims struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+// errOIDCDenied es un rechazo del login OIDC por la configuración o el
+// estado de la cuenta, no un fallo del proveedor. El texto se devuelve al
+// frontend como código de error.
+type errOIDCDenied string
+
+func (e errOIDCDenied) Error() string {
+	return string(e)
+}
+
+type OIDCHandler struct {
+	auth       *AuthHandler
+	users      repository.UserRepository
+	alchemists repository.AlchemistRepository
+	roles      repository.RoleRepository
+	audits     repository.AuditRepository
+
+	config config.OIDCConfig
+	// provider es nil si el inicio de sesión con OIDC está desactivado.
+	provider *oidc.Provider
+}
+
+func NewOIDCHandler(auth *AuthHandler, users repository.UserRepository, alchemists repository.AlchemistRepository,
+	roles repository.RoleRepository, audits repository.AuditRepository, settings Settings) *OIDCHandler {
+	return &OIDCHandler{auth: auth, users: users, alchemists: alchemists, roles: roles, audits: audits,
+		config: settings.OIDC, provider: settings.OIDCProvider}
+}
+
+// LoginMethods indica al frontend qué formas de iniciar sesión están
+// disponibles.
+func (h *OIDCHandler) LoginMethods(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"local": h.auth.localLogin, "oidc": h.provider != nil})
+}
+
+// Start redirige al proveedor de identidad para iniciar sesión.
+func (h *OIDCHandler) Start(c *gin.Context) {
+	if h.provider == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "El inicio de sesión con OIDC no está configurado"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	now := time.Now()
+	state := &oidcStateClaims{
+		State:    oidc.NewState(),
+		Nonce:    oidc.NewState(),
+		Verifier: oidc.NewVerifier(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(oidcStateTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "amestris-alchemy-system",
+		},
+	}
+	redirect, err := h.provider.AuthCodeURL(ctx, state.State, state.Nonce, state.Verifier)
+	if err != nil {
+		slog.ErrorContext(ctx, "error contactando con el proveedor OIDC", "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "El proveedor de identidad no está disponible"})
+		return
+	}
+	cookie, err := h.auth.keys.Sign(state, oidcStateType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generando token"})
+		return
+	}
+
+	h.setStateCookie(c, cookie, int(oidcStateTTL.Seconds()))
+	c.Redirect(http.StatusFound, redirect)
+}
+
+// Callback recibe al usuario de vuelta del proveedor, canjea el código,
+// crea o actualiza el usuario y redirige al frontend con los tokens en el
+// fragmento de la URL.
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	if h.provider == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "El inicio de sesión con OIDC no está configurado"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	raw, _ := c.Cookie(oidcStateCookie)
+	h.setStateCookie(c, "", -1)
+
+	if providerError := c.Query("error"); providerError != "" {
+		slog.WarnContext(ctx, "el proveedor OIDC rechazó el inicio de sesión",
+			"error", providerError, "description", c.Query("error_description"))
+		h.redirectError(c, "provider_error")
+		return
+	}
+
+	state := &oidcStateClaims{}
+	token, err := h.auth.keys.Parse(raw, state, oidcStateType)
+	if err != nil || !token.Valid || state.State == "" ||
+		subtle.ConstantTimeCompare([]byte(state.State), []byte(c.Query("state"))) != 1 {
+		h.redirectError(c, "invalid_state")
+		return
+	}
+
+	rawIDToken, err := h.provider.Exchange(ctx, c.Query("code"), state.Verifier)
+	if err != nil {
+		slog.WarnContext(ctx, "error canjeando el código OIDC", "error", err)
+		h.redirectError(c, "exchange_failed")
+		return
+	}
+	claims, err := h.provider.Verify(ctx, rawIDToken, state.Nonce)
+	if err != nil {
+		slog.WarnContext(ctx, "ID token rechazado", "error", err)
+		h.redirectError(c, "invalid_id_token")
+		return
+	}
+
+	user, err := h.provision(ctx, claims)
+	var denied errOIDCDenied
+	if errors.As(err, &denied) {
+		createAuditLog(ctx, h.audits, 0, nil, "UNAUTHORIZED_ACCESS", "user",
+			fmt.Sprintf("Inicio de sesión OIDC de %s rechazado: %s", claims.Subject, denied))
+		h.redirectError(c, string(denied))
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "error provisionando usuario OIDC", "sub", claims.Subject, "error", err)
+		h.redirectError(c, "server_error")
+		return
+	}
+
+	response, err := h.auth.openSession(ctx, user, time.Now())
+	if err != nil {
+		h.redirectError(c, "server_error")
+		return
+	}
+	fragment := url.Values{
+		"token":         {response["token"].(string)},
+		"refresh_token": {response["refresh_token"].(string)},
+		"expires_in":    {strconv.Itoa(response["expires_in"].(int))},
+	}
+	c.Redirect(http.StatusFound, h.config.FrontendURL+"#"+fragment.Encode())
+}
+
+// provision busca el usuario del sub del ID token o lo crea en su primer
+// inicio de sesión, y le aplica el rol y el alquimista que indican los
+// claims.
+func (h *OIDCHandler) provision(ctx context.Context, claims *oidc.IDClaims) (*models.User, error) {
+	role, err := h.mapRole(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := h.users.FindByOIDCSubject(ctx, h.config.Issuer, claims.Subject)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		user, err = h.createUser(ctx, claims, role)
+		if err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case user.Disabled:
+		return nil, errOIDCDenied("account_disabled")
+	case user.Role != role:
+		previous := user.Role
+		if err := h.users.UpdateRole(ctx, user.ID, role); err != nil {
+			return nil, err
+		}
+		user.Role = role
+		createAuditLog(ctx, h.audits, user.ID, user.AlchemistID, "USER_ROLE_CHANGE", "user",
+			fmt.Sprintf("Rol de %s cambiado de %s a %s por los claims OIDC", user.Username, previous, role))
+	}
+
+	if user.AlchemistID == nil {
+		if err := h.linkAlchemist(ctx, user, claims); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// mapRole aplica oidc.role_mapping al claim de rol. Gana la primera
+// correspondencia; si no hay ninguna se usa oidc.default_role.
+func (h *OIDCHandler) mapRole(ctx context.Context, claims *oidc.IDClaims) (string, error) {
+	role := h.config.DefaultRole
+	if h.config.RoleClaim != "" {
+		values := claims.Lookup(h.config.RoleClaim)
+		for _, mapping := range h.config.RoleMapping {
+			if slices.Contains(values, mapping.Value) {
+				role = mapping.Role
+				break
+			}
+		}
+	}
+	if role == "" {
+		return "", errOIDCDenied("no_role")
+	}
+	if _, err := h.roles.Get(ctx, role); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			slog.ErrorContext(ctx, "el rol asignado por OIDC no existe", "role", role)
+			return "", errOIDCDenied("no_role")
+		}
+		return "", err
+	}
+	return role, nil
+}
+
+func (h *OIDCHandler) createUser(ctx context.Context, claims *oidc.IDClaims, role string) (*models.User, error) {
+	var username string
+	if values := claims.Lookup(h.config.UsernameClaim); len(values) > 0 {
+		username = strings.TrimSpace(values[0])
+	}
+	if username == "" {
+		return nil, errOIDCDenied("missing_username")
+	}
+	// No se toma el control de una cuenta local con el mismo nombre
+	if _, err := h.users.FindByUsername(ctx, username); err == nil {
+		return nil, errOIDCDenied("username_taken")
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	user := &models.User{
+		Username:    username,
+		Role:        role,
+		OIDCIssuer:  h.config.Issuer,
+		OIDCSubject: claims.Subject,
+	}
+	if err := h.users.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	createAuditLog(ctx, h.audits, user.ID, nil, "USER_REGISTER", "user",
+		fmt.Sprintf("Usuario %s creado con rol %s al iniciar sesión con OIDC", user.Username, user.Role))
+	return user, nil
+}
+
+// linkAlchemist vincula al usuario el alquimista cuyo nombre viene en
+// oidc.alchemist_claim, si existe y no está vinculado a otro usuario.
+func (h *OIDCHandler) linkAlchemist(ctx context.Context, user *models.User, claims *oidc.IDClaims) error {
+	if h.config.AlchemistClaim == "" {
+		return nil
+	}
+	values := claims.Lookup(h.config.AlchemistClaim)
+	if len(values) == 0 || values[0] == "" {
+		return nil
+	}
+
+	found, err := h.alchemists.FindByName(ctx, values[0])
+	if errors.Is(err, repository.ErrNotFound) {
+		slog.WarnContext(ctx, "alquimista del claim OIDC no encontrado", "user", user.Username, "alchemist", values[0])
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	alchemist, err := h.alchemists.Get(ctx, found.ID)
+	if err != nil {
+		return err
+	}
+	if alchemist.User != nil && alchemist.User.ID != user.ID {
+		slog.WarnContext(ctx, "el alquimista del claim OIDC ya está vinculado a otro usuario",
+			"user", user.Username, "alchemist", alchemist.Name)
+		return nil
+	}
+
+	if err := h.users.LinkAlchemist(ctx, user.ID, alchemist.ID); err != nil {
+		return err
+	}
+	user.AlchemistID = &alchemist.ID
+	user.Alchemist = nil
+	createAuditLog(ctx, h.audits, user.ID, user.AlchemistID, "USER_LINK_ALCHEMIST", "user",
+		fmt.Sprintf("Usuario %s vinculado al alquimista %s por los claims OIDC", user.Username, alchemist.Name))
+	return nil
+}
+
+func (h *OIDCHandler) setStateCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, value, maxAge, oidcCookiePath, "",
+		strings.HasPrefix(h.config.RedirectURL, "https://"), true)
+}
+
+// redirectError vuelve al frontend con el código de error en el
+// fragmento.
+func (h *OIDCHandler) redirectError(c *gin.Context, code string) {
+	c.Redirect(http.StatusFound, h.config.FrontendURL+"#"+url.Values{"error": {code}}.Encode())
+}
@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"amestris-backend/config"
+	"amestris-backend/oidc"
+	"amestris-backend/signing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID    = "amestris"
+	testFrontendURL = "http://frontend.test/login"
+)
+
+// fakeIdP es un proveedor OIDC mínimo: descubrimiento, JWKS y un endpoint
+// de token que comprueba el code_verifier contra el code_challenge recibido
+// en la autorización.
+type fakeIdP struct {
+	t      *testing.T
+	server *httptest.Server
+	key    ed25519.PrivateKey
+
+	// challenge y nonce son los de la última petición de autorización.
+	challenge string
+	nonce     string
+	// claims son los claims del siguiente ID token; los estándar se
+	// rellenan en authorize.
+	claims jwt.MapClaims
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &fakeIdP{t: t, key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		public := key.Public().(ed25519.PublicKey)
+		writeJSON(w, http.StatusOK, signing.JWKSet{Keys: []signing.JWK{{
+			KeyType: "OKP", KeyID: "idp-1", Use: "sig", Algorithm: "EdDSA",
+			Curve: "Ed25519", X: base64.RawURLEncoding.EncodeToString(public),
+		}}})
+	})
+	mux.HandleFunc("POST /token", idp.token)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func (idp *fakeIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.Form.Get("code") != "codigo" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	verifier := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(verifier[:]) != idp.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE"})
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, idp.claims)
+	token.Header["kid"] = "idp-1"
+	raw, err := token.SignedString(idp.key)
+	if err != nil {
+		idp.t.Error(err)
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id_token": raw, "token_type": "Bearer"})
+}
+
+// authorize hace de navegador y de proveedor: recorre la redirección de
+// Start, guarda el challenge y el nonce y prepara el ID token con los
+// claims dados. Devuelve el state y la cookie de estado.
+func (idp *fakeIdP) authorize(env *testEnv, sub string, extra jwt.MapClaims) (string, *http.Cookie) {
+	idp.t.Helper()
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login/oidc", nil))
+	if w.Code != http.StatusFound {
+		idp.t.Fatalf("Start: %d %s", w.Code, w.Body)
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil || !strings.HasPrefix(location.String(), idp.server.URL+"/authorize?") {
+		idp.t.Fatalf("redirección inesperada: %s", w.Header().Get("Location"))
+	}
+	query := location.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("client_id") != testClientID {
+		idp.t.Fatalf("petición de autorización inesperada: %v", query)
+	}
+	idp.challenge, idp.nonce = query.Get("code_challenge"), query.Get("nonce")
+
+	now := time.Now()
+	idp.claims = jwt.MapClaims{
+		"iss":   idp.server.URL,
+		"aud":   testClientID,
+		"sub":   sub,
+		"nonce": idp.nonce,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+	}
+	for name, value := range extra {
+		idp.claims[name] = value
+	}
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != oidcStateCookie {
+		idp.t.Fatalf("cookies de Start: %v", cookies)
+	}
+	return query.Get("state"), cookies[0]
+}
+
+// callback vuelve del proveedor y devuelve el fragmento de la redirección
+// al frontend.
+func (env *testEnv) callback(state string, cookie *http.Cookie) url.Values {
+	env.t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/login/oidc/callback?"+url.Values{"code": {"codigo"}, "state": {state}}.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, req)
+
+	location := w.Header().Get("Location")
+	if w.Code != http.StatusFound || !strings.HasPrefix(location, testFrontendURL+"#") {
+		env.t.Fatalf("Callback: %d %s %s", w.Code, location, w.Body)
+	}
+	fragment, err := url.ParseQuery(strings.TrimPrefix(location, testFrontendURL+"#"))
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	return fragment
+}
+
+func newOIDCTestEnv(t *testing.T) (*testEnv, *fakeIdP) {
+	idp := newFakeIdP(t)
+	settings := testSettings()
+	settings.OIDC = config.OIDCConfig{
+		Enabled:       true,
+		Issuer:        idp.server.URL,
+		ClientID:      testClientID,
+		RedirectURL:   "http://backend.test/login/oidc/callback",
+		FrontendURL:   testFrontendURL,
+		Scopes:        []string{"openid", "profile"},
+		UsernameClaim: "preferred_username",
+		RoleClaim:     "groups",
+		RoleMapping: []config.OIDCRoleMapping{
+			{Value: "mando-central", Role: "supervisor"},
+			{Value: "alquimistas-estatales", Role: "alchemist"},
+		},
+	}
+	settings.OIDCProvider = oidc.New(oidc.Config{
+		Issuer:      settings.OIDC.Issuer,
+		ClientID:    settings.OIDC.ClientID,
+		RedirectURL: settings.OIDC.RedirectURL,
+		Scopes:      settings.OIDC.Scopes,
+	})
+	return newTestEnvWith(t, settings), idp
+}
+
+func TestOIDCLoginProvisionsAndMapsRole(t *testing.T) {
+	env, idp := newOIDCTestEnv(t)
+	ctx := context.Background()
+
+	state, cookie := idp.authorize(env, "sub-roy", jwt.MapClaims{
+		"preferred_username": "roy_mustang",
+		"groups":             []string{"otros", "mando-central"},
+	})
+	fragment := env.callback(state, cookie)
+	if fragment.Get("token") == "" || fragment.Get("refresh_token") == "" {
+		t.Fatalf("login OIDC sin tokens: %v", fragment)
+	}
+
+	user, err := env.store.Users.FindByOIDCSubject(ctx, idp.server.URL, "sub-roy")
+	if err != nil {
+		t.Fatalf("usuario no provisionado: %v", err)
+	}
+	if user.Username != "roy_mustang" || user.Role != "supervisor" {
+		t.Errorf("usuario provisionado %s con rol %s", user.Username, user.Role)
+	}
+	if w := env.request(http.MethodGet, "/api/profile", fragment.Get("token"), nil); w.Code != http.StatusOK {
+		t.Errorf("perfil con el token OIDC: %d %s", w.Code, w.Body)
+	}
+
+	// El segundo inicio de sesión reutiliza el usuario y aplica el rol nuevo
+	state, cookie = idp.authorize(env, "sub-roy", jwt.MapClaims{
+		"preferred_username": "roy_mustang",
+		"groups":             "alquimistas-estatales",
+	})
+	if fragment := env.callback(state, cookie); fragment.Get("token") == "" {
+		t.Fatalf("segundo login OIDC: %v", fragment)
+	}
+	again, err := env.store.Users.FindByOIDCSubject(ctx, idp.server.URL, "sub-roy")
+	if err != nil || again.ID != user.ID || again.Role != "alchemist" {
+		t.Errorf("segundo login: usuario %+v, error %v", again, err)
+	}
+	if n := env.audits("USER_REGISTER"); n != 1 {
+		t.Errorf("%d altas de usuario, esperada 1", n)
+	}
+	if n := env.audits("USER_ROLE_CHANGE"); n != 1 {
+		t.Errorf("%d cambios de rol, esperado 1", n)
+	}
+}
+
+func TestOIDCCallbackRejectsState(t *testing.T) {
+	env, idp := newOIDCTestEnv(t)
+	claims := jwt.MapClaims{"preferred_username": "roy_mustang", "groups": "mando-central"}
+
+	_, cookie := idp.authorize(env, "sub-roy", claims)
+	if got := env.callback("otro-state", cookie).Get("error"); got != "invalid_state" {
+		t.Errorf("state distinto del de la cookie: %q, esperado invalid_state", got)
+	}
+
+	state, _ := idp.authorize(env, "sub-roy", claims)
+	if got := env.callback(state, nil).Get("error"); got != "invalid_state" {
+		t.Errorf("sin cookie de estado: %q, esperado invalid_state", got)
+	}
+
+	// La cookie de otro inicio de sesión no sirve con este state
+	_, other := idp.authorize(env, "sub-roy", claims)
+	state, _ = idp.authorize(env, "sub-roy", claims)
+	if got := env.callback(state, other).Get("error"); got != "invalid_state" {
+		t.Errorf("cookie de otro inicio: %q, esperado invalid_state", got)
+	}
+}
+
+func TestOIDCCallbackRequiresPKCEVerifier(t *testing.T) {
+	env, idp := newOIDCTestEnv(t)
+
+	state, cookie := idp.authorize(env, "sub-roy", jwt.MapClaims{"preferred_username": "roy_mustang", "groups": "mando-central"})
+	// El proveedor recibió el challenge de otro verifier
+	challenge := sha256.Sum256([]byte(oidc.NewVerifier()))
+	idp.challenge = base64.RawURLEncoding.EncodeToString(challenge[:])
+
+	if got := env.callback(state, cookie).Get("error"); got != "exchange_failed" {
+		t.Errorf("verifier que no corresponde al challenge: %q, esperado exchange_failed", got)
+	}
+}
+
+func TestOIDCCallbackRejectsIDToken(t *testing.T) {
+	env, idp := newOIDCTestEnv(t)
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+	}{
+		{"nonce incorrecto", jwt.MapClaims{"nonce": "otro-nonce"}},
+		{"audiencia de otro cliente", jwt.MapClaims{"aud": "otro-cliente"}},
+		{"caducado", jwt.MapClaims{"exp": time.Now().Add(-5 * time.Minute).Unix()}},
+		{"otro emisor", jwt.MapClaims{"iss": "https://otro-emisor.test"}},
+	}
+	for _, tt := range tests {
+		tt.claims["preferred_username"] = "roy_mustang"
+		tt.claims["groups"] = "mando-central"
+		state, cookie := idp.authorize(env, "sub-roy", tt.claims)
+		if got := env.callback(state, cookie).Get("error"); got != "invalid_id_token" {
+			t.Errorf("%s: %q, esperado invalid_id_token", tt.name, got)
+		}
+	}
+	if _, err := env.store.Users.FindByUsername(context.Background(), "roy_mustang"); err == nil {
+		t.Error("se creó el usuario con un ID token rechazado")
+	}
+}
+
+func TestOIDCCallbackWithoutMappedRole(t *testing.T) {
+	env, idp := newOIDCTestEnv(t)
+
+	state, cookie := idp.authorize(env, "sub-scar", jwt.MapClaims{"preferred_username": "scar", "groups": "ishval"})
+	if got := env.callback(state, cookie).Get("error"); got != "no_role" {
+		t.Errorf("sin rol asignable: %q, esperado no_role", got)
+	}
+	if n := env.audits("UNAUTHORIZED_ACCESS"); n != 1 {
+		t.Errorf("%d rechazos auditados, esperado 1", n)
+	}
+}
+
+func TestOIDCLinksAlchemistFromClaim(t *testing.T) {
+	env, idp := newOIDCTestEnv(t)
+	env.h.OIDC.config.AlchemistClaim = "alchemist"
+	mustang := env.addAlchemist("Roy Mustang")
+
+	state, cookie := idp.authorize(env, "sub-roy", jwt.MapClaims{
+		"preferred_username": "roy_mustang",
+		"groups":             "mando-central",
+		"alchemist":          "Roy Mustang",
+	})
+	if fragment := env.callback(state, cookie); fragment.Get("token") == "" {
+		t.Fatalf("login OIDC: %v", fragment)
+	}
+	user, err := env.store.Users.FindByOIDCSubject(context.Background(), idp.server.URL, "sub-roy")
+	if err != nil || user.AlchemistID == nil || *user.AlchemistID != mustang.ID || user.Role != "supervisor" {
+		t.Errorf("usuario %+v, error %v; esperado vinculado a %d", user, err, mustang.ID)
+	}
+	if n := env.audits("USER_LINK_ALCHEMIST"); n != 1 {
+		t.Errorf("%d vinculaciones auditadas, esperada 1", n)
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"amestris-backend/query"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseList valida los parámetros de listado de la petición contra schema o
+// responde 400.
+func parseList[T any](c *gin.Context, schema *query.Schema[T]) (query.List[T], bool) {
+	list, err := schema.Parse(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return list, false
+	}
+	return list, true
+}
+
+// respondPage responde con la página en el sobre común de los listados y
+// con las cabeceras Link (RFC 8288) y X-Total-Count.
+func respondPage[T any](c *gin.Context, page query.Page[T]) {
+	pagination := gin.H{"total": page.Total, "limit": page.Limit}
+	if !page.Keyset {
+		pagination["offset"] = page.Offset
+	}
+	if page.NextCursor != "" {
+		pagination["next_cursor"] = page.NextCursor
+	}
+
+	c.Header("Link", pageLinks(c.Request.URL, page))
+	c.Header("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	c.JSON(http.StatusOK, gin.H{"data": page.Items, "pagination": pagination})
+}
+
+// pageLinks enlaza la primera página y la siguiente y, paginando por offset,
+// también la anterior y la última. Con cursor no hay enlace a la anterior.
+func pageLinks[T any](current *url.URL, page query.Page[T]) string {
+	link := func(rel, param string, value string) string {
+		params := current.Query()
+		params.Del("cursor")
+		params.Del("offset")
+		if param != "" {
+			params.Set(param, value)
+		}
+		target := url.URL{Path: current.Path, RawQuery: params.Encode()}
+		return fmt.Sprintf("<%s>; rel=\"%s\"", target.String(), rel)
+	}
+
+	links := []string{link("first", "", "")}
+	switch {
+	case page.NextCursor == "":
+	case page.Keyset:
+		links = append(links, link("next", "cursor", page.NextCursor))
+	default:
+		links = append(links, link("next", "offset", strconv.Itoa(page.Offset+page.Limit)))
+	}
+	if !page.Keyset {
+		if page.Offset > 0 {
+			links = append(links, link("prev", "offset", strconv.Itoa(max(page.Offset-page.Limit, 0))))
+		}
+		if page.Total > 0 {
+			last := (page.Total - 1) / int64(page.Limit) * int64(page.Limit)
+			links = append(links, link("last", "offset", strconv.FormatInt(last, 10)))
+		}
+	}
+	return strings.Join(links, ", ")
+}
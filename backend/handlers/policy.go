@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"amestris-backend/models"
+	"amestris-backend/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Políticas de acceso a registros concretos. Los permisos de rol deciden qué
+// rutas se pueden usar; estas funciones deciden sobre qué misiones y
+// solicitudes se puede actuar.
+
+// canUpdateMission permite actualizar una misión al alquimista asignado, a su
+// supervisor si puede gestionar misiones y a quien tiene missions:write:all
+// (administradores). mission.Alchemist debe estar cargado.
+func canUpdateMission(c *gin.Context, mission *models.Mission) bool {
+	if isOwnAlchemist(c, mission.AlchemistID) || can(c, "missions:write:all") {
+		return true
+	}
+	return can(c, "missions:write") && supervises(c, &mission.Alchemist)
+}
+
+// supervises indica si el usuario de la petición es el supervisor del
+// alquimista.
+func supervises(c *gin.Context, alchemist *models.Alchemist) bool {
+	principal := currentPrincipal(c)
+	return principal != nil && alchemist.SupervisorID != nil && *alchemist.SupervisorID == principal.User.ID
+}
+
+// isOwnAlchemist indica si alchemistID es el alquimista vinculado al usuario
+// de la petición.
+func isOwnAlchemist(c *gin.Context, alchemistID uint) bool {
+	principal := currentPrincipal(c)
+	return principal != nil && principal.Alchemist != nil && principal.Alchemist.ID == alchemistID
+}
+
+// ownsExperiment indica si la solicitud es del alquimista vinculado al
+// usuario de la petición.
+func ownsExperiment(c *gin.Context, experiment *models.ExperimentRequest) bool {
+	return isOwnAlchemist(c, experiment.AlchemistID)
+}
+
+// canViewAllExperiments indica si la petición puede ver las solicitudes de
+// todos los alquimistas: con experiments:read:all o como revisor. El resto
+// solo ve las propias. Lo usan la vista, el listado y la búsqueda.
+func canViewAllExperiments(c *gin.Context) bool {
+	return can(c, "experiments:read:all") || can(c, "experiments:approve")
+}
+
+// canViewExperiment permite ver una solicitud a su autor y a los revisores.
+func canViewExperiment(c *gin.Context, experiment *models.ExperimentRequest) bool {
+	return ownsExperiment(c, experiment) || canViewAllExperiments(c)
+}
+
+// canReviewExperiment permite a los revisores cambiar el estado de una
+// solicitud y a su autor retirarla mientras está pendiente. El autor no
+// revisa sus propias solicitudes aunque tenga experiments:approve.
+func canReviewExperiment(c *gin.Context, experiment *models.ExperimentRequest, status string) bool {
+	if ownsExperiment(c, experiment) {
+		return experiment.Status == "pending" && status == "withdrawn"
+	}
+	return can(c, "experiments:approve")
+}
+
+// denyAccess responde 403 y deja constancia del intento en auditoría.
+// attempt describe la acción denegada, p. ej. "actualizar la misión 3".
+func denyAccess(c *gin.Context, audits repository.AuditRepository, resource, attempt string) {
+	auditRequest(c, audits, "UNAUTHORIZED_ACCESS", resource,
+		fmt.Sprintf("%s intentó %s sin autorización", c.GetString("username"), attempt))
+	c.JSON(http.StatusForbidden, gin.H{"error": "No tiene permiso sobre este recurso"})
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"amestris-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMissionStatusPolicy(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	edward := env.addAlchemist("Edward Elric")
+	alphonse := env.addAlchemist("Alphonse Elric")
+	env.addUser("edward_elric", "alchemist", edward)
+	env.addUser("alphonse_elric", "alchemist", alphonse)
+	roy := env.addUser("roy_mustang", "supervisor", nil)
+	env.addUser("olivier", "supervisor", nil)
+	env.addUser("admin", "admin", nil)
+
+	edward.SupervisorID = &roy.ID
+	if err := env.store.Alchemists.Update(ctx, edward); err != nil {
+		t.Fatal(err)
+	}
+	mission := &models.Mission{Title: "Laboratorio 5", AlchemistID: edward.ID, Status: "pending"}
+	if err := env.store.Missions.Create(ctx, mission); err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/api/missions/%d/status", mission.ID)
+
+	tests := []struct {
+		username string
+		want     int
+	}{
+		{"edward_elric", http.StatusOK}, // alquimista asignado
+		{"roy_mustang", http.StatusOK},  // su supervisor
+		{"admin", http.StatusOK},        // missions:write:all
+		{"alphonse_elric", http.StatusForbidden},
+		{"olivier", http.StatusForbidden}, // supervisor de otros alquimistas
+	}
+	for _, tt := range tests {
+		w := env.request(http.MethodPut, path, env.login(tt.username), gin.H{"status": "in_progress"})
+		if w.Code != tt.want {
+			t.Errorf("%s: %d, esperado %d", tt.username, w.Code, tt.want)
+		}
+	}
+	if got := env.audits("UNAUTHORIZED_ACCESS"); got != 2 {
+		t.Errorf("%d accesos denegados en auditoría, esperados 2", got)
+	}
+
+	w := env.request(http.MethodPut, path, env.login("edward_elric"), gin.H{"status": "abandonada"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("estado desconocido: %d, esperado 400", w.Code)
+	}
+	if got, _ := env.store.Missions.Get(ctx, mission.ID); got.Status != "in_progress" {
+		t.Errorf("estado guardado %q, esperado in_progress", got.Status)
+	}
+}
+
+func TestExperimentStatusPolicy(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	edward := env.addAlchemist("Edward Elric")
+	env.addUser("edward_elric", "alchemist", edward)
+	env.addUser("roy_mustang", "supervisor", nil)
+	experiment := &models.ExperimentRequest{Title: "Piedra filosofal", AlchemistID: edward.ID, Status: "pending"}
+	if err := env.store.Experiments.Create(ctx, experiment); err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/api/experiments/%d/status", experiment.ID)
+	author, reviewer := env.login("edward_elric"), env.login("roy_mustang")
+
+	if w := env.request(http.MethodPut, path, author, gin.H{"status": "approved"}); w.Code != http.StatusForbidden {
+		t.Errorf("autor aprobando: %d, esperado 403", w.Code)
+	}
+	if w := env.request(http.MethodPut, path, reviewer, gin.H{"status": "aprobada"}); w.Code != http.StatusBadRequest {
+		t.Errorf("estado desconocido: %d, esperado 400", w.Code)
+	}
+	if w := env.request(http.MethodPut, path, reviewer, gin.H{"status": "approved"}); w.Code != http.StatusOK {
+		t.Errorf("revisor aprobando: %d, esperado 200", w.Code)
+	}
+	// Ya no está pendiente: el autor no puede retirarla
+	if w := env.request(http.MethodPut, path, author, gin.H{"status": "withdrawn"}); w.Code != http.StatusForbidden {
+		t.Errorf("autor retirando una aprobada: %d, esperado 403", w.Code)
+	}
+}
+
+func TestExperimentSelfReviewDenied(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	// Un supervisor con alquimista propio puede presentar solicitudes
+	mustang := env.addAlchemist("Roy Mustang")
+	env.addUser("roy_mustang", "supervisor", mustang)
+	experiment := &models.ExperimentRequest{Title: "Llama sin guante", AlchemistID: mustang.ID, Status: "pending"}
+	if err := env.store.Experiments.Create(ctx, experiment); err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/api/experiments/%d/status", experiment.ID)
+	token := env.login("roy_mustang")
+
+	for _, status := range []string{"approved", "rejected"} {
+		if w := env.request(http.MethodPut, path, token, gin.H{"status": status}); w.Code != http.StatusForbidden {
+			t.Errorf("autor revisor con %s: %d, esperado 403", status, w.Code)
+		}
+	}
+	if got := env.audits("UNAUTHORIZED_ACCESS"); got != 2 {
+		t.Errorf("%d accesos denegados en auditoría, esperados 2", got)
+	}
+	if got, _ := env.store.Experiments.Get(ctx, experiment.ID); got.Status != "pending" {
+		t.Errorf("estado guardado %q, esperado pending", got.Status)
+	}
+
+	// Solo puede retirarla
+	if w := env.request(http.MethodPut, path, token, gin.H{"status": "withdrawn"}); w.Code != http.StatusOK {
+		t.Errorf("autor retirando: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestAssignSupervisorRequiresWriteAll(t *testing.T) {
+	env := newTestEnv(t)
+	edward := env.addAlchemist("Edward Elric")
+	roy := env.addUser("roy_mustang", "supervisor", nil)
+	env.addUser("admin", "admin", nil)
+	path := fmt.Sprintf("/api/alchemists/%d", edward.ID)
+
+	// Un supervisor no puede asignarse alquimistas
+	w := env.request(http.MethodPut, path, env.login("roy_mustang"), gin.H{"supervisor_id": roy.ID})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("supervisor asignándose: %d, esperado 403", w.Code)
+	}
+
+	admin := env.login("admin")
+	if w := env.request(http.MethodPut, path, admin, gin.H{"supervisor_id": 999}); w.Code != http.StatusBadRequest {
+		t.Errorf("supervisor inexistente: %d, esperado 400", w.Code)
+	}
+	if w := env.request(http.MethodPut, path, admin, gin.H{"supervisor_id": roy.ID}); w.Code != http.StatusOK {
+		t.Fatalf("admin asignando: %d %s", w.Code, w.Body)
+	}
+	got, _ := env.store.Alchemists.Get(context.Background(), edward.ID)
+	if got.SupervisorID == nil || *got.SupervisorID != roy.ID {
+		t.Errorf("SupervisorID = %v, esperado %d", got.SupervisorID, roy.ID)
+	}
+}
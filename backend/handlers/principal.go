@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"amestris-backend/models"
+	"amestris-backend/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentPrincipal devuelve quién hace la petición, o nil si la ruta no usa
+// middleware.LoadPrincipal.
+func currentPrincipal(c *gin.Context) *models.Principal {
+	value, _ := c.Get("principal")
+	principal, _ := value.(*models.Principal)
+	return principal
+}
+
+// requireAlchemist devuelve el alquimista vinculado al usuario de la
+// petición o, si no tiene, responde 409.
+func requireAlchemist(c *gin.Context) (*models.Alchemist, bool) {
+	if principal := currentPrincipal(c); principal != nil && principal.Alchemist != nil {
+		return principal.Alchemist, true
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error": "Esta acción requiere un perfil de alquimista vinculado a su usuario",
+		"code":  "alchemist_profile_required",
+	})
+	return nil, false
+}
+
+// auditRequest registra un evento atribuido al usuario de la petición y a
+// su alquimista vinculado, si lo tiene.
+func auditRequest(c *gin.Context, audits repository.AuditRepository, action, resource, details string) {
+	var alchemistID *uint
+	if principal := currentPrincipal(c); principal != nil {
+		alchemistID = principal.AlchemistID()
+	}
+	createAuditLog(c.Request.Context(), audits, c.GetUint("userID"), alchemistID, action, resource, details)
+}
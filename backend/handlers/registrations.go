@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"amestris-backend/config"
+	"amestris-backend/models"
+	"amestris-backend/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegistrationHandler gestiona el autorregistro, la cola de aprobación y las
+// invitaciones. Sin invitación la cuenta se crea pendiente y con el rol de
+// registration.default_role; con invitación recibe el rol y alquimista que decidió el
+// administrador.
+type RegistrationHandler struct {
+	users       repository.UserRepository
+	alchemists  repository.AlchemistRepository
+	invitations repository.InvitationRepository
+	roles       repository.RoleRepository
+	audits      repository.AuditRepository
+	passwords   Passwords
+	config      config.RegistrationConfig
+}
+
+func NewRegistrationHandler(users repository.UserRepository, alchemists repository.AlchemistRepository,
+	invitations repository.InvitationRepository, roles repository.RoleRepository,
+	audits repository.AuditRepository, passwords Passwords, cfg config.RegistrationConfig) *RegistrationHandler {
+	return &RegistrationHandler{users: users, alchemists: alchemists, invitations: invitations, roles: roles, audits: audits,
+		passwords: passwords, config: cfg}
+}
+
+type registerRequest struct {
+	Username    string `json:"username" binding:"required"`
+	Password    string `json:"password" binding:"required"`
+	InviteToken string `json:"invite_token"`
+}
+
+type invitationRequest struct {
+	Role        string `json:"role" binding:"required"`
+	AlchemistID *uint  `json:"alchemist_id"`
+	// ExpiresIn es una duración como "48h"; por defecto la configurada.
+	ExpiresIn string `json:"expires_in"`
+}
+
+// alchemistAvailable comprueba que el alquimista exista y no tenga usuario.
+func (h *RegistrationHandler) alchemistAvailable(ctx context.Context, id uint) (int, string) {
+	alchemist, err := h.alchemists.Get(ctx, id)
+	if err != nil {
+		return http.StatusNotFound, "Alquimista no encontrado"
+	}
+	if alchemist.User != nil {
+		return http.StatusConflict, "El alquimista ya está vinculado a otro usuario"
+	}
+	return http.StatusOK, ""
+}
+
+// releaseInvitation libera una invitación reclamada para que el registro
+// pueda reintentarse.
+func (h *RegistrationHandler) releaseInvitation(ctx context.Context, invitation *models.Invitation) {
+	invitation.UsedAt, invitation.UsedByID = nil, nil
+	if err := h.invitations.Update(ctx, invitation); err != nil {
+		slog.ErrorContext(ctx, "error liberando la invitación", "invitation", invitation.ID, "error", err)
+	}
+}
+
+func (h *RegistrationHandler) Register(c *gin.Context) {
+	var req registerRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	if !h.passwords.check(c, req.Password) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.users.FindByUsername(ctx, req.Username); err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El usuario ya existe"})
+		return
+	}
+
+	user := models.User{Username: req.Username, Role: h.config.DefaultRole, Pending: true}
+
+	var invitation *models.Invitation
+	if req.InviteToken != "" {
+		found, err := h.invitations.FindByHash(ctx, hashToken(req.InviteToken))
+		if err != nil || found.UsedAt != nil || time.Now().After(found.ExpiresAt) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invitación inválida o caducada"})
+			return
+		}
+		if found.AlchemistID != nil {
+			if status, message := h.alchemistAvailable(ctx, *found.AlchemistID); status != http.StatusOK {
+				c.JSON(status, gin.H{"error": message})
+				return
+			}
+		}
+		if claimed, err := h.invitations.Claim(ctx, found.ID, time.Now()); err != nil || !claimed {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invitación inválida o caducada"})
+			return
+		}
+
+		invitation = found
+		user.Role = invitation.Role
+		user.AlchemistID = invitation.AlchemistID
+		user.Pending = false
+	} else if !h.config.Open {
+		c.JSON(http.StatusForbidden, gin.H{"error": "El registro requiere una invitación"})
+		return
+	} else if _, err := h.roles.Get(ctx, user.Role); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			slog.ErrorContext(ctx, "el rol por defecto del registro no existe", "role", user.Role)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando usuario"})
+		return
+	}
+
+	hashedPassword, err := h.passwords.Hash(req.Password)
+	if err == nil {
+		user.Password = hashedPassword
+		err = h.users.Create(ctx, &user)
+	}
+	if err != nil {
+		if invitation != nil {
+			h.releaseInvitation(ctx, invitation)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando usuario"})
+		return
+	}
+
+	message := "Registro recibido, pendiente de aprobación"
+	details := fmt.Sprintf("Registro pendiente de aprobación: %s", user.Username)
+	if invitation != nil {
+		now := time.Now()
+		invitation.UsedAt = &now
+		invitation.UsedByID = &user.ID
+		if err := h.invitations.Update(ctx, invitation); err != nil {
+			// Sin el vínculo la invitación quedaría usada por nadie, así que
+			// se deshace el registro.
+			if err := h.users.Delete(ctx, user.ID); err != nil {
+				slog.ErrorContext(ctx, "error deshaciendo el registro", "user", user.ID, "error", err)
+			}
+			h.releaseInvitation(ctx, invitation)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error registrando la invitación"})
+			return
+		}
+
+		message = "Usuario registrado exitosamente"
+		details = fmt.Sprintf("Usuario %s registrado con la invitación %d (rol %s)", user.Username, invitation.ID, user.Role)
+	}
+	createAuditLog(ctx, h.audits, user.ID, user.AlchemistID, "USER_REGISTER", "user", details)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": message,
+		"user": gin.H{
+			"id":       user.ID,
+			"username": user.Username,
+			"role":     user.Role,
+			"pending":  user.Pending,
+		},
+	})
+}
+
+// loadPending resuelve la solicitud de registro de :id o responde 404.
+func (h *RegistrationHandler) loadPending(c *gin.Context) (*models.User, bool) {
+	id, ok := parseID(c.Param("id"))
+	if ok {
+		if user, err := h.users.Get(c.Request.Context(), id); err == nil && user.Pending {
+			return user, true
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Solicitud de registro no encontrada"})
+	return nil, false
+}
+
+func (h *RegistrationHandler) GetPendingRegistrations(c *gin.Context) {
+	pending := true
+	users, err := h.users.List(c.Request.Context(), repository.UserFilter{Pending: &pending})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo solicitudes"})
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+func (h *RegistrationHandler) ApproveRegistration(c *gin.Context) {
+	user, ok := h.loadPending(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	user.Pending = false
+	if err := h.users.Update(ctx, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando usuario"})
+		return
+	}
+
+	auditRequest(c, h.audits, "USER_APPROVE", "user",
+		fmt.Sprintf("Registro de %s aprobado por %s", user.Username, c.GetString("username")))
+	c.JSON(http.StatusOK, user)
+}
+
+// RejectRegistration elimina la cuenta pendiente, liberando el nombre de
+// usuario.
+func (h *RegistrationHandler) RejectRegistration(c *gin.Context) {
+	user, ok := h.loadPending(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.users.Delete(ctx, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error eliminando usuario"})
+		return
+	}
+
+	auditRequest(c, h.audits, "USER_REJECT", "user",
+		fmt.Sprintf("Registro de %s rechazado por %s", user.Username, c.GetString("username")))
+	c.JSON(http.StatusOK, gin.H{"message": "Solicitud de registro rechazada"})
+}
+
+// CreateInvitation emite una invitación de un solo uso. El token solo se
+// devuelve en esta respuesta.
+func (h *RegistrationHandler) CreateInvitation(c *gin.Context) {
+	var req invitationRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+	if !validRole(c, h.roles, req.Role) {
+		return
+	}
+
+	ttl := h.config.InviteTTL.Std()
+	if req.ExpiresIn != "" {
+		parsed, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Duración expires_in inválida"})
+			return
+		}
+		ttl = parsed
+	}
+
+	ctx := c.Request.Context()
+	if req.AlchemistID != nil {
+		if status, message := h.alchemistAvailable(ctx, *req.AlchemistID); status != http.StatusOK {
+			c.JSON(status, gin.H{"error": message})
+			return
+		}
+	}
+
+	token := randomToken(32)
+	invitation := models.Invitation{
+		TokenHash:   hashToken(token),
+		Role:        req.Role,
+		AlchemistID: req.AlchemistID,
+		CreatedByID: c.GetUint("userID"),
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+	if err := h.invitations.Create(ctx, &invitation); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando invitación"})
+		return
+	}
+
+	auditRequest(c, h.audits, "INVITATION_CREATE", "invitation",
+		fmt.Sprintf("Invitación %d con rol %s emitida por %s", invitation.ID, invitation.Role, c.GetString("username")))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"invitation": invitation,
+		"token":      token,
+		"link":       h.config.InviteURL + token,
+	})
+}
+
+func (h *RegistrationHandler) GetInvitations(c *gin.Context) {
+	invitations, err := h.invitations.ListPending(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo invitaciones"})
+		return
+	}
+	c.JSON(http.StatusOK, invitations)
+}
+
+func (h *RegistrationHandler) DeleteInvitation(c *gin.Context) {
+	id, ok := parseID(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitación no encontrada"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.invitations.Get(ctx, id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitación no encontrada"})
+		return
+	}
+	if err := h.invitations.Delete(ctx, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error eliminando invitación"})
+		return
+	}
+
+	auditRequest(c, h.audits, "INVITATION_DELETE", "invitation",
+		fmt.Sprintf("Invitación %d revocada por %s", id, c.GetString("username")))
+	c.JSON(http.StatusOK, gin.H{"message": "Invitación revocada"})
+}
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"amestris-backend/models"
+	"amestris-backend/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// roleCacheTTL limita cuánto tarda en verse en otras instancias un cambio en
+// los permisos de un rol. En esta instancia se aplica de inmediato.
+const roleCacheTTL = 30 * time.Second
+
+var roleName = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+
+type cachedPermissions struct {
+	permissions []string
+	loadedAt    time.Time
+}
+
+// RoleHandler administra los roles y resuelve quién hace cada petición para
+// middleware.LoadPrincipal.
+type RoleHandler struct {
+	roles  repository.RoleRepository
+	users  repository.UserRepository
+	audits repository.AuditRepository
+
+	mu    sync.Mutex
+	cache map[string]cachedPermissions
+}
+
+func NewRoleHandler(roles repository.RoleRepository, users repository.UserRepository,
+	audits repository.AuditRepository) *RoleHandler {
+	return &RoleHandler{roles: roles, users: users, audits: audits, cache: map[string]cachedPermissions{}}
+}
+
+// Permissions devuelve los permisos del rol. Un rol inexistente no tiene
+// ninguno.
+func (h *RoleHandler) Permissions(ctx context.Context, role string) ([]string, error) {
+	h.mu.Lock()
+	cached, ok := h.cache[role]
+	h.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < roleCacheTTL {
+		return cached.permissions, nil
+	}
+
+	var permissions []string
+	definition, err := h.roles.Get(ctx, role)
+	switch {
+	case err == nil:
+		permissions = definition.Permissions
+	case !errors.Is(err, repository.ErrNotFound):
+		return nil, err
+	}
+
+	h.mu.Lock()
+	h.cache[role] = cachedPermissions{permissions: permissions, loadedAt: time.Now()}
+	h.mu.Unlock()
+	return permissions, nil
+}
+
+// ResolvePrincipal carga el usuario con su alquimista vinculado y los
+// permisos de su rol actual.
+func (h *RoleHandler) ResolvePrincipal(ctx context.Context, userID uint) (*models.Principal, error) {
+	user, err := h.users.Get(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.Disabled || user.Pending {
+		return nil, nil
+	}
+
+	permissions, err := h.Permissions(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Principal{
+		User:        user,
+		Role:        user.Role,
+		Permissions: permissions,
+		Alchemist:   user.Alchemist,
+	}, nil
+}
+
+func (h *RoleHandler) forget(role string) {
+	h.mu.Lock()
+	delete(h.cache, role)
+	h.mu.Unlock()
+}
+
+// can indica si el usuario de la petición tiene el permiso. Requiere
+// middleware.LoadPrincipal en la ruta.
+func can(c *gin.Context, permission string) bool {
+	principal := currentPrincipal(c)
+	return principal != nil && principal.Can(permission)
+}
+
+// validRole comprueba que el rol exista y, si no, responde 400.
+func validRole(c *gin.Context, roles repository.RoleRepository, name string) bool {
+	_, err := roles.Get(c.Request.Context(), name)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Rol inválido"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo rol"})
+	}
+	return false
+}
+
+// checkPermissions valida los permisos pedidos contra el catálogo y los
+// devuelve ordenados y sin repetir.
+func checkPermissions(c *gin.Context, requested []string) (models.SpaceList, bool) {
+	for _, permission := range requested {
+		if !models.IsPermission(permission) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Permiso desconocido: %s", permission)})
+			return nil, false
+		}
+	}
+	permissions := slices.Clone(requested)
+	slices.Sort(permissions)
+	return slices.Compact(permissions), true
+}
+
+func (h *RoleHandler) audit(c *gin.Context, action string, details string) {
+	auditRequest(c, h.audits, action, "role", fmt.Sprintf("%s (por %s)", details, c.GetString("username")))
+}
+
+// GetMyPermissions devuelve el rol y los permisos del usuario autenticado,
+// para que el frontend oculte las acciones no disponibles.
+func (h *RoleHandler) GetMyPermissions(c *gin.Context) {
+	principal := currentPrincipal(c)
+	granted := principal.Permissions
+	if granted == nil {
+		granted = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"role":         principal.Role,
+		"permissions":  granted,
+		"alchemist_id": principal.AlchemistID(),
+	})
+}
+
+// GetPermissionCatalog devuelve todos los permisos asignables.
+func (h *RoleHandler) GetPermissionCatalog(c *gin.Context) {
+	c.JSON(http.StatusOK, models.Permissions)
+}
+
+func (h *RoleHandler) ListRoles(c *gin.Context) {
+	roles, err := h.roles.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo roles"})
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+type roleDefinitionRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+func (h *RoleHandler) CreateRole(c *gin.Context) {
+	var req roleDefinitionRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+	if !roleName.MatchString(req.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nombre de rol inválido (minúsculas, dígitos, - y _)"})
+		return
+	}
+	permissions, ok := checkPermissions(c, req.Permissions)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.roles.Get(ctx, req.Name); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "El rol ya existe"})
+		return
+	}
+
+	role := models.Role{Name: req.Name, Description: req.Description, Permissions: permissions}
+	if err := h.roles.Create(ctx, &role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando rol"})
+		return
+	}
+	h.forget(role.Name)
+
+	h.audit(c, "ROLE_CREATE", fmt.Sprintf("Rol %s creado con permisos: %s", role.Name, strings.Join(role.Permissions, " ")))
+	c.JSON(http.StatusCreated, role)
+}
+
+type roleUpdateRequest struct {
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// UpdateRole sustituye los permisos de un rol. Un administrador no puede
+// quitarle a su propio rol el permiso de editar roles.
+func (h *RoleHandler) UpdateRole(c *gin.Context) {
+	var req roleUpdateRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+	permissions, ok := checkPermissions(c, req.Permissions)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	role, err := h.roles.Get(ctx, c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rol no encontrado"})
+		return
+	}
+	if role.Name == c.GetString("role") && !slices.Contains(permissions, "roles:manage") {
+		c.JSON(http.StatusConflict, gin.H{"error": "No puede quitar a su propio rol el permiso roles:manage"})
+		return
+	}
+
+	previous := strings.Join(role.Permissions, " ")
+	role.Permissions = permissions
+	if req.Description != nil {
+		role.Description = *req.Description
+	}
+	if err := h.roles.Update(ctx, role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando rol"})
+		return
+	}
+	h.forget(role.Name)
+
+	h.audit(c, "ROLE_UPDATE", fmt.Sprintf("Permisos del rol %s cambiados de [%s] a [%s]",
+		role.Name, previous, strings.Join(role.Permissions, " ")))
+	c.JSON(http.StatusOK, role)
+}
+
+// DeleteRole elimina un rol definido por un administrador que no tenga
+// usuarios asignados.
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	ctx := c.Request.Context()
+	role, err := h.roles.Get(ctx, c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rol no encontrado"})
+		return
+	}
+	if role.BuiltIn {
+		c.JSON(http.StatusConflict, gin.H{"error": "Los roles predefinidos no se pueden eliminar"})
+		return
+	}
+
+	users, err := h.users.List(ctx, repository.UserFilter{Role: role.Name})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo usuarios"})
+		return
+	}
+	if len(users) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("El rol está asignado a %d usuarios", len(users)),
+			"users": len(users),
+		})
+		return
+	}
+
+	if err := h.roles.Delete(ctx, role.Name); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error eliminando rol"})
+		return
+	}
+	h.forget(role.Name)
+
+	h.audit(c, "ROLE_DELETE", fmt.Sprintf("Rol %s eliminado", role.Name))
+	c.JSON(http.StatusOK, gin.H{"message": "Rol eliminado"})
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRoleEditChangesAccess(t *testing.T) {
+	env := newTestEnv(t)
+	edward := env.addAlchemist("Edward Elric")
+	env.addUser("edward_elric", "alchemist", edward)
+	env.addUser("admin", "admin", nil)
+	admin := env.login("admin")
+	token := env.login("edward_elric")
+	path := fmt.Sprintf("/api/alchemists/%d", edward.ID)
+	body := gin.H{"status": "Activo"}
+
+	if w := env.request(http.MethodPut, path, token, body); w.Code != http.StatusForbidden {
+		t.Fatalf("alquimista sin alchemists:write: %d, esperado 403", w.Code)
+	}
+
+	// El cambio de permisos vale desde la siguiente petición, con el mismo token
+	grant := gin.H{"permissions": []string{"alchemists:write"}}
+	if w := env.request(http.MethodPut, "/api/admin/roles/alchemist", admin, grant); w.Code != http.StatusOK {
+		t.Fatalf("editar rol: %d %s", w.Code, w.Body)
+	}
+	if w := env.request(http.MethodPut, path, token, body); w.Code != http.StatusOK {
+		t.Errorf("tras conceder alchemists:write: %d %s", w.Code, w.Body)
+	}
+
+	revoke := gin.H{"permissions": []string{}}
+	if w := env.request(http.MethodPut, "/api/admin/roles/alchemist", admin, revoke); w.Code != http.StatusOK {
+		t.Fatalf("editar rol: %d %s", w.Code, w.Body)
+	}
+	if w := env.request(http.MethodPut, path, token, body); w.Code != http.StatusForbidden {
+		t.Errorf("tras retirar alchemists:write: %d, esperado 403", w.Code)
+	}
+	if n := env.audits("ROLE_UPDATE"); n != 2 {
+		t.Errorf("%d ediciones auditadas, esperadas 2", n)
+	}
+}
+
+func TestCustomRoleGrantsAccess(t *testing.T) {
+	env := newTestEnv(t)
+	edward := env.addAlchemist("Edward Elric")
+	user := env.addUser("maes_hughes", "alchemist", nil)
+	env.addUser("admin", "admin", nil)
+	admin := env.login("admin")
+
+	role := gin.H{"name": "investigacion", "permissions": []string{"alchemists:write"}}
+	if w := env.request(http.MethodPost, "/api/admin/roles", admin, role); w.Code != http.StatusCreated {
+		t.Fatalf("crear rol: %d %s", w.Code, w.Body)
+	}
+	w := env.request(http.MethodPut, fmt.Sprintf("/api/admin/users/%d/role", user.ID), admin, gin.H{"role": "investigacion"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("asignar rol: %d %s", w.Code, w.Body)
+	}
+
+	path := fmt.Sprintf("/api/alchemists/%d", edward.ID)
+	if w := env.request(http.MethodPut, path, env.login("maes_hughes"), gin.H{"status": "Activo"}); w.Code != http.StatusOK {
+		t.Errorf("rol personalizado con alchemists:write: %d %s", w.Code, w.Body)
+	}
+
+	// Con usuarios asignados el rol no se puede borrar
+	if w := env.request(http.MethodDelete, "/api/admin/roles/investigacion", admin, nil); w.Code != http.StatusConflict {
+		t.Errorf("borrar rol en uso: %d, esperado 409", w.Code)
+	}
+}
+
+func TestUnknownRoleRejected(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser("edward_elric", "alchemist", nil)
+	env.addUser("admin", "admin", nil)
+	admin := env.login("admin")
+
+	w := env.request(http.MethodPut, fmt.Sprintf("/api/admin/users/%d/role", user.ID), admin, gin.H{"role": "homunculo"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("asignar rol inexistente: %d, esperado 400", w.Code)
+	}
+	if got, _ := env.store.Users.Get(ctx, user.ID); got.Role != "alchemist" {
+		t.Errorf("rol cambiado a %s", got.Role)
+	}
+
+	w = env.request(http.MethodPost, "/api/admin/service-accounts", admin, gin.H{"username": "laboratorio-5", "role": "homunculo"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("cuenta de servicio con rol inexistente: %d, esperado 400", w.Code)
+	}
+	if w := env.request(http.MethodPut, "/api/admin/roles/homunculo", admin, gin.H{"permissions": []string{}}); w.Code != http.StatusNotFound {
+		t.Errorf("editar rol inexistente: %d, esperado 404", w.Code)
+	}
+
+	unknown := gin.H{"permissions": []string{"alchemists:write", "piedra:filosofal"}}
+	if w := env.request(http.MethodPut, "/api/admin/roles/alchemist", admin, unknown); w.Code != http.StatusBadRequest {
+		t.Errorf("permiso desconocido: %d, esperado 400", w.Code)
+	}
+	if w := env.request(http.MethodPost, "/api/admin/roles", admin, gin.H{"name": "nuevo", "permissions": []string{"piedra:filosofal"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("crear rol con permiso desconocido: %d, esperado 400", w.Code)
+	}
+}
+
+func TestMissingRoleHasNoPermissions(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	edward := env.addAlchemist("Edward Elric")
+	user := env.addUser("maes_hughes", "alchemist", nil)
+	token := env.login("maes_hughes")
+
+	// Un usuario cuyo rol ya no existe se queda sin permisos
+	user.Role = "desaparecido"
+	if err := env.store.Users.Update(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/api/alchemists/%d", edward.ID)
+	if w := env.request(http.MethodPut, path, token, gin.H{"status": "Activo"}); w.Code != http.StatusForbidden {
+		t.Errorf("rol inexistente: %d, esperado 403", w.Code)
+	}
+}
+
+func TestCannotDropOwnRolesManage(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser("admin", "admin", nil)
+	admin := env.login("admin")
+
+	w := env.request(http.MethodPut, "/api/admin/roles/admin", admin, gin.H{"permissions": []string{"users:manage"}})
+	if w.Code != http.StatusConflict {
+		t.Errorf("quitar roles:manage al propio rol: %d, esperado 409", w.Code)
+	}
+	if w := env.request(http.MethodPost, "/api/admin/roles", admin, gin.H{"name": "archivo", "permissions": []string{}}); w.Code != http.StatusCreated {
+		t.Errorf("el administrador perdió roles:manage: %d", w.Code)
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"amestris-backend/models"
+	"amestris-backend/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+	maxSearchLength    = 200
+)
+
+// searchScopes es el permiso que necesita una API key para recibir cada
+// tipo de resultado.
+var searchScopes = map[string]string{
+	"alchemist":  "alchemists:read",
+	"mission":    "missions:read",
+	"experiment": "experiments:read",
+	"material":   "materials:read",
+}
+
+type SearchHandler struct {
+	search repository.SearchRepository
+}
+
+func NewSearchHandler(search repository.SearchRepository) *SearchHandler {
+	return &SearchHandler{search: search}
+}
+
+// Search busca q en alquimistas, misiones, solicitudes de experimento y
+// materiales. types limita los tipos de resultado, separados por comas.
+// Cada usuario solo recibe lo que podría ver en los listados.
+func (h *SearchHandler) Search(c *gin.Context) {
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro q es obligatorio"})
+		return
+	}
+	if utf8.RuneCountInString(text) > maxSearchLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La búsqueda no puede superar " + strconv.Itoa(maxSearchLength) + " caracteres"})
+		return
+	}
+
+	search := repository.SearchQuery{Text: text, Types: models.SearchTypes, Limit: defaultSearchLimit}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit debe estar entre 1 y " + strconv.Itoa(maxSearchLimit)})
+			return
+		}
+		search.Limit = limit
+	}
+	if raw := c.Query("types"); raw != "" {
+		search.Types = nil
+		for _, typ := range strings.Split(raw, ",") {
+			typ = strings.TrimSpace(typ)
+			if !slices.Contains(models.SearchTypes, typ) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Tipo de resultado inválido: " + typ})
+				return
+			}
+			if !slices.Contains(search.Types, typ) {
+				search.Types = append(search.Types, typ)
+			}
+		}
+	}
+	search.Types, search.ExperimentsOf = searchVisibility(c, search.Types)
+
+	hits, err := h.search.Search(c.Request.Context(), search)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error realizando la búsqueda"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": hits})
+}
+
+// searchVisibility quita los tipos que la petición no puede ver y, si solo
+// puede ver sus propias solicitudes de experimento, devuelve su alquimista.
+func searchVisibility(c *gin.Context, types []string) ([]string, *uint) {
+	scopes, isKey := c.Get("apiKeyScopes")
+	var visible []string
+	var experimentsOf *uint
+	for _, typ := range types {
+		if isKey && !slices.Contains(scopes.([]string), searchScopes[typ]) {
+			continue
+		}
+		if typ == "experiment" && !canViewAllExperiments(c) {
+			principal := currentPrincipal(c)
+			if principal == nil || principal.Alchemist == nil {
+				continue
+			}
+			experimentsOf = &principal.Alchemist.ID
+		}
+		visible = append(visible, typ)
+	}
+	return visible, experimentsOf
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"amestris-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// searchHits devuelve los resultados como "tipo:título".
+func searchHits(t *testing.T, w *httptest.ResponseRecorder) []string {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("búsqueda: %d %s", w.Code, w.Body)
+	}
+	var hits []string
+	for _, hit := range decode(t, w)["data"].([]any) {
+		hit := hit.(map[string]any)
+		hits = append(hits, hit["type"].(string)+":"+hit["title"].(string))
+	}
+	slices.Sort(hits)
+	return hits
+}
+
+// addSearchFixtures crea una misión y una solicitud de experimento por
+// alquimista, todas con la palabra "transmutación".
+func (e *testEnv) addSearchFixtures(alchemists ...*models.Alchemist) {
+	e.t.Helper()
+	ctx := context.Background()
+	for _, alchemist := range alchemists {
+		mission := &models.Mission{Title: "Misión de " + alchemist.Name, Description: "transmutación", AlchemistID: alchemist.ID, Status: "pending"}
+		if err := e.store.Missions.Create(ctx, mission); err != nil {
+			e.t.Fatal(err)
+		}
+		experiment := &models.ExperimentRequest{Title: "Experimento de " + alchemist.Name, Objective: "transmutación", AlchemistID: alchemist.ID, Status: "pending"}
+		if err := e.store.Experiments.Create(ctx, experiment); err != nil {
+			e.t.Fatal(err)
+		}
+	}
+}
+
+func TestSearchOnlyOwnExperiments(t *testing.T) {
+	env := newTestEnv(t)
+	edward := env.addAlchemist("Edward Elric")
+	roy := env.addAlchemist("Roy Mustang")
+	env.addSearchFixtures(edward, roy)
+	env.addUser("edward_elric", "alchemist", edward)
+	env.addUser("maes_hughes", "alchemist", nil)
+	env.addUser("olivier", "supervisor", nil)
+
+	tests := []struct {
+		username string
+		want     []string
+	}{
+		{"edward_elric", []string{
+			"experiment:Experimento de Edward Elric",
+			"mission:Misión de Edward Elric", "mission:Misión de Roy Mustang",
+		}},
+		// Sin alquimista vinculado no hay solicitudes propias
+		{"maes_hughes", []string{"mission:Misión de Edward Elric", "mission:Misión de Roy Mustang"}},
+		{"olivier", []string{
+			"experiment:Experimento de Edward Elric", "experiment:Experimento de Roy Mustang",
+			"mission:Misión de Edward Elric", "mission:Misión de Roy Mustang",
+		}},
+	}
+	for _, tt := range tests {
+		w := env.request(http.MethodGet, "/api/search?q=transmutación", env.login(tt.username), nil)
+		if got := searchHits(t, w); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: %v, esperado %v", tt.username, got, tt.want)
+		}
+	}
+}
+
+func TestSearchRespectsAPIKeyScopes(t *testing.T) {
+	env := newTestEnv(t)
+	edward := env.addAlchemist("Edward Elric")
+	env.addSearchFixtures(edward)
+	env.addUser("admin", "admin", nil)
+	admin := env.login("admin")
+
+	tests := []struct {
+		scopes []string
+		want   []string
+	}{
+		{[]string{"missions:read"}, []string{"mission:Misión de Edward Elric"}},
+		{[]string{"experiments:read"}, []string{"experiment:Experimento de Edward Elric"}},
+		{[]string{"transmute"}, nil},
+	}
+	for _, tt := range tests {
+		key, _ := env.apiKey(admin, "laboratorio-5", gin.H{"name": "busqueda", "scopes": tt.scopes})
+		req := httptest.NewRequest(http.MethodGet, "/api/search?q=transmutación", nil)
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		env.router.ServeHTTP(w, req)
+		if got := searchHits(t, w); !slices.Equal(got, tt.want) {
+			t.Errorf("scopes %v: %v, esperado %v", tt.scopes, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"amestris-backend/models"
+	"amestris-backend/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	apiKeyPrefix     = "amk_"
+	apiKeyDefaultTTL = 90 * 24 * time.Hour
+	// apiKeyTouchInterval limita cada cuánto se guarda el último uso de una
+	// clave para no escribir en cada petición.
+	apiKeyTouchInterval = time.Minute
+)
+
+// ServiceAccountHandler administra las cuentas de servicio que usan los
+// scripts del laboratorio y sus API keys. Sus rutas están restringidas a
+// administradores.
+type ServiceAccountHandler struct {
+	users   repository.UserRepository
+	apiKeys repository.APIKeyRepository
+	roles   repository.RoleRepository
+	audits  repository.AuditRepository
+}
+
+func NewServiceAccountHandler(users repository.UserRepository, apiKeys repository.APIKeyRepository,
+	roles repository.RoleRepository, audits repository.AuditRepository) *ServiceAccountHandler {
+	return &ServiceAccountHandler{users: users, apiKeys: apiKeys, roles: roles, audits: audits}
+}
+
+type serviceAccountRequest struct {
+	Username string `json:"username" binding:"required"`
+	Role     string `json:"role" binding:"required"`
+}
+
+type apiKeyRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required"`
+	// ExpiresIn es una duración como "720h"; por defecto 90 días.
+	ExpiresIn string `json:"expires_in"`
+}
+
+// newAPIKey genera una clave amk_<prefijo>_<secreto> y devuelve también su
+// prefijo visible.
+func newAPIKey() (key, prefix string) {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	prefix = apiKeyPrefix + hex.EncodeToString(id)
+	return prefix + "_" + randomToken(32), prefix
+}
+
+// splitAPIKey extrae el prefijo de una clave con el formato de newAPIKey.
+func splitAPIKey(key string) (string, bool) {
+	prefix, secret, ok := strings.Cut(strings.TrimPrefix(key, apiKeyPrefix), "_")
+	if !ok || !strings.HasPrefix(key, apiKeyPrefix) || len(prefix) != 8 || secret == "" {
+		return "", false
+	}
+	return apiKeyPrefix + prefix, true
+}
+
+// AuthenticateAPIKey resuelve la clave presentada en una petición. Devuelve
+// nil sin error si la clave no existe, está revocada o caducada, o su
+// cuenta de servicio está deshabilitada.
+func (h *ServiceAccountHandler) AuthenticateAPIKey(ctx context.Context, raw string) (*models.APIKey, error) {
+	prefix, ok := splitAPIKey(raw)
+	if !ok {
+		return nil, nil
+	}
+	key, err := h.apiKeys.FindByPrefix(ctx, prefix)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashToken(raw))) != 1 {
+		return nil, nil
+	}
+	now := time.Now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !now.Before(*key.ExpiresAt)) {
+		return nil, nil
+	}
+	if key.User == nil || !key.User.ServiceAccount || key.User.Disabled {
+		return nil, nil
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := h.apiKeys.TouchLastUsed(ctx, key.ID, now); err != nil {
+			slog.ErrorContext(ctx, "error registrando uso de API key", "api_key_id", key.ID, "error", err)
+		}
+	}
+	return key, nil
+}
+
+// loadServiceAccount resuelve la cuenta de servicio de :id o responde 404.
+func (h *ServiceAccountHandler) loadServiceAccount(c *gin.Context) (*models.User, bool) {
+	id, ok := parseID(c.Param("id"))
+	if ok {
+		user, err := h.users.Get(c.Request.Context(), id)
+		if err == nil && user.ServiceAccount {
+			return user, true
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Cuenta de servicio no encontrada"})
+	return nil, false
+}
+
+func (h *ServiceAccountHandler) audit(c *gin.Context, action string, details string) {
+	auditRequest(c, h.audits, action, "service_account",
+		fmt.Sprintf("%s (por %s)", details, c.GetString("username")))
+}
+
+func (h *ServiceAccountHandler) ListServiceAccounts(c *gin.Context) {
+	serviceAccount := true
+	users, err := h.users.List(c.Request.Context(), repository.UserFilter{ServiceAccount: &serviceAccount})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo cuentas de servicio"})
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+// CreateServiceAccount crea una cuenta sin contraseña que solo puede
+// autenticarse con API keys.
+func (h *ServiceAccountHandler) CreateServiceAccount(c *gin.Context) {
+	var req serviceAccountRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+	if !validRole(c, h.roles, req.Role) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.users.FindByUsername(ctx, req.Username); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "El nombre de usuario ya existe"})
+		return
+	}
+
+	user := models.User{Username: req.Username, Role: req.Role, ServiceAccount: true}
+	if err := h.users.Create(ctx, &user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando cuenta de servicio"})
+		return
+	}
+
+	h.audit(c, "SERVICE_ACCOUNT_CREATE", fmt.Sprintf("Cuenta de servicio %s creada con rol %s", user.Username, user.Role))
+	c.JSON(http.StatusCreated, user)
+}
+
+func (h *ServiceAccountHandler) ListAPIKeys(c *gin.Context) {
+	user, ok := h.loadServiceAccount(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.apiKeys.ListByUser(c.Request.Context(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo API keys"})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// CreateAPIKey emite una API key para la cuenta de servicio. La clave solo
+// se devuelve en esta respuesta; después solo se muestra su prefijo.
+func (h *ServiceAccountHandler) CreateAPIKey(c *gin.Context) {
+	user, ok := h.loadServiceAccount(c)
+	if !ok {
+		return
+	}
+
+	var req apiKeyRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+	if len(req.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Debe indicar al menos un permiso"})
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(models.APIKeyScopes, scope) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  fmt.Sprintf("Permiso desconocido: %s", scope),
+				"scopes": models.APIKeyScopes,
+			})
+			return
+		}
+	}
+
+	ttl := apiKeyDefaultTTL
+	if req.ExpiresIn != "" {
+		parsed, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Duración expires_in inválida"})
+			return
+		}
+		ttl = parsed
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	scopes := slices.Clone(req.Scopes)
+	slices.Sort(scopes)
+	raw, prefix := newAPIKey()
+	key := models.APIKey{
+		UserID:      user.ID,
+		Name:        req.Name,
+		Prefix:      prefix,
+		KeyHash:     hashToken(raw),
+		Scopes:      slices.Compact(scopes),
+		CreatedByID: c.GetUint("userID"),
+		ExpiresAt:   &expiresAt,
+	}
+	if err := h.apiKeys.Create(c.Request.Context(), &key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando API key"})
+		return
+	}
+
+	h.audit(c, "API_KEY_CREATE", fmt.Sprintf("API key %s (%s) emitida para %s con permisos: %s",
+		key.Prefix, key.Name, user.Username, strings.Join(key.Scopes, " ")))
+	c.JSON(http.StatusCreated, gin.H{
+		"api_key": key,
+		"key":     raw,
+	})
+}
+
+func (h *ServiceAccountHandler) RevokeAPIKey(c *gin.Context) {
+	user, ok := h.loadServiceAccount(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	keyID, ok := parseID(c.Param("keyId"))
+	var key *models.APIKey
+	if ok {
+		var err error
+		if key, err = h.apiKeys.Get(ctx, keyID); err != nil || key.UserID != user.ID {
+			ok = false
+		}
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key no encontrada"})
+		return
+	}
+
+	if err := h.apiKeys.Revoke(ctx, key.ID, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revocando API key"})
+		return
+	}
+
+	h.audit(c, "API_KEY_REVOKE", fmt.Sprintf("API key %s (%s) de %s revocada", key.Prefix, key.Name, user.Username))
+	c.JSON(http.StatusOK, gin.H{"message": "API key revocada"})
+}
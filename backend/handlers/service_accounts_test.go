@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKey crea con la API de administración una cuenta de servicio con rol
+// supervisor, si no existe, y le emite una clave. Devuelve la clave y su id.
+func (e *testEnv) apiKey(admin, username string, body gin.H) (string, uint) {
+	e.t.Helper()
+	account, err := e.store.Users.FindByUsername(e.t.Context(), username)
+	if err != nil {
+		w := e.request(http.MethodPost, "/api/admin/service-accounts", admin, gin.H{"username": username, "role": "supervisor"})
+		if w.Code != http.StatusCreated {
+			e.t.Fatalf("cuenta de servicio: %d %s", w.Code, w.Body)
+		}
+		account, _ = e.store.Users.FindByUsername(e.t.Context(), username)
+	}
+
+	w := e.request(http.MethodPost, fmt.Sprintf("/api/admin/service-accounts/%d/keys", account.ID), admin, body)
+	if w.Code != http.StatusCreated {
+		e.t.Fatalf("API key: %d %s", w.Code, w.Body)
+	}
+	created := decode(e.t, w)
+	return created["key"].(string), uint(created["api_key"].(map[string]any)["id"].(float64))
+}
+
+// withAPIKey hace la petición con la clave en X-API-Key.
+func (e *testEnv) withAPIKey(method, path, key string) int {
+	e.t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("X-API-Key", key)
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser("admin", "admin", nil)
+	admin := env.login("admin")
+	key, _ := env.apiKey(admin, "laboratorio-5", gin.H{"name": "informes", "scopes": []string{"missions:read"}})
+
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		t.Fatalf("clave sin prefijo %s: %s", apiKeyPrefix, key)
+	}
+	if code := env.withAPIKey(http.MethodGet, "/api/missions", key); code != http.StatusOK {
+		t.Errorf("X-API-Key: %d, esperado 200", code)
+	}
+	if w := env.request(http.MethodGet, "/api/missions", key, nil); w.Code != http.StatusOK {
+		t.Errorf("clave como Bearer: %d, esperado 200", w.Code)
+	}
+
+	prefix, _ := splitAPIKey(key)
+	for name, forged := range map[string]string{
+		"otro secreto con el mismo prefijo": prefix + "_" + strings.Repeat("a", 43),
+		"prefijo desconocido":               apiKeyPrefix + "00000000_" + strings.Repeat("a", 43),
+		"mal formada":                       apiKeyPrefix + "corta",
+	} {
+		if code := env.withAPIKey(http.MethodGet, "/api/missions", forged); code != http.StatusUnauthorized {
+			t.Errorf("%s: %d, esperado 401", name, code)
+		}
+	}
+
+	// Sin el scope de la ruta
+	if code := env.withAPIKey(http.MethodGet, "/api/experiments", key); code != http.StatusForbidden {
+		t.Errorf("ruta sin scope: %d, esperado 403", code)
+	}
+	// Ni en las rutas que solo aceptan access tokens
+	if w := env.request(http.MethodPut, "/api/missions/1/status", key, gin.H{"status": "completed"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("ruta sin API keys: %d, esperado 401", w.Code)
+	}
+}
+
+func TestAPIKeyRevokedAndExpired(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser("admin", "admin", nil)
+	admin := env.login("admin")
+	scopes := []string{"missions:read"}
+
+	key, id := env.apiKey(admin, "laboratorio-5", gin.H{"name": "informes", "scopes": scopes})
+	account, _ := env.store.Users.FindByUsername(t.Context(), "laboratorio-5")
+	path := fmt.Sprintf("/api/admin/service-accounts/%d/keys/%d", account.ID, id)
+	if w := env.request(http.MethodDelete, path, admin, nil); w.Code != http.StatusOK {
+		t.Fatalf("revocación: %d %s", w.Code, w.Body)
+	}
+	if code := env.withAPIKey(http.MethodGet, "/api/missions", key); code != http.StatusUnauthorized {
+		t.Errorf("clave revocada: %d, esperado 401", code)
+	}
+
+	expired, _ := env.apiKey(admin, "laboratorio-5", gin.H{"name": "efímera", "scopes": scopes, "expires_in": "1ns"})
+	if code := env.withAPIKey(http.MethodGet, "/api/missions", expired); code != http.StatusUnauthorized {
+		t.Errorf("clave caducada: %d, esperado 401", code)
+	}
+}
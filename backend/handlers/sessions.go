@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"amestris-backend/models"
+	"amestris-backend/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// randomToken devuelve n bytes aleatorios codificados en base64url.
+func randomToken(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// hashToken es el valor con el que se guarda un token de renovación.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueTokens emite un access token y un token de renovación para la sesión.
+func (h *AuthHandler) issueTokens(ctx context.Context, user models.User, sessionID string) (gin.H, error) {
+	access, claims, err := h.GenerateJWT(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh := randomToken(32)
+	err = h.refreshTokens.Create(ctx, &models.RefreshToken{
+		UserID:          user.ID,
+		SessionID:       sessionID,
+		TokenHash:       hashToken(refresh),
+		AccessJTI:       claims.ID,
+		AccessExpiresAt: claims.ExpiresAt.Time,
+		ExpiresAt:       time.Now().Add(h.refreshTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"token":         access,
+		"token_type":    "Bearer",
+		"expires_in":    int(h.accessTTL.Seconds()),
+		"refresh_token": refresh,
+	}, nil
+}
+
+// RevokeAllSessions cierra todas las sesiones del usuario como
+// revokeSessions. La usa el comando user reset-password.
+func RevokeAllSessions(ctx context.Context, refreshTokens repository.RefreshTokenRepository,
+	revokedTokens repository.RevokedTokenRepository, userID uint) (int, error) {
+	return revokeSessions(ctx, refreshTokens, revokedTokens, userID, "")
+}
+
+// revokeSessions revoca los tokens de renovación del usuario (solo los de
+// sessionID si no está vacío) y añade a la lista de denegación los access
+// tokens emitidos con ellos que siguen vigentes. Devuelve cuántas sesiones
+// activas se cerraron.
+func revokeSessions(ctx context.Context, refreshTokens repository.RefreshTokenRepository,
+	revokedTokens repository.RevokedTokenRepository, userID uint, sessionID string) (int, error) {
+	tokens, err := refreshTokens.ListUnexpired(ctx, userID, sessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	ids := make([]uint, 0, len(tokens))
+	sessions := map[string]bool{}
+	for _, token := range tokens {
+		ids = append(ids, token.ID)
+		if token.RevokedAt == nil {
+			sessions[token.SessionID] = true
+		}
+		if token.AccessExpiresAt.After(now) {
+			err := revokedTokens.Add(ctx, &models.RevokedToken{JTI: token.AccessJTI, ExpiresAt: token.AccessExpiresAt})
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if _, err := refreshTokens.Revoke(ctx, ids, now); err != nil {
+		return 0, err
+	}
+	return len(sessions), nil
+}
+
+// RefreshToken canjea un token de renovación por un nuevo par de tokens. El
+// token usado queda revocado; si se presenta uno ya revocado se asume robo y
+// se cierra la sesión entera.
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req refreshRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	stored, err := h.refreshTokens.FindByHash(ctx, hashToken(req.RefreshToken))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token de renovación inválido"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando token"})
+		return
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token de renovación caducado"})
+		return
+	}
+
+	revoked := int64(0)
+	if stored.RevokedAt == nil {
+		if revoked, err = h.refreshTokens.Revoke(ctx, []uint{stored.ID}, time.Now()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando token"})
+			return
+		}
+	}
+	if revoked == 0 {
+		if _, err := revokeSessions(ctx, h.refreshTokens, h.revokedTokens, stored.UserID, stored.SessionID); err != nil {
+			slog.ErrorContext(ctx, "error revocando sesión", "user_id", stored.UserID, "error", err)
+		}
+		createAuditLog(ctx, h.audits, stored.UserID, nil, "UNAUTHORIZED_ACCESS", "session",
+			"Reutilización de token de renovación detectada, sesión revocada")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token de renovación inválido"})
+		return
+	}
+
+	user, err := h.users.Get(ctx, stored.UserID)
+	if err != nil || user.Disabled || user.Pending {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token de renovación inválido"})
+		return
+	}
+
+	response, err := h.issueTokens(ctx, *user, stored.SessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generando token"})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Logout cierra la sesión del token actual: lo revoca junto con sus tokens
+// de renovación.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.GetUint("userID")
+
+	err := h.revokedTokens.Add(ctx, &models.RevokedToken{
+		JTI:       c.GetString("tokenID"),
+		ExpiresAt: c.GetTime("tokenExpiresAt"),
+	})
+	if err == nil {
+		_, err = revokeSessions(ctx, h.refreshTokens, h.revokedTokens, userID, c.GetString("sessionID"))
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error cerrando sesión"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sesión cerrada"})
+}
+
+// RunTokenCleanup borra cada interval los tokens de renovación caducados y
+// las entradas vencidas de la lista de denegación, hasta que se cancele ctx.
+func (h *AuthHandler) RunTokenCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			refresh, err := h.refreshTokens.DeleteExpired(ctx, now)
+			if err != nil {
+				slog.Error("error limpiando tokens de renovación", "error", err)
+				continue
+			}
+			denied, err := h.revokedTokens.DeleteExpired(ctx, now)
+			if err != nil {
+				slog.Error("error limpiando tokens revocados", "error", err)
+				continue
+			}
+			slog.Debug("limpieza de tokens completada", "refresh_tokens", refresh, "revoked_tokens", denied)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// session inicia sesión y devuelve el access token y el de renovación.
+func (e *testEnv) session(username string) (access, refresh string) {
+	e.t.Helper()
+	w := e.request(http.MethodPost, "/login", "", gin.H{"username": username, "password": testPassword})
+	if w.Code != http.StatusOK {
+		e.t.Fatalf("login de %s: %d %s", username, w.Code, w.Body)
+	}
+	body := decode(e.t, w)
+	return body["token"].(string), body["refresh_token"].(string)
+}
+
+// refresh canjea el token de renovación y devuelve la respuesta.
+func (e *testEnv) refresh(token string) (int, map[string]any) {
+	e.t.Helper()
+	w := e.request(http.MethodPost, "/token/refresh", "", gin.H{"refresh_token": token})
+	if w.Code != http.StatusOK {
+		return w.Code, nil
+	}
+	return w.Code, decode(e.t, w)
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser("edward_elric", "alchemist", nil)
+	firstAccess, firstRefresh := env.session("edward_elric")
+	otherAccess, otherRefresh := env.session("edward_elric")
+
+	code, rotated := env.refresh(firstRefresh)
+	if code != http.StatusOK {
+		t.Fatalf("rotación: %d", code)
+	}
+	rotatedAccess, rotatedRefresh := rotated["token"].(string), rotated["refresh_token"].(string)
+	if rotatedRefresh == firstRefresh {
+		t.Fatal("la rotación devolvió el mismo token de renovación")
+	}
+
+	// Alguien repite el token ya usado: se asume robo
+	if code, _ := env.refresh(firstRefresh); code != http.StatusUnauthorized {
+		t.Fatalf("token reutilizado: %d, esperado 401", code)
+	}
+	if n := env.audits("UNAUTHORIZED_ACCESS"); n != 1 {
+		t.Errorf("%d reutilizaciones auditadas, esperada 1", n)
+	}
+
+	// Toda la sesión queda cerrada: el token rotado y los access tokens
+	if code, _ := env.refresh(rotatedRefresh); code != http.StatusUnauthorized {
+		t.Errorf("token rotado tras la reutilización: %d, esperado 401", code)
+	}
+	for name, token := range map[string]string{"original": firstAccess, "rotado": rotatedAccess} {
+		if w := env.request(http.MethodGet, "/api/missions", token, nil); w.Code != http.StatusUnauthorized {
+			t.Errorf("access token %s: %d, esperado 401", name, w.Code)
+		}
+	}
+
+	// La otra sesión del usuario sigue abierta
+	if w := env.request(http.MethodGet, "/api/missions", otherAccess, nil); w.Code != http.StatusOK {
+		t.Errorf("otra sesión: %d, esperado 200", w.Code)
+	}
+	if code, _ := env.refresh(otherRefresh); code != http.StatusOK {
+		t.Errorf("renovación de otra sesión: %d, esperado 200", code)
+	}
+}
+
+func TestLogoutClosesOnlyCurrentSession(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser("edward_elric", "alchemist", nil)
+	access, refresh := env.session("edward_elric")
+	otherAccess, _ := env.session("edward_elric")
+
+	if w := env.request(http.MethodPost, "/logout", access, nil); w.Code != http.StatusOK {
+		t.Fatalf("logout: %d %s", w.Code, w.Body)
+	}
+	if w := env.request(http.MethodGet, "/api/missions", access, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("access token tras logout: %d, esperado 401", w.Code)
+	}
+	if code, _ := env.refresh(refresh); code != http.StatusUnauthorized {
+		t.Errorf("renovación tras logout: %d, esperado 401", code)
+	}
+	if w := env.request(http.MethodGet, "/api/missions", otherAccess, nil); w.Code != http.StatusOK {
+		t.Errorf("otra sesión tras logout: %d, esperado 200", w.Code)
+	}
+}
@@ -1,17 +1,37 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"log/slog"
 	"math"
 	"net/http"
 	"strings"
 	"time"
 
+	"amestris-backend/logging"
+	"amestris-backend/metrics"
 	"amestris-backend/models"
+	"amestris-backend/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-func HandleTransmutation(c *gin.Context) {
+type TransmutationHandler struct {
+	transmutations repository.TransmutationRepository
+	alchemists     repository.AlchemistRepository
+	tasks          TaskRunner
+}
+
+func NewTransmutationHandler(transmutations repository.TransmutationRepository, alchemists repository.AlchemistRepository,
+	tasks TaskRunner) *TransmutationHandler {
+	return &TransmutationHandler{transmutations: transmutations, alchemists: alchemists, tasks: tasks}
+}
+
+// HandleTransmutation registra la transmutación a nombre del alquimista
+// vinculado al usuario. Solo las cuentas de servicio, que no tienen perfil
+// de alquimista, pueden indicar otro con alchemist_id, que debe existir.
+func (h *TransmutationHandler) HandleTransmutation(c *gin.Context) {
 	var request struct {
 		InputMaterials []string `json:"input_materials"`
 		OutputMaterial string   `json:"output_material"`
@@ -28,22 +48,51 @@ func HandleTransmutation(c *gin.Context) {
 		return
 	}
 
-	// Simular procesamiento asíncrono
-	go func() {
-		time.Sleep(2 * time.Second)
-
-		// Crear log de la transmutación
-		transmutationLog := models.TransmutationLog{
-			AlchemistID:  request.AlchemistID,
-			Input:        strings.Join(request.InputMaterials, ","),
-			Output:       request.OutputMaterial,
-			Success:      true,
-			Cost:         calculateTransmutationCost(request.InputMaterials, "moderate"),
-			EnergyUsed:   calculateEnergyRequired("moderate"),
-			LawRespected: verifyEquivalentExchange(request.InputMaterials, request.OutputMaterial),
+	if principal := currentPrincipal(c); principal == nil || !principal.User.ServiceAccount {
+		alchemist, ok := requireAlchemist(c)
+		if !ok {
+			return
+		}
+		request.AlchemistID = alchemist.ID
+	} else if _, err := h.alchemists.Get(c.Request.Context(), request.AlchemistID); err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando el alquimista"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Alquimista no encontrado"})
+		return
+	}
+
+	// Crear log de la transmutación
+	transmutationLog := models.TransmutationLog{
+		AlchemistID:  request.AlchemistID,
+		Input:        strings.Join(request.InputMaterials, ","),
+		Output:       request.OutputMaterial,
+		Success:      true,
+		Cost:         calculateTransmutationCost(request.InputMaterials, "moderate"),
+		EnergyUsed:   calculateEnergyRequired("moderate"),
+		LawRespected: verifyEquivalentExchange(request.InputMaterials, request.OutputMaterial),
+		RequestID:    logging.RequestID(c.Request.Context()),
+	}
+
+	// Simular procesamiento asíncrono. Si el servidor se apaga durante la
+	// espera, el log se guarda de inmediato en lugar de perderse.
+	persist := func(ctx context.Context) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-ctx.Done():
+		}
+		if err := h.transmutations.Create(context.WithoutCancel(ctx), &transmutationLog); err != nil {
+			slog.Error("error guardando log de transmutación",
+				"request_id", transmutationLog.RequestID, "error", err)
+			return
 		}
-		models.DB.Create(&transmutationLog)
-	}()
+		metrics.Transmutations.WithLabelValues(
+			metrics.Bool(transmutationLog.Success), metrics.Bool(transmutationLog.LawRespected)).Inc()
+	}
+	if !h.tasks.Go("transmutation-log", persist) {
+		persist(canceledContext())
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":              "Transmutación exitosa - Ley del Intercambio Equivalente respetada",
@@ -53,7 +102,15 @@ func HandleTransmutation(c *gin.Context) {
 	})
 }
 
-func SimulateTransmutation(c *gin.Context) {
+// canceledContext permite ejecutar una tarea de fondo de forma síncrona
+// saltando sus esperas.
+func canceledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+func (h *TransmutationHandler) SimulateTransmutation(c *gin.Context) {
 	var request struct {
 		InputMaterials []string `json:"input_materials"`
 		OutputMaterial string   `json:"output_material"`
@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"amestris-backend/models"
+	"amestris-backend/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserHandler agrupa la administración de cuentas de usuario. Todas sus
+// rutas están restringidas a administradores.
+type UserHandler struct {
+	users         repository.UserRepository
+	alchemists    repository.AlchemistRepository
+	refreshTokens repository.RefreshTokenRepository
+	revokedTokens repository.RevokedTokenRepository
+	recoveryCodes repository.RecoveryCodeRepository
+	roles         repository.RoleRepository
+	audits        repository.AuditRepository
+	passwords     Passwords
+}
+
+func NewUserHandler(users repository.UserRepository, alchemists repository.AlchemistRepository,
+	refreshTokens repository.RefreshTokenRepository, revokedTokens repository.RevokedTokenRepository,
+	recoveryCodes repository.RecoveryCodeRepository, roles repository.RoleRepository,
+	audits repository.AuditRepository, passwords Passwords) *UserHandler {
+	return &UserHandler{users: users, alchemists: alchemists, refreshTokens: refreshTokens, revokedTokens: revokedTokens,
+		recoveryCodes: recoveryCodes, roles: roles, audits: audits, passwords: passwords}
+}
+
+type roleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type statusRequest struct {
+	Disabled *bool `json:"disabled" binding:"required"`
+}
+
+type linkAlchemistRequest struct {
+	// AlchemistID nulo desvincula al usuario.
+	AlchemistID *uint `json:"alchemist_id"`
+}
+
+// loadUser resuelve el usuario de :id o responde 404.
+func (h *UserHandler) loadUser(c *gin.Context) (*models.User, bool) {
+	id, ok := parseID(c.Param("id"))
+	if ok {
+		if user, err := h.users.Get(c.Request.Context(), id); err == nil {
+			return user, true
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+	return nil, false
+}
+
+// loadOtherUser es loadUser pero rechaza que el administrador se modifique a
+// sí mismo, para no dejar el sistema sin administradores por error.
+func (h *UserHandler) loadOtherUser(c *gin.Context) (*models.User, bool) {
+	user, ok := h.loadUser(c)
+	if !ok {
+		return nil, false
+	}
+	if user.ID == c.GetUint("userID") {
+		c.JSON(http.StatusConflict, gin.H{"error": "No puede realizar esta operación sobre su propia cuenta"})
+		return nil, false
+	}
+	return user, true
+}
+
+func (h *UserHandler) audit(c *gin.Context, action string, details string) {
+	auditRequest(c, h.audits, action, "user",
+		fmt.Sprintf("%s (por %s)", details, c.GetString("username")))
+}
+
+func (h *UserHandler) revokeSessions(ctx context.Context, userID uint) error {
+	_, err := revokeSessions(ctx, h.refreshTokens, h.revokedTokens, userID, "")
+	return err
+}
+
+// ListUsers admite los filtros username, role, disabled, pending y
+// alchemist_id.
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	filter := repository.UserFilter{
+		Username: c.Query("username"),
+		Role:     c.Query("role"),
+	}
+	if raw := c.Query("disabled"); raw != "" {
+		disabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Filtro disabled inválido"})
+			return
+		}
+		filter.Disabled = &disabled
+	}
+	if raw := c.Query("pending"); raw != "" {
+		pending, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Filtro pending inválido"})
+			return
+		}
+		filter.Pending = &pending
+	}
+	if raw := c.Query("alchemist_id"); raw != "" {
+		id, ok := parseID(raw)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Filtro alchemist_id inválido"})
+			return
+		}
+		filter.AlchemistID = &id
+	}
+
+	users, err := h.users.List(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo usuarios"})
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+func (h *UserHandler) GetUser(c *gin.Context) {
+	if user, ok := h.loadUser(c); ok {
+		c.JSON(http.StatusOK, user)
+	}
+}
+
+// UpdateUserRole cambia el rol y cierra las sesiones del usuario para que
+// los tokens con el rol anterior dejen de valer.
+func (h *UserHandler) UpdateUserRole(c *gin.Context) {
+	user, ok := h.loadOtherUser(c)
+	if !ok {
+		return
+	}
+
+	var req roleRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+	if !validRole(c, h.roles, req.Role) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	previous := user.Role
+	user.Role = req.Role
+	if err := h.users.Update(ctx, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando usuario"})
+		return
+	}
+	if err := h.revokeSessions(ctx, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revocando sesiones"})
+		return
+	}
+
+	h.audit(c, "USER_ROLE_CHANGE", fmt.Sprintf("Rol de %s cambiado de %s a %s", user.Username, previous, user.Role))
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateUserStatus habilita o deshabilita la cuenta. Deshabilitarla cierra
+// todas sus sesiones.
+func (h *UserHandler) UpdateUserStatus(c *gin.Context) {
+	user, ok := h.loadOtherUser(c)
+	if !ok {
+		return
+	}
+
+	var req statusRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user.Disabled = *req.Disabled
+	if err := h.users.Update(ctx, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando usuario"})
+		return
+	}
+
+	if user.Disabled {
+		if err := h.revokeSessions(ctx, user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revocando sesiones"})
+			return
+		}
+		h.audit(c, "USER_DISABLE", fmt.Sprintf("Cuenta de %s deshabilitada", user.Username))
+	} else {
+		h.audit(c, "USER_ENABLE", fmt.Sprintf("Cuenta de %s habilitada", user.Username))
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// LinkAlchemist vincula el usuario a un alquimista o lo desvincula si
+// alchemist_id es nulo. Un alquimista solo puede tener un usuario.
+func (h *UserHandler) LinkAlchemist(c *gin.Context) {
+	user, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+
+	var req linkAlchemistRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	details := fmt.Sprintf("Usuario %s desvinculado de su alquimista", user.Username)
+	if req.AlchemistID != nil {
+		alchemist, err := h.alchemists.Get(ctx, *req.AlchemistID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Alquimista no encontrado"})
+			return
+		}
+		if alchemist.User != nil && alchemist.User.ID != user.ID {
+			c.JSON(http.StatusConflict, gin.H{"error": "El alquimista ya está vinculado a otro usuario"})
+			return
+		}
+		details = fmt.Sprintf("Usuario %s vinculado al alquimista %s", user.Username, alchemist.Name)
+	}
+
+	user.AlchemistID = req.AlchemistID
+	user.Alchemist = nil
+	if err := h.users.Update(ctx, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando usuario"})
+		return
+	}
+
+	h.audit(c, "USER_LINK_ALCHEMIST", details)
+	if updated, err := h.users.Get(ctx, user.ID); err == nil {
+		user = updated
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// ResetUserPassword asigna una contraseña de un solo uso, que se devuelve
+// una sola vez y debe cambiarse al iniciar sesión, levanta el bloqueo por
+// intentos fallidos y cierra todas las sesiones del usuario.
+func (h *UserHandler) ResetUserPassword(c *gin.Context) {
+	user, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+	if user.ServiceAccount {
+		c.JSON(http.StatusConflict, gin.H{"error": "Las cuentas de servicio no tienen contraseña"})
+		return
+	}
+
+	password := h.passwords.Policy.Generate()
+	hashed, err := h.passwords.Hash(password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error procesando contraseña"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user.Password = hashed
+	user.MustChangePassword = true
+	user.FailedLogins = 0
+	user.LockedUntil = nil
+	if err := h.users.Update(ctx, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando usuario"})
+		return
+	}
+	if err := h.revokeSessions(ctx, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revocando sesiones"})
+		return
+	}
+
+	h.audit(c, "USER_PASSWORD_RESET", fmt.Sprintf("Contraseña de %s restablecida", user.Username))
+	c.JSON(http.StatusOK, gin.H{
+		"message":              "Contraseña restablecida",
+		"username":             user.Username,
+		"password":             password,
+		"must_change_password": true,
+	})
+}
+
+// UnlockUser levanta el bloqueo por intentos fallidos y reinicia el
+// contador.
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	user, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.users.SetLockedUntil(ctx, user.ID, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando usuario"})
+		return
+	}
+
+	h.audit(c, "USER_UNLOCK", fmt.Sprintf("Cuenta de %s desbloqueada tras %d intentos fallidos", user.Username, user.FailedLogins))
+	user.LockedUntil = nil
+	user.FailedLogins = 0
+	c.JSON(http.StatusOK, user)
+}
+
+// ResetUserMFA elimina el segundo factor de un usuario que perdió su
+// dispositivo y sus códigos de recuperación, y cierra sus sesiones. Si su rol
+// lo exige, deberá activarlo de nuevo en el siguiente login.
+func (h *UserHandler) ResetUserMFA(c *gin.Context) {
+	user, ok := h.loadOtherUser(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	if err := h.users.Update(ctx, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando usuario"})
+		return
+	}
+	if err := h.recoveryCodes.Replace(ctx, user.ID, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error borrando códigos de recuperación"})
+		return
+	}
+	if err := h.revokeSessions(ctx, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revocando sesiones"})
+		return
+	}
+
+	h.audit(c, "MFA_RESET", fmt.Sprintf("Verificación en dos pasos de %s restablecida", user.Username))
+	c.JSON(http.StatusOK, user)
+}
+
+// RevokeUserSessions cierra todas las sesiones de un usuario.
+func (h *UserHandler) RevokeUserSessions(c *gin.Context) {
+	user, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := revokeSessions(c.Request.Context(), h.refreshTokens, h.revokedTokens, user.ID, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revocando sesiones"})
+		return
+	}
+
+	h.audit(c, "SESSIONS_REVOKE", fmt.Sprintf("Sesiones de %s revocadas (%d activas)", user.Username, sessions))
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Sesiones revocadas",
+		"sessions": sessions,
+	})
+}
+
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	user, ok := h.loadOtherUser(c)
+	if !ok {
+		return
+	}
+
+	// Revocar primero para invalidar los access tokens aún vigentes
+	ctx := c.Request.Context()
+	if err := h.revokeSessions(ctx, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revocando sesiones"})
+		return
+	}
+	if err := h.users.Delete(ctx, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error eliminando usuario"})
+		return
+	}
+
+	h.audit(c, "USER_DELETE", fmt.Sprintf("Usuario %s eliminado", user.Username))
+	c.JSON(http.StatusOK, gin.H{"message": "Usuario eliminado"})
+}
@@ -0,0 +1,74 @@
+// Package lifecycle coordina las goroutines de fondo del servidor para que
+// terminen de forma ordenada al apagarlo.
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// Supervisor lanza tareas ligadas a un contexto común y espera a que todas
+// terminen durante el apagado.
+type Supervisor struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	stopped bool
+}
+
+func NewSupervisor(parent context.Context) *Supervisor {
+	ctx, cancel := context.WithCancel(parent)
+	return &Supervisor{ctx: ctx, cancel: cancel}
+}
+
+// Go ejecuta fn en una goroutine supervisada. El contexto recibido se cancela
+// cuando empieza el apagado; fn debe terminar o persistir su trabajo en ese
+// momento. Devuelve false si el supervisor ya se está apagando y la tarea no
+// se lanzó.
+func (s *Supervisor) Go(name string, fn func(ctx context.Context)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return false
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				slog.Error("tarea de fondo terminó con pánico", "task", name, "panic", r)
+			}
+		}()
+		fn(s.ctx)
+	}()
+	return true
+}
+
+// Shutdown cancela el contexto de las tareas y espera a que terminen o a que
+// venza ctx.
+func (s *Supervisor) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("tareas de fondo sin terminar: %w", ctx.Err())
+	}
+}
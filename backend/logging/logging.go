@@ -0,0 +1,111 @@
+// Package logging configura el logger slog del backend y propaga los datos de
+// la petición (request ID y usuario) a través del context.Context, de modo
+// que cualquier llamada a slog.*Context los incluya automáticamente.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+type requestKey struct{}
+
+// RequestInfo identifica la petición en curso. Se guarda como puntero en el
+// contexto para que los middlewares posteriores (autenticación) puedan
+// completar el usuario sin reemplazar el contexto.
+type RequestInfo struct {
+	mu        sync.RWMutex
+	requestID string
+	userID    uint
+}
+
+// WithRequest devuelve un contexto hijo asociado a requestID.
+func WithRequest(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestKey{}, &RequestInfo{requestID: requestID})
+}
+
+// SetUserID registra el usuario autenticado en la petición de ctx.
+func SetUserID(ctx context.Context, id uint) {
+	if info := requestInfo(ctx); info != nil {
+		info.mu.Lock()
+		info.userID = id
+		info.mu.Unlock()
+	}
+}
+
+func requestInfo(ctx context.Context) *RequestInfo {
+	if ctx == nil {
+		return nil
+	}
+	info, _ := ctx.Value(requestKey{}).(*RequestInfo)
+	return info
+}
+
+// RequestID devuelve el ID de la petición asociada a ctx, o "".
+func RequestID(ctx context.Context) string {
+	info := requestInfo(ctx)
+	if info == nil {
+		return ""
+	}
+	info.mu.RLock()
+	defer info.mu.RUnlock()
+	return info.requestID
+}
+
+// UserID devuelve el usuario autenticado de la petición, o 0.
+func UserID(ctx context.Context) uint {
+	info := requestInfo(ctx)
+	if info == nil {
+		return 0
+	}
+	info.mu.RLock()
+	defer info.mu.RUnlock()
+	return info.userID
+}
+
+// contextHandler añade request_id y user_id a cada registro.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if id := UserID(ctx); id != 0 {
+		record.AddAttrs(slog.Uint64("user_id", uint64(id)))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// New construye un logger con el formato ("json" o "text") y nivel dados.
+func New(w io.Writer, format, level string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(level)}
+
+	var handler slog.Handler
+	if strings.EqualFold(format, "text") {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(contextHandler{handler})
+}
+
+func ParseLevel(level string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
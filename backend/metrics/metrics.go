@@ -0,0 +1,98 @@
+// Package metrics define las métricas Prometheus del backend. Todas se
+// registran en Registry, que se expone en /metrics.
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "amestris"
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Peticiones HTTP atendidas por ruta, método y código de estado.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latencia de las peticiones HTTP por ruta y método.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	Transmutations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transmutations_total",
+		Help:      "Transmutaciones registradas por resultado y respeto del intercambio equivalente.",
+	}, []string{"success", "law_respected"})
+
+	ExperimentApprovals = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "experiment_approvals_total",
+		Help:      "Solicitudes de experimento aprobadas por nivel de riesgo.",
+	}, []string{"risk_level"})
+
+	AuditLogs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "audit_logs_total",
+		Help:      "Registros de auditoría creados por severidad y acción.",
+	}, []string{"severity", "action"})
+
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Peticiones rechazadas por límite de peticiones, por grupo de rutas.",
+	}, []string{"group"})
+
+	BackgroundAuditDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "background_audit_duration_seconds",
+		Help:      "Duración de cada verificación automática de auditoría.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	BackgroundAuditFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "background_audit_failures_total",
+		Help:      "Verificaciones automáticas de auditoría que terminaron con error.",
+	})
+
+	BackgroundAuditLastSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "background_audit_last_success_timestamp_seconds",
+		Help:      "Momento (Unix) de la última verificación automática exitosa.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		Transmutations,
+		ExperimentApprovals,
+		AuditLogs,
+		RateLimited,
+		BackgroundAuditDuration,
+		BackgroundAuditFailures,
+		BackgroundAuditLastSuccess,
+	)
+}
+
+// RegisterDB expone las estadísticas del pool de conexiones.
+func RegisterDB(db *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, "amestris"))
+}
+
+func Bool(b bool) string {
+	return strconv.FormatBool(b)
+}
@@ -1,69 +1,207 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
+	"slices"
+	"strings"
 
+	"amestris-backend/logging"
 	"amestris-backend/models"
+	"amestris-backend/signing"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// TokenDenylist indica si un access token fue revocado antes de caducar. Lo
+// implementa repository.RevokedTokenRepository.
+type TokenDenylist interface {
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// APIKeyAuthenticator resuelve una API key de cuenta de servicio. Devuelve
+// nil sin error si la clave no es válida. Lo implementa
+// handlers.ServiceAccountHandler.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+// AuthMiddleware autentica con un access token JWT o, si apiKeys no es nil,
+// también con una API key enviada en X-API-Key o como Bearer.
+func AuthMiddleware(keys *signing.KeySet, denylist TokenDenylist, apiKeys APIKeyAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
+		if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
+			tokenString = tokenString[7:]
+		}
+
+		if apiKeys != nil {
+			if key := c.GetHeader("X-API-Key"); key != "" || strings.HasPrefix(tokenString, "amk_") {
+				if key == "" {
+					key = tokenString
+				}
+				authenticateAPIKey(c, apiKeys, key)
+				return
+			}
+		}
+
 		if tokenString == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token de autorización requerido"})
 			c.Abort()
 			return
 		}
 
-		if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-			tokenString = tokenString[7:]
-		}
-
 		claims := &models.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return models.JWTSecret, nil
-		})
+		token, err := keys.Parse(tokenString, claims, signing.TypeAccess)
 
-		if err != nil || !token.Valid {
+		// Los tokens sin jti no se pueden revocar y no se aceptan
+		if err != nil || !token.Valid || claims.ID == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token inválido"})
 			c.Abort()
 			return
 		}
 
+		revoked, err := denylist.IsRevoked(c.Request.Context(), claims.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando token"})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token revocado"})
+			c.Abort()
+			return
+		}
+
+		logging.SetUserID(c.Request.Context(), claims.UserID)
 		c.Set("userID", claims.UserID)
 		c.Set("role", claims.Role)
 		c.Set("username", claims.Username)
+		c.Set("tokenID", claims.ID)
+		c.Set("sessionID", claims.SessionID)
+		c.Set("tokenExpiresAt", claims.ExpiresAt.Time)
+		c.Set("mustChangePassword", claims.MustChangePassword)
+		c.Set("mfaSetupRequired", claims.MFASetupRequired)
 
 		c.Next()
 	}
 }
 
-func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
+func authenticateAPIKey(c *gin.Context, apiKeys APIKeyAuthenticator, raw string) {
+	key, err := apiKeys.AuthenticateAPIKey(c.Request.Context(), raw)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando API key"})
+		c.Abort()
+		return
+	}
+	if key == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API key inválida"})
+		c.Abort()
+		return
+	}
+
+	// Lo que se haga con la clave se atribuye a su cuenta de servicio
+	logging.SetUserID(c.Request.Context(), key.UserID)
+	c.Set("userID", key.UserID)
+	c.Set("role", key.User.Role)
+	c.Set("username", key.User.Username)
+	c.Set("apiKeyID", key.ID)
+	c.Set("apiKeyScopes", []string(key.Scopes))
+
+	c.Next()
+}
+
+// RequireScope exige que una petición autenticada con API key tenga el
+// permiso scope. Las peticiones con access token no se ven afectadas.
+func RequireScope(scope string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userRole, exists := c.Get("role")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Acceso denegado"})
+		if scopes, ok := c.Get("apiKeyScopes"); ok && !slices.Contains(scopes.([]string), scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "La API key no tiene el permiso " + scope})
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
-		hasAccess := false
-		for _, role := range allowedRoles {
-			if userRole == role {
-				hasAccess = true
-				break
-			}
+// PasswordChanged bloquea las rutas mientras el usuario tenga pendiente
+// cambiar una contraseña de un solo uso. Debe ir después de AuthMiddleware.
+func PasswordChanged() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool("mustChangePassword") {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Debe cambiar su contraseña antes de continuar",
+				"code":  "password_change_required",
+			})
+			c.Abort()
+			return
 		}
+		c.Next()
+	}
+}
 
-		if !hasAccess {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Permisos insuficientes"})
+// MFAEnrolled bloquea las rutas mientras el rol del usuario exija
+// verificación en dos pasos y aún no la haya activado. Debe ir después de
+// AuthMiddleware.
+func MFAEnrolled() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool("mfaSetupRequired") {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Debe activar la verificación en dos pasos antes de continuar",
+				"code":  "mfa_setup_required",
+			})
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
+// PrincipalResolver carga el usuario, sus permisos y su alquimista
+// vinculado. Devuelve nil sin error si el usuario ya no existe, está
+// deshabilitado o pendiente de aprobación. Lo implementa
+// handlers.RoleHandler.
+type PrincipalResolver interface {
+	ResolvePrincipal(ctx context.Context, userID uint) (*models.Principal, error)
+}
+
+// LoadPrincipal carga una vez por petición quién la hace y lo deja en el
+// contexto como "principal" para RequirePermission y los handlers. Debe ir
+// después de AuthMiddleware.
+func LoadPrincipal(resolver PrincipalResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := resolver.ResolvePrincipal(c.Request.Context(), c.GetUint("userID"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo permisos"})
+			c.Abort()
+			return
+		}
+		if principal == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "La cuenta ya no está activa"})
+			c.Abort()
+			return
+		}
+		principal.APIKeyID = c.GetUint("apiKeyID")
+		c.Set("principal", principal)
+		c.Set("role", principal.Role)
+		c.Next()
+	}
+}
+
+// RequirePermission exige que el rol del usuario tenga el permiso. Debe ir
+// después de LoadPrincipal.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("principal")
+		principal, _ := value.(*models.Principal)
+		if principal == nil || !principal.Can(permission) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":      "Permisos insuficientes",
+				"permission": permission,
+			})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"amestris-backend/models"
+	"amestris-backend/signing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type noDenylist struct{}
+
+func (noDenylist) IsRevoked(ctx context.Context, jti string) (bool, error) { return false, nil }
+
+// fakeAPIKeys acepta solo la clave valid con los scopes dados.
+type fakeAPIKeys struct {
+	valid  string
+	scopes []string
+	calls  int
+}
+
+func (f *fakeAPIKeys) AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	f.calls++
+	if key != f.valid {
+		return nil, nil
+	}
+	return &models.APIKey{ID: 3, UserID: 9, Scopes: f.scopes, User: &models.User{Username: "laboratorio-5", Role: "supervisor"}}, nil
+}
+
+func newAuthRouter(keys *signing.KeySet, apiKeys APIKeyAuthenticator) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/missions", AuthMiddleware(keys, noDenylist{}, apiKeys), RequireScope("missions:read"), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("username"))
+	})
+	return router
+}
+
+func get(router *gin.Engine, header, value string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/missions", nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthMiddlewareAPIKeys(t *testing.T) {
+	keys := signing.NewHMAC([]byte("secreto"))
+	apiKeys := &fakeAPIKeys{valid: "amk_0badcafe_secreto", scopes: []string{"missions:read"}}
+	router := newAuthRouter(keys, apiKeys)
+
+	if w := get(router, "X-API-Key", apiKeys.valid); w.Code != http.StatusOK || w.Body.String() != "laboratorio-5" {
+		t.Errorf("X-API-Key: %d %s", w.Code, w.Body)
+	}
+	if w := get(router, "Authorization", "Bearer "+apiKeys.valid); w.Code != http.StatusOK {
+		t.Errorf("Bearer amk_: %d", w.Code)
+	}
+	if w := get(router, "X-API-Key", "amk_0badcafe_otro"); w.Code != http.StatusUnauthorized {
+		t.Errorf("clave desconocida: %d, esperado 401", w.Code)
+	}
+
+	// Un access token no pasa por el autenticador de claves
+	apiKeys.calls = 0
+	token, _ := keys.Sign(models.Claims{UserID: 1, Username: "roy_mustang", RegisteredClaims: jwt.RegisteredClaims{
+		ID: "jti", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}}, signing.TypeAccess)
+	if w := get(router, "Authorization", "Bearer "+token); w.Code != http.StatusOK || apiKeys.calls != 0 {
+		t.Errorf("access token: %d, %d llamadas al autenticador de claves", w.Code, apiKeys.calls)
+	}
+
+	// Donde no se aceptan claves, amk_ se trata como un JWT inválido
+	if w := get(newAuthRouter(keys, nil), "Authorization", "Bearer "+apiKeys.valid); w.Code != http.StatusUnauthorized {
+		t.Errorf("clave en ruta sin API keys: %d, esperado 401", w.Code)
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	keys := signing.NewHMAC([]byte("secreto"))
+	apiKeys := &fakeAPIKeys{valid: "amk_0badcafe_secreto", scopes: []string{"experiments:read"}}
+
+	if w := get(newAuthRouter(keys, apiKeys), "X-API-Key", apiKeys.valid); w.Code != http.StatusForbidden {
+		t.Errorf("clave sin missions:read: %d, esperado 403", w.Code)
+	}
+}
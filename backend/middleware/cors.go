@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"amestris-backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORS aplica la política configurada. Los orígenes no permitidos no reciben
+// cabeceras CORS y sus preflight se rechazan con 403.
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	allowAll := false
+	var exact []string
+	var wildcards [][2]string // prefijo y sufijo alrededor de "*"
+
+	for _, origin := range cfg.AllowedOrigins {
+		switch {
+		case origin == "*":
+			allowAll = true
+		case strings.Contains(origin, "*"):
+			prefix, suffix, _ := strings.Cut(origin, "*")
+			wildcards = append(wildcards, [2]string{prefix, suffix})
+		default:
+			exact = append(exact, origin)
+		}
+	}
+
+	allowed := func(origin string) bool {
+		if allowAll {
+			return true
+		}
+		for _, o := range exact {
+			if o == origin {
+				return true
+			}
+		}
+		for _, w := range wildcards {
+			if len(origin) > len(w[0])+len(w[1]) && strings.HasPrefix(origin, w[0]) && strings.HasSuffix(origin, w[1]) {
+				// El comodín solo cubre etiquetas de subdominio
+				sub := origin[len(w[0]) : len(origin)-len(w[1])]
+				if !strings.ContainsAny(sub, "/:@") {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Std().Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		if origin != "" {
+			c.Writer.Header().Add("Vary", "Origin")
+
+			if !allowed(origin) {
+				if preflight {
+					c.AbortWithStatus(http.StatusForbidden)
+					return
+				}
+				c.Next()
+				return
+			}
+
+			if allowAll && !cfg.AllowCredentials {
+				c.Header("Access-Control-Allow-Origin", "*")
+			} else {
+				c.Header("Access-Control-Allow-Origin", origin)
+			}
+			if cfg.AllowCredentials {
+				c.Header("Access-Control-Allow-Credentials", "true")
+			}
+			if exposed != "" {
+				c.Header("Access-Control-Expose-Headers", exposed)
+			}
+		}
+
+		if c.Request.Method == http.MethodOptions {
+			if preflight {
+				c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+				c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+				c.Header("Access-Control-Allow-Methods", methods)
+				if headers != "" {
+					c.Header("Access-Control-Allow-Headers", headers)
+				}
+				if cfg.MaxAge > 0 {
+					c.Header("Access-Control-Max-Age", maxAge)
+				}
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"amestris-backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+var testCORS = config.CORSConfig{
+	AllowedOrigins:   []string{"https://amestris.example", "https://*.central.example"},
+	AllowedMethods:   []string{"GET", "POST"},
+	AllowedHeaders:   []string{"Authorization", "Content-Type"},
+	ExposedHeaders:   []string{"X-Request-ID"},
+	AllowCredentials: true,
+	MaxAge:           config.Duration(10 * time.Minute),
+}
+
+func corsRequest(cfg config.CORSConfig, method, origin string, preflight bool) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CORS(cfg))
+	router.GET("/api/missions", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(method, "/api/missions", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if preflight {
+		req.Header.Set("Access-Control-Request-Method", "GET")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORSOrigins(t *testing.T) {
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://amestris.example", true},
+		{"https://east.central.example", true},
+		{"https://a.b.central.example", true},
+		{"http://amestris.example", false},
+		{"https://amestris.example.evil", false},
+		{"https://central.example", false}, // el comodín exige un subdominio
+		{"https://evil.example/.central.example", false},
+		{"https://evil.example:443.central.example", false},
+		{"https://user@evil.central.example", false},
+	}
+	for _, tt := range tests {
+		w := corsRequest(testCORS, http.MethodGet, tt.origin, false)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: %d, la petición simple debe llegar al handler", tt.origin, w.Code)
+		}
+		got := w.Header().Get("Access-Control-Allow-Origin")
+		if tt.allowed && (got != tt.origin || w.Header().Get("Access-Control-Allow-Credentials") != "true") {
+			t.Errorf("%s: Allow-Origin %q, esperado el propio origen con credenciales", tt.origin, got)
+		}
+		if !tt.allowed && got != "" {
+			t.Errorf("%s: Allow-Origin %q para un origen no permitido", tt.origin, got)
+		}
+		if vary := w.Header().Values("Vary"); !slices.Contains(vary, "Origin") {
+			t.Errorf("%s: Vary %v sin Origin", tt.origin, vary)
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	w := corsRequest(testCORS, http.MethodOptions, "https://east.central.example", true)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("preflight permitido: %d, esperado 204", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST" {
+		t.Errorf("Allow-Methods %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got != "Authorization, Content-Type" {
+		t.Errorf("Allow-Headers %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Max-Age %q, esperado 600", got)
+	}
+	want := []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}
+	if vary := w.Header().Values("Vary"); !slices.Equal(vary, want) {
+		t.Errorf("Vary %v, esperado %v", vary, want)
+	}
+
+	w = corsRequest(testCORS, http.MethodOptions, "https://evil.example", true)
+	if w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("preflight de origen no permitido: %d %v, esperado 403 sin cabeceras", w.Code, w.Header())
+	}
+}
+
+func TestCORSAllowAllWithoutCredentials(t *testing.T) {
+	cfg := testCORS
+	cfg.AllowedOrigins = []string{"*"}
+	cfg.AllowCredentials = false
+
+	w := corsRequest(cfg, http.MethodGet, "https://cualquiera.example", false)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Allow-Origin %q, esperado *", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Allow-Credentials %q sin credenciales configuradas", got)
+	}
+}
+
+func TestCORSWithoutOrigin(t *testing.T) {
+	w := corsRequest(testCORS, http.MethodGet, "", false)
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "" || len(w.Header().Values("Vary")) != 0 {
+		t.Errorf("petición sin Origin: %d %v", w.Code, w.Header())
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"amestris-backend/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics registra latencia y código de estado de cada petición. Se usa la
+// plantilla de la ruta (/api/missions/:id/status) para acotar la
+// cardinalidad; las rutas inexistentes se agrupan como "unmatched".
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		metrics.HTTPDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
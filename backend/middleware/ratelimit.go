@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"amestris-backend/metrics"
+	"amestris-backend/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Auditor registra eventos de seguridad en el log de auditoría. Lo
+// implementa handlers.AuditHandler.
+type Auditor interface {
+	Log(ctx context.Context, userID uint, action, resource, details string)
+}
+
+// RateLimitGroup es la política aplicada a un grupo de rutas.
+type RateLimitGroup struct {
+	Name string
+	Rule ratelimit.Rule
+	// Action es la acción de auditoría registrada al superar el límite
+	// (UNAUTHORIZED_ACCESS, RESOURCE_MISUSE...).
+	Action string
+	// Subject, si se indica, devuelve una clave adicional para rutas sin
+	// autenticar, como el usuario que intenta iniciar sesión. Vacía si la
+	// petición no la incluye.
+	Subject func(c *gin.Context) string
+}
+
+// maxSubjectBody es lo máximo que se lee del cuerpo para extraer el sujeto.
+const maxSubjectBody = 64 << 10
+
+// LoginSubject identifica el objetivo de un intento de login: el usuario
+// enviado o, en /login/mfa, el desafío. Así un ataque repartido entre
+// muchas IP contra la misma cuenta también se limita. El cuerpo se
+// restaura para el handler.
+func LoginSubject(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	head, err := io.ReadAll(io.LimitReader(c.Request.Body, maxSubjectBody))
+	c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(head), c.Request.Body), c.Request.Body}
+	if err != nil {
+		return ""
+	}
+
+	var body struct {
+		Username string `json:"username"`
+		MFAToken string `json:"mfa_token"`
+	}
+	if json.Unmarshal(head, &body) != nil {
+		return ""
+	}
+	if body.Username != "" {
+		return "username:" + strings.ToLower(body.Username)
+	}
+	if body.MFAToken != "" {
+		// El desafío es un JWT; basta un resumen para identificarlo.
+		sum := sha256.Sum256([]byte(body.MFAToken))
+		return "mfa:" + hex.EncodeToString(sum[:8])
+	}
+	return ""
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// RateLimit limita las peticiones del grupo por IP, por usuario si la
+// petición está autenticada y por el sujeto del grupo si lo hay. Al superar
+// el límite responde 429 con Retry-After y deja constancia en auditoría una
+// vez por periodo y clave.
+func RateLimit(limiter ratelimit.Limiter, group RateLimitGroup, auditor Auditor) gin.HandlerFunc {
+	if group.Rule.Requests <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	var (
+		audited sync.Map // clave -> time.Time del último registro de auditoría
+		calls   atomic.Int64
+	)
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		// Cada cierto número de peticiones se olvidan los registros de
+		// auditoría de más de un periodo, que ya no silencian nada.
+		if calls.Add(1)%1000 == 0 {
+			now := time.Now()
+			audited.Range(func(key, last any) bool {
+				if now.Sub(last.(time.Time)) > group.Rule.Period {
+					audited.Delete(key)
+				}
+				return true
+			})
+		}
+
+		keys := []string{group.Name + ":ip:" + c.ClientIP()}
+		if userID, ok := c.Get("userID"); ok {
+			keys = append(keys, fmt.Sprintf("%s:user:%d", group.Name, userID))
+		}
+		if group.Subject != nil {
+			if subject := group.Subject(c); subject != "" {
+				keys = append(keys, group.Name+":"+subject)
+			}
+		}
+
+		for _, key := range keys {
+			result, err := limiter.Allow(ctx, key, group.Rule)
+			if err != nil {
+				// Ante un fallo del almacenamiento se prefiere no bloquear.
+				slog.ErrorContext(ctx, "error consultando límite de peticiones", "group", group.Name, "error", err)
+				continue
+			}
+			if result.Allowed {
+				continue
+			}
+
+			metrics.RateLimited.WithLabelValues(group.Name).Inc()
+
+			now := time.Now()
+			if last, ok := audited.Load(key); !ok || now.Sub(last.(time.Time)) > group.Rule.Period {
+				audited.Store(key, now)
+				var userID uint
+				if id, ok := c.Get("userID"); ok {
+					userID = id.(uint)
+				}
+				auditor.Log(ctx, userID, group.Action, group.Name,
+					fmt.Sprintf("Límite de peticiones superado en %s %s (%s)", c.Request.Method, c.FullPath(), key))
+			}
+
+			retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Demasiadas solicitudes, intente más tarde",
+				"retry_after": retryAfter,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"amestris-backend/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+type recordingAuditor struct{ actions []string }
+
+func (a *recordingAuditor) Log(ctx context.Context, userID uint, action, resource, details string) {
+	a.actions = append(a.actions, action)
+}
+
+func newLoginRouter(auditor Auditor, bodies *[]string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	limit := RateLimit(ratelimit.NewMemoryLimiter(), RateLimitGroup{
+		Name:    "login",
+		Rule:    ratelimit.Rule{Requests: 2, Period: time.Minute},
+		Action:  "UNAUTHORIZED_ACCESS",
+		Subject: LoginSubject,
+	}, auditor)
+	router.POST("/login", limit, func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		*bodies = append(*bodies, string(body))
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func postLogin(router *gin.Engine, ip, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":1234"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitKeysOnLoginUsername(t *testing.T) {
+	auditor := &recordingAuditor{}
+	var bodies []string
+	router := newLoginRouter(auditor, &bodies)
+
+	body := `{"username":"Edward_Elric","password":"x"}`
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		if w := postLogin(router, ip, body); w.Code != http.StatusOK {
+			t.Fatalf("desde %s: %d, esperado 200", ip, w.Code)
+		}
+	}
+
+	// Una IP nueva contra el mismo usuario, aunque cambie mayúsculas
+	w := postLogin(router, "10.0.0.3", `{"username":"edward_elric","password":"y"}`)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("tercer intento contra el mismo usuario: %d, esperado 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("falta Retry-After")
+	}
+	if len(auditor.actions) != 1 || auditor.actions[0] != "UNAUTHORIZED_ACCESS" {
+		t.Errorf("auditoría %v, esperado un UNAUTHORIZED_ACCESS", auditor.actions)
+	}
+
+	// Otro usuario desde una IP nueva no está afectado
+	if w := postLogin(router, "10.0.0.4", `{"username":"alphonse_elric","password":"x"}`); w.Code != http.StatusOK {
+		t.Fatalf("otro usuario: %d, esperado 200", w.Code)
+	}
+
+	// El handler recibe el cuerpo intacto
+	if bodies[0] != body {
+		t.Errorf("cuerpo recibido %q, esperado %q", bodies[0], body)
+	}
+}
+
+func TestLoginSubject(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"username":"Roy_Mustang"}`, "username:roy_mustang"},
+		{`{"mfa_token":"abc","code":"123456"}`, "mfa:"},
+		{`{}`, ""},
+		{`no es json`, ""},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+
+		got := LoginSubject(c)
+		if !strings.HasPrefix(got, tt.want) || (tt.want == "" && got != "") {
+			t.Errorf("LoginSubject(%s) = %q, esperado %q", tt.body, got, tt.want)
+		}
+		if rest, _ := io.ReadAll(c.Request.Body); string(rest) != tt.body {
+			t.Errorf("cuerpo restaurado %q, esperado %q", rest, tt.body)
+		}
+	}
+}
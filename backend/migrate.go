@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"amestris-backend/config"
+	"amestris-backend/migrations"
+	"amestris-backend/models"
+)
+
+const migrateUsage = `Uso: main migrate <up|down|status> [opciones]
+
+  up      aplica las migraciones pendientes
+  down    revierte la última migración aplicada
+  status  muestra el estado de cada migración
+
+Opciones:
+  --steps N   número de migraciones a aplicar/revertir
+  --dry-run   muestra el SQL sin ejecutarlo`
+
+// newMigrator construye el migrador sobre la conexión global ya abierta.
+func newMigrator() (*migrations.Migrator, error) {
+	sqlDB, err := models.DB.DB()
+	if err != nil {
+		return nil, err
+	}
+	return migrations.New(sqlDB, os.Stdout)
+}
+
+func runMigrate(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", migrateUsage)
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	steps := flags.Int("steps", 0, "número de migraciones a aplicar/revertir")
+	dryRun := flags.Bool("dry-run", false, "mostrar el SQL sin ejecutarlo")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	migrator, err := newMigrator()
+	if err != nil {
+		return err
+	}
+	migrator.DryRun = *dryRun
+
+	switch args[0] {
+	case "up":
+		return migrator.Up(ctx, *steps)
+	case "down":
+		return migrator.Down(ctx, *steps)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSIÓN\tNOMBRE\tESTADO\tAPLICADA")
+		for _, s := range statuses {
+			state, appliedAt := "pendiente", "-"
+			if s.Applied {
+				state, appliedAt = "aplicada", s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("subcomando desconocido %q\n%s", args[0], migrateUsage)
+	}
+}
@@ -0,0 +1,392 @@
+// Package migrations aplica las migraciones SQL versionadas embebidas en el
+// binario y lleva el registro en la tabla schema_migrations.
+//
+// Cada migración se compone de dos archivos en sql/:
+//
+//	NNNN_descripcion.up.sql
+//	NNNN_descripcion.down.sql
+//
+// Las migraciones aplicadas guardan el checksum SHA-256 de su archivo up;
+// si un archivo ya aplicado se modifica, el migrador se niega a continuar.
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// AppliedMigration es una fila de schema_migrations.
+type AppliedMigration struct {
+	Version   int
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Status describe el estado de una migración conocida por el binario.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Load lee y valida las migraciones embebidas, ordenadas por versión.
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("nombre de migración inválido: %s", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(files, path.Join("sql", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("la versión %d tiene nombres distintos: %s y %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("la migración %04d_%s debe tener archivos up y down", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// lockKey identifica el advisory lock de Postgres que serializa Up y Down
+// entre réplicas que arrancan a la vez.
+const lockKey int64 = 0x616d657374726973 // "amestris"
+
+// querier es lo que el migrador usa de *sql.DB o de una conexión concreta.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// Migrator ejecuta las migraciones contra una base de datos Postgres.
+type Migrator struct {
+	pool *sql.DB
+	// db es el pool, o la conexión que tiene el lock durante Up y Down.
+	db         querier
+	migrations []Migration
+	out        io.Writer
+
+	// DryRun muestra el SQL que se ejecutaría sin aplicarlo.
+	DryRun bool
+}
+
+func New(db *sql.DB, out io.Writer) (*Migrator, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{pool: db, db: db, migrations: migrations, out: out}, nil
+}
+
+// Latest devuelve la versión más alta embebida en el binario.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    integer PRIMARY KEY,
+		name       text NOT NULL,
+		checksum   text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+	return err
+}
+
+func (m *Migrator) tableExists(ctx context.Context) (bool, error) {
+	var exists bool
+	err := m.db.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists)
+	return exists, err
+}
+
+// locked ejecuta fn con el advisory lock del migrador tomado en una
+// conexión propia, por la que van también las migraciones. Otra réplica que
+// llegue a la vez espera y después no encuentra nada pendiente. En dry-run no
+// se toma el lock ni se crea schema_migrations.
+func (m *Migrator) locked(ctx context.Context, fn func(m *Migrator) error) error {
+	if m.DryRun {
+		return fn(m)
+	}
+
+	conn, err := m.pool.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("esperando el lock de migraciones: %w", err)
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	locked := *m
+	locked.db = conn
+	if err := locked.ensureTable(ctx); err != nil {
+		return err
+	}
+	return fn(&locked)
+}
+
+// applied lee schema_migrations sin crearla: si no existe no hay nada
+// aplicado.
+func (m *Migrator) applied(ctx context.Context) (map[int]AppliedMigration, error) {
+	applied := map[int]AppliedMigration{}
+
+	exists, err := m.tableExists(ctx)
+	if err != nil || !exists {
+		return applied, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a AppliedMigration
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[a.Version] = a
+	}
+	return applied, rows.Err()
+}
+
+// verify comprueba que todas las migraciones aplicadas existan en el binario
+// con el mismo checksum.
+func (m *Migrator) verify(applied map[int]AppliedMigration) error {
+	known := map[int]Migration{}
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	var problems []string
+	for version, a := range applied {
+		migration, ok := known[version]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%04d_%s está aplicada pero no existe en este binario", version, a.Name))
+			continue
+		}
+		if migration.Checksum != a.Checksum {
+			problems = append(problems, fmt.Sprintf("%04d_%s fue modificada después de aplicarse (checksum %s, esperado %s)",
+				version, a.Name, migration.Checksum[:12], a.Checksum[:12]))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("verificación de migraciones fallida:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// Status devuelve el estado de todas las migraciones conocidas.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		a, ok := applied[migration.Version]
+		statuses = append(statuses, Status{Migration: migration, Applied: ok, AppliedAt: a.AppliedAt})
+	}
+	return statuses, nil
+}
+
+// Current devuelve la versión más alta aplicada, o 0 si no hay ninguna. Solo
+// lee: no crea schema_migrations ni verifica checksums, así que se puede
+// usar desde las comprobaciones de salud.
+func (m *Migrator) Current(ctx context.Context) (int, error) {
+	exists, err := m.tableExists(ctx)
+	if err != nil || !exists {
+		return 0, err
+	}
+
+	var current int
+	err = m.db.QueryRowContext(ctx, `SELECT coalesce(max(version), 0) FROM schema_migrations`).Scan(&current)
+	return current, err
+}
+
+// Pending devuelve las migraciones aún no aplicadas, en orden.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, s := range statuses {
+		if !s.Applied {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up aplica hasta steps migraciones pendientes (todas si steps <= 0).
+func (m *Migrator) Up(ctx context.Context, steps int) error {
+	return m.locked(ctx, func(m *Migrator) error {
+		return m.up(ctx, steps)
+	})
+}
+
+func (m *Migrator) up(ctx context.Context, steps int) error {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if steps > 0 && steps < len(pending) {
+		pending = pending[:steps]
+	}
+	if len(pending) == 0 {
+		fmt.Fprintln(m.out, "No hay migraciones pendientes")
+		return nil
+	}
+
+	for _, migration := range pending {
+		err := m.run(ctx, migration, "up", migration.Up, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+				migration.Version, migration.Name, migration.Checksum)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Down revierte las últimas steps migraciones aplicadas (al menos una).
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.locked(ctx, func(m *Migrator) error {
+		return m.down(ctx, steps)
+	})
+}
+
+func (m *Migrator) down(ctx context.Context, steps int) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if steps <= 0 {
+		steps = 1
+	}
+
+	var toRevert []Migration
+	for i := len(statuses) - 1; i >= 0 && len(toRevert) < steps; i-- {
+		if statuses[i].Applied {
+			toRevert = append(toRevert, statuses[i].Migration)
+		}
+	}
+	if len(toRevert) == 0 {
+		fmt.Fprintln(m.out, "No hay migraciones aplicadas")
+		return nil
+	}
+
+	for _, migration := range toRevert {
+		err := m.run(ctx, migration, "down", migration.Down, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) run(ctx context.Context, migration Migration, direction, script string, record func(*sql.Tx) error) error {
+	label := fmt.Sprintf("%04d_%s (%s)", migration.Version, migration.Name, direction)
+
+	if m.DryRun {
+		fmt.Fprintf(m.out, "-- [dry-run] %s\n%s\n", label, strings.TrimSpace(script))
+		return nil
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("%s: %w", label, err)
+	}
+	if err := record(tx); err != nil {
+		return fmt.Errorf("%s: %w", label, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", label, err)
+	}
+
+	fmt.Fprintf(m.out, "Aplicada %s\n", label)
+	return nil
+}
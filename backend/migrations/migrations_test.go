@@ -0,0 +1,333 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDB simula lo que el migrador necesita de Postgres: la tabla
+// schema_migrations, el advisory lock y el registro de los scripts
+// ejecutados.
+type fakeDB struct {
+	advisory sync.Mutex
+
+	mu      sync.Mutex
+	exists  bool
+	applied map[int]AppliedMigration
+	scripts []string
+}
+
+var fakeDBs sync.Map // nombre de la conexión -> *fakeDB
+
+func init() {
+	sql.Register("fakepg", fakeDriver{})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	db, ok := fakeDBs.Load(name)
+	if !ok {
+		return nil, errors.New("base de datos desconocida")
+	}
+	return &fakeConn{db: db.(*fakeDB)}, nil
+}
+
+type fakeConn struct {
+	db       *fakeDB
+	snapshot *fakeDB
+	locked   bool
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+// Close termina la sesión, que suelta el advisory lock como en Postgres.
+func (c *fakeConn) Close() error {
+	if c.locked {
+		c.locked = false
+		c.db.advisory.Unlock()
+	}
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.snapshot = &fakeDB{exists: c.db.exists, applied: maps.Clone(c.db.applied), scripts: c.db.scripts}
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.snapshot = nil
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	if c.snapshot != nil {
+		c.db.mu.Lock()
+		c.db.exists, c.db.applied, c.db.scripts = c.snapshot.exists, c.snapshot.applied, c.snapshot.scripts
+		c.db.mu.Unlock()
+		c.snapshot = nil
+	}
+	return nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	db := s.conn.db
+	switch {
+	case strings.Contains(s.query, "pg_advisory_lock"):
+		db.advisory.Lock()
+		s.conn.locked = true
+		return driver.RowsAffected(1), nil
+	case strings.Contains(s.query, "pg_advisory_unlock"):
+		s.conn.Close()
+		return driver.RowsAffected(1), nil
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE IF NOT EXISTS schema_migrations"):
+		db.exists = true
+	case strings.HasPrefix(s.query, "INSERT INTO schema_migrations"):
+		version := int(args[0].(int64))
+		if _, ok := db.applied[version]; ok {
+			return nil, errors.New(`duplicate key value violates unique constraint "schema_migrations_pkey"`)
+		}
+		db.applied[version] = AppliedMigration{Version: version, Name: args[1].(string), Checksum: args[2].(string), AppliedAt: time.Now()}
+	case strings.HasPrefix(s.query, "DELETE FROM schema_migrations"):
+		delete(db.applied, int(args[0].(int64)))
+	case strings.Contains(s.query, "FAIL"):
+		return nil, errors.New("error de sintaxis")
+	default:
+		db.scripts = append(db.scripts, s.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	db := s.conn.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	switch {
+	case strings.Contains(s.query, "to_regclass"):
+		return &fakeRows{columns: []string{"exists"}, values: [][]driver.Value{{db.exists}}}, nil
+	case !db.exists:
+		return nil, errors.New(`relation "schema_migrations" does not exist`)
+	case strings.Contains(s.query, "max(version)"):
+		current := int64(0)
+		for version := range db.applied {
+			current = max(current, int64(version))
+		}
+		return &fakeRows{columns: []string{"max"}, values: [][]driver.Value{{current}}}, nil
+	case strings.HasPrefix(s.query, "SELECT version, name, checksum, applied_at"):
+		rows := &fakeRows{columns: []string{"version", "name", "checksum", "applied_at"}}
+		for _, a := range db.applied {
+			rows.values = append(rows.values, []driver.Value{int64(a.Version), a.Name, a.Checksum, a.AppliedAt})
+		}
+		return rows, nil
+	}
+	return nil, errors.New("consulta no soportada: " + s.query)
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "one", Up: "CREATE one", Down: "DROP one", Checksum: "1111111111111111"},
+	{Version: 2, Name: "two", Up: "CREATE two", Down: "DROP two", Checksum: "2222222222222222"},
+	{Version: 3, Name: "three", Up: "CREATE three", Down: "DROP three", Checksum: "3333333333333333"},
+}
+
+func newTestMigrator(t *testing.T, migrations []Migration) (*Migrator, *fakeDB) {
+	t.Helper()
+	fake := &fakeDB{applied: map[int]AppliedMigration{}}
+	fakeDBs.Store(t.Name(), fake)
+	t.Cleanup(func() { fakeDBs.Delete(t.Name()) })
+	return openTestMigrator(t, migrations), fake
+}
+
+// openTestMigrator abre otro migrador sobre la base de datos del test, como
+// otra réplica.
+func openTestMigrator(t *testing.T, migrations []Migration) *Migrator {
+	t.Helper()
+	db, err := sql.Open("fakepg", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return &Migrator{pool: db, db: db, migrations: slices.Clone(migrations), out: io.Discard}
+}
+
+func appliedVersions(fake *fakeDB) []int {
+	var versions []int
+	for version := range fake.applied {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+func TestLoadEmbedded(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no hay migraciones embebidas")
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("versión %d en la posición %d: las versiones deben ser consecutivas", m.Version, i)
+		}
+		if m.Up == "" || m.Down == "" || len(m.Checksum) != 64 {
+			t.Errorf("%04d_%s incompleta", m.Version, m.Name)
+		}
+	}
+}
+
+func TestUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	m, fake := newTestMigrator(t, testMigrations)
+
+	if err := m.Up(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := appliedVersions(fake); len(got) != 2 || got[1] != 2 {
+		t.Fatalf("tras up 2 aplicadas %v, esperadas [1 2]", got)
+	}
+
+	if err := m.Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := appliedVersions(fake); len(got) != 3 {
+		t.Fatalf("tras up aplicadas %v, esperadas [1 2 3]", got)
+	}
+	if current, err := m.Current(ctx); err != nil || current != 3 {
+		t.Fatalf("Current = %d, %v; esperado 3", current, err)
+	}
+
+	if err := m.Down(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := appliedVersions(fake); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("tras down 2 aplicadas %v, esperadas [1]", got)
+	}
+
+	want := []string{"CREATE one", "CREATE two", "CREATE three", "DROP three", "DROP two"}
+	if strings.Join(fake.scripts, ";") != strings.Join(want, ";") {
+		t.Errorf("scripts ejecutados %v, esperados %v", fake.scripts, want)
+	}
+}
+
+func TestUpStopsAtFailedMigration(t *testing.T) {
+	ctx := context.Background()
+	broken := append([]Migration{}, testMigrations...)
+	broken[1].Up = "FAIL"
+	m, fake := newTestMigrator(t, broken)
+
+	if err := m.Up(ctx, 0); err == nil {
+		t.Fatal("se esperaba error")
+	}
+	if got := appliedVersions(fake); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("aplicadas %v, esperada solo [1]", got)
+	}
+}
+
+func TestCurrentDoesNotCreateTable(t *testing.T) {
+	m, fake := newTestMigrator(t, testMigrations)
+
+	current, err := m.Current(context.Background())
+	if err != nil || current != 0 {
+		t.Fatalf("Current = %d, %v; esperado 0 sin schema_migrations", current, err)
+	}
+	if fake.exists {
+		t.Error("Current creó schema_migrations")
+	}
+}
+
+func TestStatusDoesNotCreateTable(t *testing.T) {
+	m, fake := newTestMigrator(t, testMigrations)
+
+	pending, err := m.Pending(context.Background())
+	if err != nil || len(pending) != len(testMigrations) {
+		t.Fatalf("Pending = %d, %v; esperadas %d", len(pending), err, len(testMigrations))
+	}
+	if fake.exists {
+		t.Error("Pending creó schema_migrations")
+	}
+}
+
+func TestConcurrentUpAppliesOnce(t *testing.T) {
+	ctx := context.Background()
+	_, fake := newTestMigrator(t, testMigrations)
+
+	// Varias réplicas arrancan a la vez y ejecutan migrate up
+	errs := make(chan error, 3)
+	for range cap(errs) {
+		replica := openTestMigrator(t, testMigrations)
+		go func() { errs <- replica.Up(ctx, 0) }()
+	}
+	for range cap(errs) {
+		if err := <-errs; err != nil {
+			t.Errorf("una réplica falló: %v", err)
+		}
+	}
+
+	if got := appliedVersions(fake); len(got) != 3 {
+		t.Errorf("aplicadas %v, esperadas [1 2 3]", got)
+	}
+	if len(fake.scripts) != 3 {
+		t.Errorf("scripts ejecutados %v, cada migración debía ejecutarse una vez", fake.scripts)
+	}
+}
+
+func TestStatusRejectsModifiedMigration(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newTestMigrator(t, testMigrations)
+	if err := m.Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	m.migrations[1].Checksum = "9999999999999999"
+	if _, err := m.Status(ctx); err == nil || !strings.Contains(err.Error(), "0002_two") {
+		t.Fatalf("Status = %v; se esperaba error por 0002_two modificada", err)
+	}
+}
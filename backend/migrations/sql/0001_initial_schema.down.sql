@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS materials;
+DROP TABLE IF EXISTS audit_logs;
+DROP TABLE IF EXISTS transmutation_logs;
+DROP TABLE IF EXISTS experiment_requests;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS missions;
+DROP TABLE IF EXISTS alchemists;
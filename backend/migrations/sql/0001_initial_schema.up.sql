@@ -0,0 +1,87 @@
+-- Esquema inicial, equivalente al que generaba models.AutoMigrate.
+-- Se usa IF NOT EXISTS para poder adoptar bases de datos ya creadas.
+
+CREATE TABLE IF NOT EXISTS alchemists (
+    id         bigserial PRIMARY KEY,
+    name       text,
+    title      text,
+    specialty  text,
+    rank       text,
+    status     text,
+    automail   boolean,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS missions (
+    id           bigserial PRIMARY KEY,
+    title        text,
+    description  text,
+    alchemist_id bigint,
+    status       text,
+    priority     text,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    CONSTRAINT fk_missions_alchemist FOREIGN KEY (alchemist_id) REFERENCES alchemists (id)
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    id           bigserial PRIMARY KEY,
+    username     text,
+    password     text,
+    role         text,
+    alchemist_id bigint,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    CONSTRAINT fk_alchemists_user FOREIGN KEY (alchemist_id) REFERENCES alchemists (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+
+CREATE TABLE IF NOT EXISTS experiment_requests (
+    id           bigserial PRIMARY KEY,
+    title        text,
+    description  text,
+    alchemist_id bigint,
+    materials    text,
+    objective    text,
+    status       text,
+    risk_level   text,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    CONSTRAINT fk_experiment_requests_alchemist FOREIGN KEY (alchemist_id) REFERENCES alchemists (id)
+);
+
+CREATE TABLE IF NOT EXISTS transmutation_logs (
+    id            bigserial PRIMARY KEY,
+    alchemist_id  bigint,
+    input         text,
+    output        text,
+    success       boolean,
+    cost          decimal,
+    energy_used   decimal,
+    law_respected boolean,
+    created_at    timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id           bigserial PRIMARY KEY,
+    alchemist_id bigint,
+    action       text,
+    resource     text,
+    details      text,
+    severity     text,
+    checked      boolean DEFAULT false,
+    created_at   timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS materials (
+    id           bigserial PRIMARY KEY,
+    name         text,
+    type         text,
+    rarity       text,
+    base_value   decimal,
+    danger_level text,
+    created_at   timestamptz,
+    updated_at   timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_materials_name ON materials (name);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_transmutation_logs_request_id;
+DROP INDEX IF EXISTS idx_audit_logs_request_id;
+
+ALTER TABLE transmutation_logs DROP COLUMN IF EXISTS request_id;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS request_id;
@@ -0,0 +1,6 @@
+-- Correlación de auditoría y transmutaciones con la petición HTTP que las originó.
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS request_id text;
+ALTER TABLE transmutation_logs ADD COLUMN IF NOT EXISTS request_id text;
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_request_id ON audit_logs (request_id);
+CREATE INDEX IF NOT EXISTS idx_transmutation_logs_request_id ON transmutation_logs (request_id);
@@ -0,0 +1,11 @@
+-- Cubos de token bucket compartidos entre réplicas (RATE_LIMIT_BACKEND=postgres).
+-- Cada cubo guarda el periodo de su regla para purgarlo cuando ya estaría
+-- lleno de nuevo, sea cual sea el grupo que dispara la purga.
+CREATE TABLE rate_limit_buckets (
+    key            text PRIMARY KEY,
+    tokens         double precision NOT NULL,
+    period_seconds double precision NOT NULL DEFAULT 0,
+    updated_at     timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_rate_limit_buckets_updated_at ON rate_limit_buckets (updated_at);
@@ -0,0 +1,23 @@
+-- Tokens de renovación rotativos y lista de access tokens revocados.
+CREATE TABLE refresh_tokens (
+    id                bigserial PRIMARY KEY,
+    user_id           bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    session_id        text NOT NULL,
+    token_hash        text NOT NULL,
+    access_jti        text NOT NULL,
+    access_expires_at timestamptz NOT NULL,
+    expires_at        timestamptz NOT NULL,
+    revoked_at        timestamptz,
+    created_at        timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX idx_refresh_tokens_user_session ON refresh_tokens (user_id, session_id);
+CREATE INDEX idx_refresh_tokens_expires_at ON refresh_tokens (expires_at);
+
+CREATE TABLE revoked_tokens (
+    jti        text PRIMARY KEY,
+    expires_at timestamptz NOT NULL
+);
+
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
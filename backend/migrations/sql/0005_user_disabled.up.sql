@@ -0,0 +1,2 @@
+-- Cuentas deshabilitadas por un administrador.
+ALTER TABLE users ADD COLUMN disabled boolean NOT NULL DEFAULT false;
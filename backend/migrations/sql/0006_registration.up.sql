@@ -0,0 +1,16 @@
+-- Registro con aprobación e invitaciones de un solo uso.
+ALTER TABLE users ADD COLUMN pending boolean NOT NULL DEFAULT false;
+
+CREATE TABLE invitations (
+    id            bigserial PRIMARY KEY,
+    token_hash    text NOT NULL,
+    role          text NOT NULL,
+    alchemist_id  bigint REFERENCES alchemists (id) ON DELETE SET NULL,
+    created_by_id bigint NOT NULL,
+    expires_at    timestamptz NOT NULL,
+    used_at       timestamptz,
+    used_by_id    bigint REFERENCES users (id) ON DELETE SET NULL,
+    created_at    timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX idx_invitations_token_hash ON invitations (token_hash);
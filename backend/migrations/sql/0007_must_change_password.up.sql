@@ -0,0 +1,2 @@
+-- Contraseñas de un solo uso que deben cambiarse en el primer inicio de sesión.
+ALTER TABLE users ADD COLUMN must_change_password boolean NOT NULL DEFAULT false;
@@ -0,0 +1,6 @@
+-- Seguimiento de inicios de sesión y bloqueo progresivo de cuentas.
+ALTER TABLE users
+    ADD COLUMN failed_logins        integer NOT NULL DEFAULT 0,
+    ADD COLUMN locked_until         timestamptz,
+    ADD COLUMN last_login_at        timestamptz,
+    ADD COLUMN last_failed_login_at timestamptz;
@@ -0,0 +1,15 @@
+-- Verificación en dos pasos (TOTP) y códigos de recuperación.
+ALTER TABLE users
+    ADD COLUMN totp_secret    text NOT NULL DEFAULT '',
+    ADD COLUMN totp_enabled   boolean NOT NULL DEFAULT false,
+    ADD COLUMN totp_last_step bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE recovery_codes (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash  text NOT NULL,
+    used_at    timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_recovery_codes_user_id ON recovery_codes (user_id);
@@ -0,0 +1,19 @@
+-- Cuentas de servicio y sus API keys.
+ALTER TABLE users ADD COLUMN service_account boolean NOT NULL DEFAULT false;
+
+CREATE TABLE api_keys (
+    id            bigserial PRIMARY KEY,
+    user_id       bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name          text NOT NULL,
+    prefix        text NOT NULL,
+    key_hash      text NOT NULL,
+    scopes        text NOT NULL DEFAULT '',
+    created_by_id bigint NOT NULL,
+    expires_at    timestamptz,
+    last_used_at  timestamptz,
+    revoked_at    timestamptz,
+    created_at    timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX idx_api_keys_prefix ON api_keys (prefix);
+CREATE INDEX idx_api_keys_user_id ON api_keys (user_id);
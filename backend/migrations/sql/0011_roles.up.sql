@@ -0,0 +1,24 @@
+-- Roles definidos en base de datos como conjuntos de permisos. Los permisos
+-- se guardan separados por espacios; los valores iniciales coinciden con
+-- models.DefaultRoles.
+CREATE TABLE roles (
+    name        text PRIMARY KEY,
+    description text NOT NULL DEFAULT '',
+    permissions text NOT NULL DEFAULT '',
+    built_in    boolean NOT NULL DEFAULT false,
+    created_at  timestamptz NOT NULL DEFAULT now(),
+    updated_at  timestamptz NOT NULL DEFAULT now()
+);
+
+INSERT INTO roles (name, description, permissions, built_in) VALUES
+    ('alchemist', 'Alquimista estatal', '', true),
+    ('supervisor', 'Supervisa misiones y experimentos',
+     'alchemists:write alchemists:register missions:write experiments:read:all experiments:approve materials:write audit:read:alerts registrations:approve',
+     true),
+    ('admin', 'Administrador del sistema',
+     'alchemists:write alchemists:register missions:write experiments:read:all experiments:approve materials:write audit:read:alerts audit:read:all registrations:approve users:manage invitations:manage service-accounts:manage roles:manage',
+     true);
+
+-- Un usuario no puede quedarse con un rol que no existe
+ALTER TABLE users
+    ADD CONSTRAINT fk_users_role FOREIGN KEY (role) REFERENCES roles (name) ON UPDATE CASCADE;
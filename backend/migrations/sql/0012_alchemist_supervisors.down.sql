@@ -0,0 +1,3 @@
+UPDATE roles SET permissions = trim(replace(' ' || permissions || ' ', ' missions:write:all ', ' '));
+
+ALTER TABLE alchemists DROP COLUMN supervisor_id;
@@ -0,0 +1,7 @@
+-- Supervisor responsable de cada alquimista. Un supervisor solo puede
+-- actualizar las misiones de los alquimistas que supervisa; missions:write:all
+-- permite actualizar cualquiera.
+ALTER TABLE alchemists
+    ADD COLUMN supervisor_id bigint REFERENCES users (id) ON DELETE SET NULL;
+
+UPDATE roles SET permissions = trim(permissions || ' missions:write:all') WHERE name = 'admin';
@@ -0,0 +1,6 @@
+-- Las solicitudes de experimento no se revierten: no se puede saber qué
+-- usuario creó cada una.
+UPDATE audit_logs SET alchemist_id = user_id WHERE user_id IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_audit_logs_user_id;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,21 @@
+-- Separa en los registros de auditoría el usuario que hace la acción de su
+-- alquimista vinculado. Hasta ahora alchemist_id guardaba el ID del usuario,
+-- salvo en MISSION_UPDATE y FREQUENT_ACTIVITY.
+ALTER TABLE audit_logs ADD COLUMN user_id bigint;
+CREATE INDEX idx_audit_logs_user_id ON audit_logs (user_id);
+
+UPDATE audit_logs SET user_id = NULLIF(alchemist_id, 0)
+WHERE action NOT IN ('MISSION_UPDATE', 'RISK_MONITOR', 'FREQUENT_ACTIVITY');
+
+UPDATE audit_logs SET alchemist_id = (SELECT u.alchemist_id FROM users u WHERE u.id = audit_logs.user_id)
+WHERE action NOT IN ('MISSION_UPDATE', 'RISK_MONITOR', 'FREQUENT_ACTIVITY');
+
+-- RISK_MONITOR es un evento del sistema, pero copiaba el alchemist_id de
+-- experiment_requests, que también era un ID de usuario
+UPDATE audit_logs SET alchemist_id = (SELECT u.alchemist_id FROM users u WHERE u.id = audit_logs.alchemist_id)
+WHERE action = 'RISK_MONITOR';
+
+-- Las solicitudes de experimento se guardaban con el ID del usuario que las
+-- creó. Las de usuarios sin alquimista vinculado quedan sin autor.
+UPDATE experiment_requests e SET alchemist_id = u.alchemist_id
+FROM users u WHERE u.id = e.alchemist_id;
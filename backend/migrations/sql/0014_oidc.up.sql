@@ -0,0 +1,7 @@
+-- Usuarios creados al iniciar sesión con el proveedor OIDC.
+ALTER TABLE users
+    ADD COLUMN oidc_issuer  text NOT NULL DEFAULT '',
+    ADD COLUMN oidc_subject text NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX idx_users_oidc_subject ON users (oidc_issuer, oidc_subject)
+    WHERE oidc_subject <> '';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_audit_logs_created_at;
+DROP INDEX IF EXISTS idx_experiment_requests_alchemist_id;
+DROP INDEX IF EXISTS idx_missions_alchemist_id;
@@ -0,0 +1,4 @@
+-- Índices para los filtros y el orden por defecto de los listados paginados.
+CREATE INDEX idx_missions_alchemist_id ON missions (alchemist_id);
+CREATE INDEX idx_experiment_requests_alchemist_id ON experiment_requests (alchemist_id);
+CREATE INDEX idx_audit_logs_created_at ON audit_logs (created_at DESC, id DESC);
@@ -0,0 +1,4 @@
+ALTER TABLE materials DROP COLUMN search_vector;
+ALTER TABLE experiment_requests DROP COLUMN search_vector;
+ALTER TABLE missions DROP COLUMN search_vector;
+ALTER TABLE alchemists DROP COLUMN search_vector;
@@ -0,0 +1,28 @@
+-- Vectores de búsqueda de texto completo para /api/search, con la
+-- configuración española (stemming y palabras vacías). El título o nombre
+-- pesa más que el resto del texto.
+ALTER TABLE alchemists ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('spanish', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('spanish', coalesce(title, '')), 'B') ||
+    setweight(to_tsvector('spanish', coalesce(specialty, '')), 'B')
+) STORED;
+
+ALTER TABLE missions ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('spanish', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('spanish', coalesce(description, '')), 'B')
+) STORED;
+
+ALTER TABLE experiment_requests ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('spanish', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('spanish', coalesce(objective, '')), 'B') ||
+    setweight(to_tsvector('spanish', coalesce(description, '')), 'B')
+) STORED;
+
+ALTER TABLE materials ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('spanish', coalesce(name, '')), 'A')
+) STORED;
+
+CREATE INDEX idx_alchemists_search ON alchemists USING GIN (search_vector);
+CREATE INDEX idx_missions_search ON missions USING GIN (search_vector);
+CREATE INDEX idx_experiment_requests_search ON experiment_requests USING GIN (search_vector);
+CREATE INDEX idx_materials_search ON materials USING GIN (search_vector);
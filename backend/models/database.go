@@ -1,7 +1,7 @@
 package models
 
 import (
-	"log"
+	"log/slog"
 	"time"
 
 	"gorm.io/driver/postgres"
@@ -9,7 +9,6 @@ import (
 )
 
 var DB *gorm.DB
-var JWTSecret = []byte("amestris_secret_key_2024")
 
 func ConnectDatabase(dsn string) error {
 	var db *gorm.DB
@@ -18,7 +17,7 @@ func ConnectDatabase(dsn string) error {
 	for i := 0; i < 10; i++ {
 		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
 		if err != nil {
-			log.Printf("Intento %d: Error conectando a la base de datos, reintentando...", i+1)
+			slog.Warn("error conectando a la base de datos, reintentando", "attempt", i+1, "error", err)
 			time.Sleep(2 * time.Second)
 			continue
 		}
@@ -29,26 +28,14 @@ func ConnectDatabase(dsn string) error {
 		}
 
 		if err == nil {
-			log.Println("✅ Conexión a la base de datos establecida")
+			slog.Info("conexión a la base de datos establecida")
 			DB = db
 			break
 		}
 
-		log.Printf("Intento %d: Ping falló, reintentando...", i+1)
+		slog.Warn("ping a la base de datos falló, reintentando", "attempt", i+1, "error", err)
 		time.Sleep(2 * time.Second)
 	}
 
 	return err
 }
-
-func AutoMigrate() error {
-	return DB.AutoMigrate(
-		&Alchemist{},
-		&Mission{},
-		&User{},
-		&ExperimentRequest{},
-		&TransmutationLog{},
-		&AuditLog{},
-		&Material{},
-	)
-}
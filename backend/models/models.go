@@ -7,16 +7,19 @@ import (
 )
 
 type Alchemist struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Name      string    `json:"name"`
-	Title     string    `json:"title"`
-	Specialty string    `json:"specialty"`
-	Rank      string    `json:"rank"`
-	Status    string    `json:"status"`
-	Automail  bool      `json:"automail"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	User      *User     `json:"user" gorm:"foreignKey:AlchemistID"`
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	Name      string `json:"name"`
+	Title     string `json:"title"`
+	Specialty string `json:"specialty"`
+	Rank      string `json:"rank"`
+	Status    string `json:"status"`
+	Automail  bool   `json:"automail"`
+	// SupervisorID es el usuario que supervisa al alquimista y puede
+	// actualizar sus misiones.
+	SupervisorID *uint     `json:"supervisor_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	User         *User     `json:"user" gorm:"foreignKey:AlchemistID"`
 }
 
 type Mission struct {
@@ -31,6 +34,9 @@ type Mission struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// MissionStatuses son los estados válidos de una misión.
+var MissionStatuses = []string{"pending", "in_progress", "completed"}
+
 type ExperimentRequest struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
 	Title       string    `json:"title"`
@@ -45,6 +51,9 @@ type ExperimentRequest struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// ExperimentStatuses son los estados válidos de una solicitud de experimento.
+var ExperimentStatuses = []string{"pending", "approved", "rejected", "withdrawn"}
+
 type TransmutationLog struct {
 	ID           uint      `gorm:"primaryKey" json:"id"`
 	AlchemistID  uint      `json:"alchemist_id"`
@@ -55,19 +64,25 @@ type TransmutationLog struct {
 	Cost         float64   `json:"cost"`
 	EnergyUsed   float64   `json:"energy_used"`
 	LawRespected bool      `json:"law_respected"`
+	RequestID    string    `json:"request_id"`
 	CreatedAt    time.Time `json:"created_at"`
 }
 
+// AuditLog registra una acción. UserID es el usuario que la hizo (nil en los
+// eventos del sistema) y AlchemistID su alquimista vinculado o, en los
+// eventos del sistema, el alquimista al que se refieren.
 type AuditLog struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	AlchemistID uint      `json:"alchemist_id"`
-	Alchemist   Alchemist `json:"alchemist" gorm:"foreignKey:AlchemistID"`
-	Action      string    `json:"action"`
-	Resource    string    `json:"resource"`
-	Details     string    `json:"details"`
-	Severity    string    `json:"severity"`
-	Checked     bool      `json:"checked" gorm:"default:false"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      *uint      `json:"user_id"`
+	AlchemistID *uint      `json:"alchemist_id"`
+	Alchemist   *Alchemist `json:"alchemist" gorm:"foreignKey:AlchemistID"`
+	Action      string     `json:"action"`
+	Resource    string     `json:"resource"`
+	Details     string     `json:"details"`
+	Severity    string     `json:"severity"`
+	Checked     bool       `json:"checked" gorm:"default:false"`
+	RequestID   string     `json:"request_id"`
+	CreatedAt   time.Time  `json:"created_at"`
 }
 
 type Material struct {
@@ -82,24 +97,133 @@ type Material struct {
 }
 
 type User struct {
-	ID          uint       `gorm:"primaryKey" json:"id"`
-	Username    string     `json:"username" gorm:"uniqueIndex"`
-	Password    string     `json:"-"`
-	Role        string     `json:"role"`
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	Username           string     `json:"username" gorm:"uniqueIndex"`
+	Password           string     `json:"-"`
+	Role               string     `json:"role"`
+	Disabled           bool       `json:"disabled"`
+	Pending            bool       `json:"pending"`
+	ServiceAccount     bool       `json:"service_account"`
+	MustChangePassword bool       `json:"must_change_password"`
+	FailedLogins       int        `json:"failed_logins"`
+	LockedUntil        *time.Time `json:"locked_until"`
+	LastLoginAt        *time.Time `json:"last_login_at"`
+	LastFailedLoginAt  *time.Time `json:"last_failed_login_at"`
+	TOTPSecret         string     `json:"-" gorm:"column:totp_secret"`
+	TOTPEnabled        bool       `json:"mfa_enabled" gorm:"column:totp_enabled"`
+	TOTPLastStep       int64      `json:"-" gorm:"column:totp_last_step"`
+	// OIDCIssuer y OIDCSubject identifican a los usuarios creados al entrar
+	// por OIDC; están vacíos en las cuentas locales.
+	OIDCIssuer  string     `json:"oidc_issuer,omitempty" gorm:"column:oidc_issuer"`
+	OIDCSubject string     `json:"oidc_subject,omitempty" gorm:"column:oidc_subject"`
 	AlchemistID *uint      `json:"alchemist_id"`
 	Alchemist   *Alchemist `json:"alchemist" gorm:"foreignKey:AlchemistID"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
+// Invitation permite registrarse con un rol y alquimista decididos por un
+// administrador, sin pasar por la cola de aprobación. Solo se guarda el hash
+// del token y se puede usar una sola vez.
+type Invitation struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	TokenHash   string     `json:"-"`
+	Role        string     `json:"role"`
+	AlchemistID *uint      `json:"alchemist_id"`
+	CreatedByID uint       `json:"created_by_id"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	UsedAt      *time.Time `json:"used_at"`
+	UsedByID    *uint      `json:"used_by_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// APIKeyScopes son los permisos que se pueden conceder a una API key. Cada
+// ruta que acepta API keys exige uno de ellos.
+var APIKeyScopes = []string{
+	"alchemists:read",
+	"missions:read",
+	"experiments:read",
+	"materials:read",
+	"transmute",
+	"transmute:simulate",
+	"audit:read",
+}
+
+// APIKey autentica a una cuenta de servicio. Solo se guarda el hash de la
+// clave; Prefix es la parte visible que permite identificarla y buscarla.
+type APIKey struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `json:"user_id"`
+	User        *User      `json:"-" gorm:"foreignKey:UserID"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix" gorm:"uniqueIndex"`
+	KeyHash     string     `json:"-"`
+	Scopes      SpaceList  `json:"scopes" gorm:"type:text"`
+	CreatedByID uint       `json:"created_by_id"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// RecoveryCode es un código de recuperación de la verificación en dos pasos.
+// Solo se guarda su hash y se puede usar una vez.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `json:"user_id"`
+	CodeHash  string     `json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// RefreshToken es un token de renovación emitido al iniciar sesión. Solo se
+// guarda su hash; cada uso lo revoca y emite otro de la misma sesión.
+// AccessJTI identifica el access token emitido junto a él, para poder
+// invalidarlo al revocar la sesión.
+type RefreshToken struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	UserID          uint       `json:"user_id"`
+	SessionID       string     `json:"session_id"`
+	TokenHash       string     `json:"-"`
+	AccessJTI       string     `json:"-" gorm:"column:access_jti"`
+	AccessExpiresAt time.Time  `json:"-"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	RevokedAt       *time.Time `json:"revoked_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// RevokedToken es una entrada de la lista de access tokens revocados antes
+// de caducar. Puede borrarse una vez pasado ExpiresAt.
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey;column:jti" json:"jti"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SearchTypes son los tipos de resultado de /api/search.
+var SearchTypes = []string{"alchemist", "mission", "experiment", "material"}
+
+// SearchHit es un resultado de /api/search. Highlight es un fragmento del
+// texto con las coincidencias entre <mark> y </mark>; el resto va escapado
+// como HTML.
+type SearchHit struct {
+	Type      string  `json:"type"`
+	ID        uint    `json:"id"`
+	Title     string  `json:"title"`
+	Highlight string  `json:"highlight"`
+	Rank      float64 `json:"rank"`
+}
+
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
 
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Role     string `json:"role"`
-	Username string `json:"username"`
+	UserID             uint   `json:"user_id"`
+	Role               string `json:"role"`
+	Username           string `json:"username"`
+	SessionID          string `json:"sid"`
+	MustChangePassword bool   `json:"mcp,omitempty"`
+	MFASetupRequired   bool   `json:"mfa_setup,omitempty"`
 	jwt.RegisteredClaims
 }
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SpaceList es una lista de nombres que se guarda en una columna de texto
+// separada por espacios, como los scopes de OAuth, y se serializa en JSON
+// como un array.
+type SpaceList []string
+
+func (l SpaceList) Value() (driver.Value, error) {
+	return strings.Join(l, " "), nil
+}
+
+func (l *SpaceList) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+	case string:
+		*l = strings.Fields(v)
+	case []byte:
+		*l = strings.Fields(string(v))
+	default:
+		return fmt.Errorf("SpaceList: tipo no soportado %T", src)
+	}
+	return nil
+}
+
+// Permission es una acción que se puede conceder a un rol.
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Permissions es el catálogo de permisos asignables a los roles.
+var Permissions = []Permission{
+	{"alchemists:write", "Crear y editar alquimistas"},
+	{"alchemists:register", "Registrar alquimistas con cuenta de usuario"},
+	{"missions:write", "Crear y asignar misiones"},
+	{"missions:write:all", "Actualizar cualquier misión y asignar supervisores a los alquimistas"},
+	{"experiments:read:all", "Ver las solicitudes de experimentos de todos los alquimistas"},
+	{"experiments:approve", "Aprobar o rechazar solicitudes de experimentos"},
+	{"materials:write", "Crear, editar y eliminar materiales"},
+	{"audit:read:alerts", "Ver los avisos y alertas de auditoría de todos"},
+	{"audit:read:all", "Ver el registro de auditoría completo"},
+	{"registrations:approve", "Aprobar o rechazar solicitudes de registro"},
+	{"users:manage", "Administrar cuentas de usuario"},
+	{"invitations:manage", "Emitir y revocar invitaciones"},
+	{"service-accounts:manage", "Administrar cuentas de servicio y API keys"},
+	{"roles:manage", "Editar los roles y sus permisos"},
+}
+
+// IsPermission indica si name está en el catálogo.
+func IsPermission(name string) bool {
+	for _, p := range Permissions {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Role es un conjunto de permisos con nombre. Los roles predefinidos no se
+// pueden eliminar, aunque sí cambiar sus permisos.
+type Role struct {
+	Name        string    `gorm:"primaryKey" json:"name"`
+	Description string    `json:"description"`
+	Permissions SpaceList `json:"permissions" gorm:"type:text"`
+	BuiltIn     bool      `json:"built_in"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// DefaultRoles son los roles predefinidos con sus permisos iniciales. La
+// migración 0011_roles los inserta con los mismos valores (0012 añade
+// missions:write:all al administrador).
+func DefaultRoles() []Role {
+	all := make(SpaceList, len(Permissions))
+	for i, p := range Permissions {
+		all[i] = p.Name
+	}
+	return []Role{
+		{Name: "alchemist", Description: "Alquimista estatal", BuiltIn: true, Permissions: SpaceList{}},
+		{Name: "supervisor", Description: "Supervisa misiones y experimentos", BuiltIn: true, Permissions: SpaceList{
+			"alchemists:write", "alchemists:register", "missions:write", "experiments:read:all",
+			"experiments:approve", "materials:write", "audit:read:alerts", "registrations:approve",
+		}},
+		{Name: "admin", Description: "Administrador del sistema", BuiltIn: true, Permissions: all},
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import "slices"
+
+// Principal es quien hace una petición autenticada: el usuario con su rol y
+// permisos y, si lo tiene, el alquimista vinculado. Lo carga
+// middleware.LoadPrincipal una vez por petición.
+type Principal struct {
+	User        *User
+	Role        string
+	Permissions []string
+	// Alchemist es nil si el usuario no tiene perfil de alquimista, como los
+	// administradores o las cuentas de servicio.
+	Alchemist *Alchemist
+	// APIKeyID es 0 si la petición se autenticó con un access token.
+	APIKeyID uint
+}
+
+func (p *Principal) UserID() uint {
+	return p.User.ID
+}
+
+// AlchemistID devuelve el ID del alquimista vinculado o nil.
+func (p *Principal) AlchemistID() *uint {
+	if p.Alchemist == nil {
+		return nil
+	}
+	return &p.Alchemist.ID
+}
+
+func (p *Principal) Can(permission string) bool {
+	return slices.Contains(p.Permissions, permission)
+}
@@ -0,0 +1,149 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Scheme es un algoritmo de hash de contraseñas con sus parámetros. El hash
+// codificado lleva el algoritmo y los parámetros con que se calculó, de modo
+// que se puede verificar aunque la configuración haya cambiado después.
+type Scheme interface {
+	Hash(plain string) (string, error)
+	// Matches indica si encoded es un hash de este algoritmo.
+	Matches(encoded string) bool
+	// Verify compara plain con encoded. outdated indica que encoded se
+	// calculó con otros parámetros y conviene volver a calcularlo.
+	Verify(plain, encoded string) (ok, outdated bool, err error)
+}
+
+// Hasher calcula los hashes nuevos con Current y verifica también los de
+// Legacy, marcándolos como desactualizados.
+type Hasher struct {
+	Current Scheme
+	Legacy  []Scheme
+}
+
+func (h Hasher) Hash(plain string) (string, error) {
+	return h.Current.Hash(plain)
+}
+
+// Verify compara plain con el hash encoded. rehash indica que la contraseña
+// es correcta pero el hash no usa el algoritmo o los parámetros actuales.
+func (h Hasher) Verify(plain, encoded string) (ok, rehash bool) {
+	if h.Current.Matches(encoded) {
+		ok, outdated, err := h.Current.Verify(plain, encoded)
+		return ok && err == nil, ok && outdated
+	}
+	for _, scheme := range h.Legacy {
+		if scheme.Matches(encoded) {
+			ok, _, err := scheme.Verify(plain, encoded)
+			return ok && err == nil, ok && err == nil
+		}
+	}
+	return false, false
+}
+
+// Argon2id es el algoritmo por defecto (RFC 9106). Los hashes se codifican
+// en el formato PHC: $argon2id$v=19$m=<KiB>,t=<iteraciones>,p=<hilos>$sal$hash.
+type Argon2id struct {
+	MemoryKiB   uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2id son los parámetros recomendados por el RFC 9106 para
+// entornos con memoria limitada.
+var DefaultArgon2id = Argon2id{MemoryKiB: 64 * 1024, Iterations: 3, Parallelism: 4, SaltLength: 16, KeyLength: 32}
+
+const argon2idPrefix = "$argon2id$"
+
+func (a Argon2id) Hash(plain string) (string, error) {
+	salt := make([]byte, a.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(plain), salt, a.Iterations, a.MemoryKiB, a.Parallelism, a.KeyLength)
+	encode := base64.RawStdEncoding.EncodeToString
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		a.MemoryKiB, a.Iterations, a.Parallelism, encode(salt), encode(key)), nil
+}
+
+func (a Argon2id) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, argon2idPrefix)
+}
+
+func (a Argon2id) Verify(plain, encoded string) (bool, bool, error) {
+	stored, salt, key, err := parseArgon2id(encoded)
+	if err != nil {
+		return false, false, err
+	}
+	candidate := argon2.IDKey([]byte(plain), salt, stored.Iterations, stored.MemoryKiB, stored.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(candidate, key) != 1 {
+		return false, false, nil
+	}
+	outdated := stored.MemoryKiB != a.MemoryKiB || stored.Iterations != a.Iterations ||
+		stored.Parallelism != a.Parallelism || stored.SaltLength != a.SaltLength || stored.KeyLength != a.KeyLength
+	return true, outdated, nil
+}
+
+func parseArgon2id(encoded string) (params Argon2id, salt, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, errors.New("hash argon2id mal formado")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("versión de argon2id no soportada: %s", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.MemoryKiB, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("parámetros de argon2id inválidos: %w", err)
+	}
+	if params.Iterations == 0 || params.Parallelism == 0 {
+		return params, nil, nil, errors.New("parámetros de argon2id inválidos")
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, nil, nil, err
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return params, nil, nil, err
+	}
+	params.SaltLength, params.KeyLength = uint32(len(salt)), uint32(len(key))
+	return params, salt, key, nil
+}
+
+// Bcrypt se mantiene para las contraseñas guardadas antes de argon2id. El
+// hash ($2a$<coste>$...) ya incluye el coste.
+type Bcrypt struct {
+	Cost int
+}
+
+func (b Bcrypt) Hash(plain string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(plain), b.Cost)
+	return string(hashed), err
+}
+
+func (b Bcrypt) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (b Bcrypt) Verify(plain, encoded string) (bool, bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(plain))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return true, err == nil && cost != b.Cost, nil
+}
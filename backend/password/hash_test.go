@@ -0,0 +1,109 @@
+package password
+
+import (
+	"regexp"
+	"testing"
+)
+
+// Parámetros bajos para que los tests sean rápidos.
+var (
+	testArgon  = Argon2id{MemoryKiB: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	testBcrypt = Bcrypt{Cost: 4}
+)
+
+func TestArgon2idPHCFormat(t *testing.T) {
+	encoded, err := testArgon.Hash("piedra filosofal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	phc := regexp.MustCompile(`^\$argon2id\$v=19\$m=64,t=1,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`)
+	if !phc.MatchString(encoded) {
+		t.Fatalf("hash fuera del formato PHC: %s", encoded)
+	}
+
+	params, salt, key, err := parseArgon2id(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params != testArgon || len(salt) != 16 || len(key) != 32 {
+		t.Errorf("parámetros leídos %+v, esperados %+v", params, testArgon)
+	}
+
+	if ok, outdated, err := testArgon.Verify("piedra filosofal", encoded); !ok || outdated || err != nil {
+		t.Errorf("Verify = (%v, %v, %v), esperado (true, false, nil)", ok, outdated, err)
+	}
+	if ok, _, _ := testArgon.Verify("piedra roja", encoded); ok {
+		t.Error("se aceptó una contraseña incorrecta")
+	}
+}
+
+func TestParseArgon2idRejectsMalformed(t *testing.T) {
+	for _, encoded := range []string{
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+		"$argon2i$v=19$m=64,t=1,p=1$c2FsdHNhbHRzYWx0$a2V5",
+		"$argon2id$v=16$m=64,t=1,p=1$c2FsdHNhbHRzYWx0$a2V5",
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHRzYWx0$a2V5",
+		"$argon2id$v=19$m=64;t=1;p=1$c2FsdHNhbHRzYWx0$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$no base64!$a2V5",
+	} {
+		if _, _, _, err := parseArgon2id(encoded); err == nil {
+			t.Errorf("se aceptó %s", encoded)
+		}
+	}
+}
+
+func TestArgon2idOutdatedParameters(t *testing.T) {
+	encoded, _ := testArgon.Hash("secreto")
+
+	stronger := testArgon
+	stronger.Iterations = 2
+	ok, outdated, err := stronger.Verify("secreto", encoded)
+	if !ok || !outdated || err != nil {
+		t.Errorf("Verify con otros parámetros = (%v, %v, %v), esperado (true, true, nil)", ok, outdated, err)
+	}
+}
+
+func TestHasherRehash(t *testing.T) {
+	hasher := Hasher{Current: testArgon, Legacy: []Scheme{testBcrypt}}
+	current, _ := testArgon.Hash("secreto")
+	legacy, _ := testBcrypt.Hash("secreto")
+	stronger := testArgon
+	stronger.MemoryKiB = 128
+	outdated, _ := stronger.Hash("secreto")
+
+	tests := []struct {
+		name       string
+		plain      string
+		encoded    string
+		ok, rehash bool
+	}{
+		{"actual", "secreto", current, true, false},
+		{"parámetros antiguos", "secreto", outdated, true, true},
+		{"algoritmo antiguo", "secreto", legacy, true, true},
+		{"incorrecta con algoritmo antiguo", "otro", legacy, false, false},
+		{"incorrecta", "otro", current, false, false},
+		{"formato desconocido", "secreto", "$md5$secreto", false, false},
+	}
+	for _, tt := range tests {
+		ok, rehash := hasher.Verify(tt.plain, tt.encoded)
+		if ok != tt.ok || rehash != tt.rehash {
+			t.Errorf("%s: Verify = (%v, %v), esperado (%v, %v)", tt.name, ok, rehash, tt.ok, tt.rehash)
+		}
+	}
+
+	// Los hashes nuevos usan siempre el algoritmo actual
+	encoded, _ := hasher.Hash("secreto")
+	if !testArgon.Matches(encoded) {
+		t.Errorf("hash nuevo con otro algoritmo: %s", encoded)
+	}
+}
+
+func TestBcryptOutdatedCost(t *testing.T) {
+	encoded, _ := testBcrypt.Hash("secreto")
+	if ok, outdated, _ := (Bcrypt{Cost: 5}).Verify("secreto", encoded); !ok || !outdated {
+		t.Errorf("coste distinto: (%v, %v), esperado (true, true)", ok, outdated)
+	}
+	if ok, outdated, _ := testBcrypt.Verify("secreto", encoded); !ok || outdated {
+		t.Errorf("mismo coste: (%v, %v), esperado (true, false)", ok, outdated)
+	}
+}
@@ -0,0 +1,71 @@
+// Package password define la política de contraseñas y genera contraseñas
+// aleatorias que la cumplen.
+package password
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Policy son los requisitos mínimos de una contraseña elegida por el usuario.
+type Policy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// Check devuelve los requisitos que no cumple password; vacío si es válida.
+func (p Policy) Check(password string) []string {
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	var problems []string
+	if utf8.RuneCountInString(password) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("debe tener al menos %d caracteres", p.MinLength))
+	}
+	if p.RequireUpper && !upper {
+		problems = append(problems, "debe incluir una mayúscula")
+	}
+	if p.RequireLower && !lower {
+		problems = append(problems, "debe incluir una minúscula")
+	}
+	if p.RequireDigit && !digit {
+		problems = append(problems, "debe incluir un dígito")
+	}
+	if p.RequireSymbol && !symbol {
+		problems = append(problems, "debe incluir un símbolo")
+	}
+	return problems
+}
+
+// Generate devuelve una contraseña aleatoria de al menos 16 caracteres que
+// cumple la política.
+func (p Policy) Generate() string {
+	length := max(16, p.MinLength)
+	buf := make([]byte, length*3/4+1)
+	for {
+		if _, err := rand.Read(buf); err != nil {
+			panic(err)
+		}
+		candidate := base64.RawURLEncoding.EncodeToString(buf)[:length]
+		if len(p.Check(candidate)) == 0 {
+			return candidate
+		}
+	}
+}
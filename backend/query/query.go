@@ -0,0 +1,418 @@
+// Package query interpreta los parámetros de los listados (filtros, orden y
+// paginación por offset o por cursor) contra una lista blanca de campos por
+// recurso. Los repositorios aplican el resultado en SQL o, el de memoria,
+// con List.Apply.
+package query
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// Type es el tipo de un campo, con el que se interpretan los valores de los
+// filtros y del cursor.
+type Type int
+
+const (
+	Text Type = iota
+	Int
+	Float
+	Bool
+	Time
+)
+
+// Field es un campo admitido en los listados. Value lo lee de una fila; se
+// usa para construir el cursor y en el repositorio en memoria.
+type Field[T any] struct {
+	Column string
+	Type   Type
+	Value  func(T) any
+	Filter bool
+	Sort   bool
+}
+
+// Schema son los campos de un recurso por nombre de parámetro. Debe incluir
+// "id", que desempata el orden para que el cursor sea estable.
+type Schema[T any] struct {
+	Fields map[string]Field[T]
+	// DefaultSort se aplica si la petición no trae sort, con el mismo
+	// formato: campos separados por comas y "-" para orden descendente.
+	DefaultSort string
+}
+
+// Condition exige que Field tenga uno de Values.
+type Condition struct {
+	Field  string
+	Column string
+	Values []any
+}
+
+type Order struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+// List es un listado ya validado.
+type List[T any] struct {
+	Conditions []Condition
+	// Orders termina siempre en id.
+	Orders []Order
+	Limit  int
+	Offset int
+	// After son los valores de Orders de la última fila de la página
+	// anterior; nil si no se pagina por cursor.
+	After []any
+
+	schema *Schema[T]
+	sort   string
+}
+
+// Error es un parámetro inválido; el texto se puede devolver al cliente.
+type Error struct {
+	Param  string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("parámetro %s inválido: %s", e.Param, e.Reason)
+}
+
+// Parse valida los parámetros de la petición. Los que no son limit, offset,
+// cursor ni sort deben ser filtros del esquema; un filtro con varios valores
+// separados por comas admite cualquiera de ellos.
+func (s *Schema[T]) Parse(params url.Values) (List[T], error) {
+	list := List[T]{Limit: DefaultLimit, schema: s}
+
+	for name, values := range params {
+		raw := values[len(values)-1]
+		switch name {
+		case "limit":
+			limit, err := strconv.Atoi(raw)
+			if err != nil || limit < 1 || limit > MaxLimit {
+				return list, &Error{name, fmt.Sprintf("debe estar entre 1 y %d", MaxLimit)}
+			}
+			list.Limit = limit
+		case "offset":
+			offset, err := strconv.Atoi(raw)
+			if err != nil || offset < 0 {
+				return list, &Error{name, "debe ser un entero no negativo"}
+			}
+			list.Offset = offset
+		case "cursor", "sort":
+		default:
+			field, ok := s.Fields[name]
+			if !ok || !field.Filter {
+				return list, &Error{name, "no se puede filtrar por este campo"}
+			}
+			condition := Condition{Field: name, Column: field.Column}
+			for _, value := range strings.Split(strings.Join(values, ","), ",") {
+				parsed, err := parseValue(field.Type, strings.TrimSpace(value))
+				if err != nil {
+					return list, &Error{name, err.Error()}
+				}
+				condition.Values = append(condition.Values, parsed)
+			}
+			list.Conditions = append(list.Conditions, condition)
+		}
+	}
+	// El orden de los mapas no es estable y los filtros acaban en el SQL
+	slices.SortFunc(list.Conditions, func(a, b Condition) int { return strings.Compare(a.Field, b.Field) })
+
+	sort := params.Get("sort")
+	if sort == "" {
+		sort = s.DefaultSort
+	}
+	if err := list.parseSort(sort); err != nil {
+		return list, err
+	}
+
+	if cursor := params.Get("cursor"); cursor != "" {
+		if params.Has("offset") {
+			return list, &Error{"cursor", "no se puede combinar con offset"}
+		}
+		if err := list.decodeCursor(cursor); err != nil {
+			return list, err
+		}
+	}
+	return list, nil
+}
+
+func (l *List[T]) parseSort(sort string) error {
+	var names []string
+	for _, item := range strings.Split(sort, ",") {
+		item = strings.TrimSpace(item)
+		name := strings.TrimPrefix(item, "-")
+		field, ok := l.schema.Fields[name]
+		if !ok || !field.Sort {
+			return &Error{"sort", fmt.Sprintf("no se puede ordenar por %q", name)}
+		}
+		if slices.Contains(names, name) {
+			return &Error{"sort", fmt.Sprintf("campo %q repetido", name)}
+		}
+		names = append(names, name)
+		l.Orders = append(l.Orders, Order{Field: name, Column: field.Column, Desc: strings.HasPrefix(item, "-")})
+	}
+	if !slices.Contains(names, "id") {
+		l.Orders = append(l.Orders, Order{Field: "id", Column: l.schema.Fields["id"].Column})
+	}
+
+	canonical := make([]string, len(l.Orders))
+	for i, order := range l.Orders {
+		canonical[i] = order.Field
+		if order.Desc {
+			canonical[i] = "-" + order.Field
+		}
+	}
+	l.sort = strings.Join(canonical, ",")
+	return nil
+}
+
+// Where añade una condición impuesta por el servidor, como las reglas de
+// visibilidad de cada rol. No hace falta que el campo admita filtros.
+func (l *List[T]) Where(field string, values ...any) {
+	condition := Condition{Field: field, Column: l.schema.Fields[field].Column}
+	for _, value := range values {
+		condition.Values = append(condition.Values, normalize(value))
+	}
+	l.Conditions = append(l.Conditions, condition)
+}
+
+// Keyset indica si la petición pagina por cursor.
+func (l List[T]) Keyset() bool {
+	return l.After != nil
+}
+
+type cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+func (l *List[T]) decodeCursor(raw string) error {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	var decoded cursor
+	if err == nil {
+		err = json.Unmarshal(data, &decoded)
+	}
+	if err != nil {
+		return &Error{"cursor", "mal formado"}
+	}
+	if decoded.Sort != l.sort {
+		return &Error{"cursor", "corresponde a otro orden"}
+	}
+	if len(decoded.Values) != len(l.Orders) {
+		return &Error{"cursor", "mal formado"}
+	}
+
+	l.After = make([]any, len(l.Orders))
+	for i, order := range l.Orders {
+		value, err := parseValue(l.schema.Fields[order.Field].Type, decoded.Values[i])
+		if err != nil {
+			return &Error{"cursor", "mal formado"}
+		}
+		l.After[i] = value
+	}
+	return nil
+}
+
+func (l List[T]) encodeCursor(row T) string {
+	values := make([]string, len(l.Orders))
+	for i, order := range l.Orders {
+		values[i] = formatValue(normalize(l.schema.Fields[order.Field].Value(row)))
+	}
+	data, _ := json.Marshal(cursor{Sort: l.sort, Values: values})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Page es una página de resultados. Total cuenta todas las filas que
+// cumplen los filtros, no solo las de la página.
+type Page[T any] struct {
+	Items  []T
+	Total  int64
+	Limit  int
+	Offset int
+	Keyset bool
+	// NextCursor está vacío en la última página.
+	NextCursor string
+}
+
+// NewPage construye la página a partir de hasta Limit+1 filas: la sobrante
+// solo indica que hay más.
+func (l List[T]) NewPage(rows []T, total int64) Page[T] {
+	page := Page[T]{Items: rows, Total: total, Limit: l.Limit, Offset: l.Offset, Keyset: l.Keyset()}
+	if len(rows) > l.Limit {
+		page.Items = rows[:l.Limit]
+		page.NextCursor = l.encodeCursor(page.Items[l.Limit-1])
+	}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	return page
+}
+
+// Apply evalúa el listado sobre filas en memoria.
+func (l List[T]) Apply(rows []T) Page[T] {
+	var matched []T
+	for _, row := range rows {
+		if l.matches(row) {
+			matched = append(matched, row)
+		}
+	}
+	slices.SortStableFunc(matched, func(a, b T) int { return l.compareRows(a, b) })
+
+	start := min(l.Offset, len(matched))
+	if l.After != nil {
+		start = 0
+		for start < len(matched) && l.compareAfter(matched[start]) <= 0 {
+			start++
+		}
+	}
+	end := min(start+l.Limit+1, len(matched))
+	return l.NewPage(matched[start:end], int64(len(matched)))
+}
+
+func (l List[T]) matches(row T) bool {
+	for _, condition := range l.Conditions {
+		value := normalize(l.schema.Fields[condition.Field].Value(row))
+		if !slices.ContainsFunc(condition.Values, func(want any) bool { return compare(value, want) == 0 }) {
+			return false
+		}
+	}
+	return true
+}
+
+func (l List[T]) compareRows(a, b T) int {
+	for _, order := range l.Orders {
+		value := l.schema.Fields[order.Field].Value
+		if result := compare(normalize(value(a)), normalize(value(b))); result != 0 {
+			if order.Desc {
+				return -result
+			}
+			return result
+		}
+	}
+	return 0
+}
+
+// compareAfter compara la fila con la posición del cursor: negativo o cero
+// si va antes o es la misma.
+func (l List[T]) compareAfter(row T) int {
+	for i, order := range l.Orders {
+		if result := compare(normalize(l.schema.Fields[order.Field].Value(row)), l.After[i]); result != 0 {
+			if order.Desc {
+				return -result
+			}
+			return result
+		}
+	}
+	return 0
+}
+
+func parseValue(typ Type, raw string) (any, error) {
+	switch typ {
+	case Int:
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q no es un entero", raw)
+		}
+		return value, nil
+	case Float:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q no es un número", raw)
+		}
+		return value, nil
+	case Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q no es true ni false", raw)
+		}
+		return value, nil
+	case Time:
+		value, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q no es una fecha RFC 3339", raw)
+		}
+		return value, nil
+	default:
+		return raw, nil
+	}
+}
+
+func formatValue(value any) string {
+	switch value := value.(type) {
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	case time.Time:
+		return value.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// normalize reduce los valores de los campos a los tipos de parseValue.
+func normalize(value any) any {
+	switch value := value.(type) {
+	case uint:
+		return int64(value)
+	case int:
+		return int64(value)
+	case *uint:
+		if value == nil {
+			return nil
+		}
+		return int64(*value)
+	default:
+		return value
+	}
+}
+
+func compare(a, b any) int {
+	switch a := a.(type) {
+	case string:
+		b, _ := b.(string)
+		return strings.Compare(a, b)
+	case int64:
+		b, _ := b.(int64)
+		return cmp.Compare(a, b)
+	case float64:
+		b, _ := b.(float64)
+		return cmp.Compare(a, b)
+	case bool:
+		b, _ := b.(bool)
+		return cmp.Compare(boolRank(a), boolRank(b))
+	case time.Time:
+		b, _ := b.(time.Time)
+		return a.Compare(b)
+	case nil:
+		if b == nil {
+			return 0
+		}
+		return 1
+	default:
+		return 0
+	}
+}
+
+func boolRank(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
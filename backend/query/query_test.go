@@ -0,0 +1,149 @@
+package query
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+type row struct {
+	ID     uint
+	Name   string
+	Rank   int
+	Active bool
+}
+
+var testSchema = &Schema[row]{
+	Fields: map[string]Field[row]{
+		"id":     {Column: "id", Type: Int, Sort: true, Value: func(r row) any { return r.ID }},
+		"name":   {Column: "name", Type: Text, Filter: true, Sort: true, Value: func(r row) any { return r.Name }},
+		"rank":   {Column: "rank", Type: Int, Filter: true, Sort: true, Value: func(r row) any { return r.Rank }},
+		"active": {Column: "active", Type: Bool, Filter: true, Value: func(r row) any { return r.Active }},
+	},
+	DefaultSort: "name",
+}
+
+var testRows = []row{
+	{1, "Edward", 3, true},
+	{2, "Alphonse", 1, true},
+	{3, "Roy", 5, true},
+	{4, "Riza", 3, false},
+	{5, "Olivier", 5, true},
+	{6, "Izumi", 3, false},
+	{7, "Scar", 0, false},
+}
+
+func parse(t *testing.T, raw string) List[row] {
+	t.Helper()
+	params, err := url.ParseQuery(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := testSchema.Parse(params)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", raw, err)
+	}
+	return list
+}
+
+func ids(rows []row) []uint {
+	var ids []uint
+	for _, r := range rows {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestParseDefaults(t *testing.T) {
+	list := parse(t, "")
+	if list.Limit != DefaultLimit || list.Offset != 0 || list.Keyset() {
+		t.Errorf("valores por defecto inesperados: %+v", list)
+	}
+	// El orden por defecto termina en id para desempatar
+	if len(list.Orders) != 2 || list.Orders[0].Field != "name" || list.Orders[1].Field != "id" {
+		t.Errorf("orden %+v, esperado name,id", list.Orders)
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	list := parse(t, "rank=3,5&active=true")
+	if len(list.Conditions) != 2 {
+		t.Fatalf("condiciones %+v", list.Conditions)
+	}
+	// Ordenadas por campo
+	if c := list.Conditions[1]; c.Field != "rank" || !slices.Equal(c.Values, []any{int64(3), int64(5)}) {
+		t.Errorf("condición de rank %+v", c)
+	}
+	if got := ids(list.Apply(testRows).Items); !slices.Equal(got, []uint{1, 5, 3}) {
+		t.Errorf("filtrado %v, esperado [1 5 3]", got)
+	}
+}
+
+func TestParseRejectsInvalid(t *testing.T) {
+	next := parse(t, "sort=rank&limit=1").Apply(testRows).NextCursor
+
+	for _, raw := range []string{
+		"limit=0",
+		"limit=201",
+		"offset=-1",
+		"desconocido=1",
+		"id=1", // id no admite filtro
+		"rank=alto",
+		"active=quizá",
+		"sort=active",
+		"sort=name,-name",
+		"cursor=no-es-un-cursor",
+		"cursor=" + next + "&offset=10",
+		"cursor=" + next + "&sort=name",
+	} {
+		params, _ := url.ParseQuery(raw)
+		_, err := testSchema.Parse(params)
+		var queryErr *Error
+		if !errors.As(err, &queryErr) {
+			t.Errorf("Parse(%q) = %v, esperado *Error", raw, err)
+		}
+	}
+}
+
+func TestApplyOffset(t *testing.T) {
+	page := parse(t, "sort=-rank&limit=3&offset=3").Apply(testRows)
+	// -rank con empates por id: 3 5 1 4 6 2 7
+	if got := ids(page.Items); !slices.Equal(got, []uint{4, 6, 2}) {
+		t.Errorf("página %v, esperada [4 6 2]", got)
+	}
+	if page.Total != int64(len(testRows)) || page.Keyset {
+		t.Errorf("Total %d, Keyset %v", page.Total, page.Keyset)
+	}
+}
+
+func TestKeysetCursorWalk(t *testing.T) {
+	for _, sort := range []string{"-rank", "name", "rank,-id"} {
+		all := ids(parse(t, "sort="+sort+"&limit=200").Apply(testRows).Items)
+
+		var walked []uint
+		raw := "sort=" + sort + "&limit=2"
+		for pages := 0; ; pages++ {
+			if pages > len(testRows) {
+				t.Fatalf("%s: el cursor no avanza", sort)
+			}
+			page := parse(t, raw).Apply(testRows)
+			walked = append(walked, ids(page.Items)...)
+			if page.NextCursor == "" {
+				break
+			}
+			raw = "sort=" + sort + "&limit=2&cursor=" + page.NextCursor
+		}
+		if !slices.Equal(walked, all) {
+			t.Errorf("%s: recorrido por cursor %v, esperado %v", sort, walked, all)
+		}
+	}
+}
+
+func TestWhereAppliesServerConditions(t *testing.T) {
+	list := parse(t, "")
+	list.Where("id", uint(2), uint(7))
+	if got := ids(list.Apply(testRows).Items); !slices.Equal(got, []uint{2, 7}) {
+		t.Errorf("Where(id) = %v, esperado [2 7]", got)
+	}
+}
@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	rule    Rule
+}
+
+// MemoryLimiter guarda los cubos en memoria del proceso.
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+	now     func() time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.calls++
+	if l.calls%1000 == 0 {
+		l.evict(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: rule.capacity(), updated: now}
+		l.buckets[key] = b
+	}
+	b.rule = rule
+
+	tokens, result := take(b.tokens, now.Sub(b.updated), rule)
+	b.tokens, b.updated = tokens, now
+	return result, nil
+}
+
+// evict elimina los cubos inactivos durante más de un periodo de su propia
+// regla, que ya estarían llenos de nuevo.
+func (l *MemoryLimiter) evict(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) > b.rule.Period {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// PostgresLimiter guarda los cubos en la tabla rate_limit_buckets para que
+// todas las réplicas compartan los límites. Usa el reloj de la base de datos.
+// Los cubos inactivos se borran con RunPurge.
+type PostgresLimiter struct {
+	db *sql.DB
+}
+
+func NewPostgresLimiter(db *sql.DB) *PostgresLimiter {
+	return &PostgresLimiter{db: db}
+}
+
+func (l *PostgresLimiter) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO rate_limit_buckets (key, tokens, period_seconds, updated_at) VALUES ($1, $2, $3, now())
+		 ON CONFLICT (key) DO NOTHING`, key, rule.capacity(), rule.Period.Seconds())
+	if err != nil {
+		return Result{}, err
+	}
+
+	var tokens, elapsed float64
+	err = tx.QueryRowContext(ctx,
+		`SELECT tokens, EXTRACT(EPOCH FROM (now() - updated_at))
+		 FROM rate_limit_buckets WHERE key = $1 FOR UPDATE`, key).Scan(&tokens, &elapsed)
+	if err != nil {
+		return Result{}, err
+	}
+
+	tokens, result := take(tokens, time.Duration(elapsed*float64(time.Second)), rule)
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE rate_limit_buckets SET tokens = $2, period_seconds = $3, updated_at = now() WHERE key = $1`,
+		key, tokens, rule.Period.Seconds())
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result, tx.Commit()
+}
+
+// Purge borra los cubos inactivos durante más de un periodo de su regla, que
+// ya estarían llenos de nuevo, y devuelve cuántos borró.
+func (l *PostgresLimiter) Purge(ctx context.Context) (int64, error) {
+	result, err := l.db.ExecContext(ctx,
+		`DELETE FROM rate_limit_buckets WHERE updated_at < now() - period_seconds * interval '1 second'`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RunPurge ejecuta Purge cada interval hasta que se cancele ctx.
+func (l *PostgresLimiter) RunPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := l.Purge(ctx)
+			if err != nil {
+				slog.Error("error purgando los cubos de rate limiting", "error", err)
+				continue
+			}
+			slog.Debug("purga de rate limiting completada", "buckets", purged)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Package ratelimit implementa limitación de peticiones por token bucket con
+// almacenamiento intercambiable: en memoria para una sola réplica o en
+// Postgres para compartir los cubos entre réplicas.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Rule permite Requests peticiones por Period con ráfagas de hasta Burst.
+type Rule struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// rate devuelve los tokens repuestos por segundo.
+func (r Rule) rate() float64 {
+	return float64(r.Requests) / r.Period.Seconds()
+}
+
+func (r Rule) capacity() float64 {
+	if r.Burst > 0 {
+		return float64(r.Burst)
+	}
+	return float64(r.Requests)
+}
+
+// Result es la decisión para una petición.
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Limiter consume un token del cubo identificado por key.
+type Limiter interface {
+	Allow(ctx context.Context, key string, rule Rule) (Result, error)
+}
+
+// take aplica el algoritmo de token bucket: repone los tokens acumulados
+// desde la última petición y consume uno si hay disponible. Devuelve los
+// tokens restantes y el resultado.
+func take(tokens float64, elapsed time.Duration, rule Rule) (float64, Result) {
+	capacity := rule.capacity()
+	tokens = math.Min(capacity, tokens+elapsed.Seconds()*rule.rate())
+
+	if tokens >= 1 {
+		tokens--
+		return tokens, Result{Allowed: true, Remaining: int(tokens)}
+	}
+
+	wait := time.Duration((1 - tokens) / rule.rate() * float64(time.Second))
+	return tokens, Result{Allowed: false, RetryAfter: wait}
+}
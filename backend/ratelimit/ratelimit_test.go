@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// clock es un reloj manual para MemoryLimiter.
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time          { return c.now }
+func (c *clock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestLimiter() (*MemoryLimiter, *clock) {
+	c := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := NewMemoryLimiter()
+	l.now = c.Now
+	return l, c
+}
+
+func TestTokenBucketBurstAndRefill(t *testing.T) {
+	ctx := context.Background()
+	l, clock := newTestLimiter()
+	rule := Rule{Requests: 6, Period: time.Minute, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		if result, _ := l.Allow(ctx, "k", rule); !result.Allowed {
+			t.Fatalf("petición %d denegada dentro de la ráfaga", i+1)
+		}
+	}
+	result, _ := l.Allow(ctx, "k", rule)
+	if result.Allowed {
+		t.Fatal("se permitió superar la ráfaga")
+	}
+	// 6 por minuto: un token cada 10 segundos.
+	if result.RetryAfter != 10*time.Second {
+		t.Errorf("RetryAfter = %v, esperado 10s", result.RetryAfter)
+	}
+
+	clock.Advance(10 * time.Second)
+	if result, _ := l.Allow(ctx, "k", rule); !result.Allowed {
+		t.Fatal("no se repuso el token tras 10s")
+	}
+	if result, _ := l.Allow(ctx, "k", rule); result.Allowed {
+		t.Fatal("se repuso más de un token")
+	}
+
+	// Las claves son independientes
+	if result, _ := l.Allow(ctx, "otra", rule); !result.Allowed {
+		t.Fatal("una clave nueva empieza con el cubo lleno")
+	}
+}
+
+func TestTokenBucketCapacity(t *testing.T) {
+	ctx := context.Background()
+	l, clock := newTestLimiter()
+	rule := Rule{Requests: 2, Period: time.Second}
+
+	l.Allow(ctx, "k", rule)
+	clock.Advance(time.Hour)
+
+	// Sin Burst la capacidad es Requests aunque pase mucho tiempo
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if result, _ := l.Allow(ctx, "k", rule); result.Allowed {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("permitidas %d tras una hora, esperadas 2", allowed)
+	}
+}
+
+func TestEvictUsesEachBucketRule(t *testing.T) {
+	ctx := context.Background()
+	l, clock := newTestLimiter()
+	short := Rule{Requests: 5, Period: time.Minute}
+	long := Rule{Requests: 5, Period: time.Hour}
+
+	l.Allow(ctx, "login:ip:1", short)
+	l.Allow(ctx, "transmute:ip:1", long)
+	l.Allow(ctx, "transmute:ip:1", long)
+
+	// La purga la dispara una petición del grupo de periodo corto, pero el
+	// cubo del grupo largo sigue vigente y no debe perder lo consumido.
+	clock.Advance(10 * time.Minute)
+	l.calls = 999
+	l.Allow(ctx, "login:ip:2", short)
+
+	if _, ok := l.buckets["login:ip:1"]; ok {
+		t.Error("no se purgó el cubo inactivo durante más de su periodo")
+	}
+	b, ok := l.buckets["transmute:ip:1"]
+	if !ok {
+		t.Fatal("se purgó un cubo dentro de su periodo")
+	}
+	if b.tokens != 3 {
+		t.Errorf("tokens = %v, esperados 3", b.tokens)
+	}
+}
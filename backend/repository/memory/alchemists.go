@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+
+	"amestris-backend/models"
+	"amestris-backend/query"
+	"amestris-backend/repository"
+)
+
+type alchemistRepository struct {
+	db *database
+}
+
+func (r *alchemistRepository) List(ctx context.Context) ([]models.Alchemist, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	return r.db.alchemists.values(nil), nil
+}
+
+func (r *alchemistRepository) Page(ctx context.Context, list query.List[models.Alchemist]) (query.Page[models.Alchemist], error) {
+	alchemists, _ := r.List(ctx)
+	return list.Apply(alchemists), nil
+}
+
+func (r *alchemistRepository) Get(ctx context.Context, id uint) (*models.Alchemist, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	alchemist, ok := r.db.alchemists.rows[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	for _, user := range r.db.users.rows {
+		if user.AlchemistID != nil && *user.AlchemistID == id {
+			user.Alchemist = nil
+			alchemist.User = &user
+			break
+		}
+	}
+	return &alchemist, nil
+}
+
+func (r *alchemistRepository) FindByName(ctx context.Context, name string) (*models.Alchemist, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, alchemist := range r.db.alchemists.values(nil) {
+		if alchemist.Name == name {
+			return &alchemist, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *alchemistRepository) Create(ctx context.Context, alchemist *models.Alchemist) error {
+	return r.Update(ctx, alchemist)
+}
+
+func (r *alchemistRepository) Update(ctx context.Context, alchemist *models.Alchemist) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	alchemist.ID = r.db.alchemists.assign(alchemist.ID)
+	touch(&alchemist.CreatedAt, &alchemist.UpdatedAt)
+
+	row := *alchemist
+	row.User = nil
+	r.db.alchemists.rows[row.ID] = row
+	return nil
+}
+
+func (r *alchemistRepository) Delete(ctx context.Context, id uint) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	delete(r.db.alchemists.rows, id)
+	return nil
+}
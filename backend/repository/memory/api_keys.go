@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"amestris-backend/models"
+	"amestris-backend/repository"
+)
+
+type apiKeyRepository struct {
+	db *database
+}
+
+func (r *apiKeyRepository) ListByUser(ctx context.Context, userID uint) ([]models.APIKey, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.db.apiKeys.values(func(k models.APIKey) bool { return k.UserID == userID }), nil
+}
+
+func (r *apiKeyRepository) Get(ctx context.Context, id uint) (*models.APIKey, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	key, ok := r.db.apiKeys.rows[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) FindByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, key := range r.db.apiKeys.rows {
+		if key.Prefix != prefix {
+			continue
+		}
+		user, ok := r.db.users.rows[key.UserID]
+		if !ok {
+			return nil, repository.ErrNotFound
+		}
+		key.User = &user
+		return &key, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, existing := range r.db.apiKeys.rows {
+		if existing.Prefix == key.Prefix {
+			return errDuplicate
+		}
+	}
+	key.ID = r.db.apiKeys.assign(key.ID)
+	touch(&key.CreatedAt, nil)
+	stored := *key
+	stored.User = nil
+	r.db.apiKeys.rows[key.ID] = stored
+	return nil
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id uint, at time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if key, ok := r.db.apiKeys.rows[id]; ok && key.RevokedAt == nil {
+		key.RevokedAt = &at
+		r.db.apiKeys.rows[id] = key
+	}
+	return nil
+}
+
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id uint, at time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if key, ok := r.db.apiKeys.rows[id]; ok {
+		key.LastUsedAt = &at
+		r.db.apiKeys.rows[id] = key
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package memory
+
+import (
+	"context"
+
+	"amestris-backend/models"
+	"amestris-backend/query"
+)
+
+type auditRepository struct {
+	db *database
+}
+
+func (r *auditRepository) Page(ctx context.Context, list query.List[models.AuditLog]) (query.Page[models.AuditLog], error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	page := list.Apply(r.db.audits.values(nil))
+	for i := range page.Items {
+		if id := page.Items[i].AlchemistID; id != nil {
+			if _, ok := r.db.alchemists.rows[*id]; ok {
+				alchemist := r.db.alchemist(*id)
+				page.Items[i].Alchemist = &alchemist
+			}
+		}
+	}
+	return page, nil
+}
+
+func (r *auditRepository) Create(ctx context.Context, audit *models.AuditLog) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	audit.ID = r.db.audits.assign(audit.ID)
+	touch(&audit.CreatedAt, nil)
+
+	row := *audit
+	row.Alchemist = nil
+	r.db.audits.rows[row.ID] = row
+	return nil
+}
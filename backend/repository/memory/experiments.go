@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"context"
+
+	"amestris-backend/models"
+	"amestris-backend/query"
+	"amestris-backend/repository"
+)
+
+type experimentRepository struct {
+	db *database
+}
+
+func (r *experimentRepository) List(ctx context.Context) ([]models.ExperimentRequest, error) {
+	return r.list(nil, true), nil
+}
+
+func (r *experimentRepository) Page(ctx context.Context, list query.List[models.ExperimentRequest]) (query.Page[models.ExperimentRequest], error) {
+	return list.Apply(r.list(nil, true)), nil
+}
+
+func (r *experimentRepository) ListByRiskAndStatus(ctx context.Context, riskLevel, status string) ([]models.ExperimentRequest, error) {
+	return r.list(func(e models.ExperimentRequest) bool {
+		return e.RiskLevel == riskLevel && e.Status == status
+	}, false), nil
+}
+
+func (r *experimentRepository) list(keep func(models.ExperimentRequest) bool, preload bool) []models.ExperimentRequest {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	experiments := r.db.experiments.values(keep)
+	if preload {
+		for i := range experiments {
+			experiments[i].Alchemist = r.db.alchemist(experiments[i].AlchemistID)
+		}
+	}
+	return experiments
+}
+
+func (r *experimentRepository) Get(ctx context.Context, id uint) (*models.ExperimentRequest, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	experiment, ok := r.db.experiments.rows[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &experiment, nil
+}
+
+func (r *experimentRepository) Create(ctx context.Context, experiment *models.ExperimentRequest) error {
+	return r.Update(ctx, experiment)
+}
+
+func (r *experimentRepository) Update(ctx context.Context, experiment *models.ExperimentRequest) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	experiment.ID = r.db.experiments.assign(experiment.ID)
+	touch(&experiment.CreatedAt, &experiment.UpdatedAt)
+
+	row := *experiment
+	row.Alchemist = models.Alchemist{}
+	r.db.experiments.rows[row.ID] = row
+	return nil
+}
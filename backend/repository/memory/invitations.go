@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"amestris-backend/models"
+	"amestris-backend/repository"
+)
+
+type invitationRepository struct {
+	db *database
+}
+
+func (r *invitationRepository) ListPending(ctx context.Context) ([]models.Invitation, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	now := time.Now()
+	invitations := r.db.invitations.values(func(i models.Invitation) bool {
+		return i.UsedAt == nil && i.ExpiresAt.After(now)
+	})
+	sort.SliceStable(invitations, func(i, j int) bool { return invitations[i].CreatedAt.After(invitations[j].CreatedAt) })
+	return invitations, nil
+}
+
+func (r *invitationRepository) Get(ctx context.Context, id uint) (*models.Invitation, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	invitation, ok := r.db.invitations.rows[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &invitation, nil
+}
+
+func (r *invitationRepository) FindByHash(ctx context.Context, hash string) (*models.Invitation, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, invitation := range r.db.invitations.rows {
+		if invitation.TokenHash == hash {
+			return &invitation, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *invitationRepository) Create(ctx context.Context, invitation *models.Invitation) error {
+	return r.Update(ctx, invitation)
+}
+
+func (r *invitationRepository) Update(ctx context.Context, invitation *models.Invitation) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	invitation.ID = r.db.invitations.assign(invitation.ID)
+	touch(&invitation.CreatedAt, nil)
+	r.db.invitations.rows[invitation.ID] = *invitation
+	return nil
+}
+
+func (r *invitationRepository) Claim(ctx context.Context, id uint, at time.Time) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	invitation, ok := r.db.invitations.rows[id]
+	if !ok || invitation.UsedAt != nil {
+		return false, nil
+	}
+	invitation.UsedAt = &at
+	r.db.invitations.rows[id] = invitation
+	return true, nil
+}
+
+func (r *invitationRepository) Delete(ctx context.Context, id uint) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	delete(r.db.invitations.rows, id)
+	return nil
+}
@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"context"
+
+	"amestris-backend/models"
+	"amestris-backend/query"
+	"amestris-backend/repository"
+)
+
+type materialRepository struct {
+	db *database
+}
+
+func (r *materialRepository) List(ctx context.Context) ([]models.Material, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	return r.db.materials.values(nil), nil
+}
+
+func (r *materialRepository) Page(ctx context.Context, list query.List[models.Material]) (query.Page[models.Material], error) {
+	materials, _ := r.List(ctx)
+	return list.Apply(materials), nil
+}
+
+func (r *materialRepository) Get(ctx context.Context, id uint) (*models.Material, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	material, ok := r.db.materials.rows[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &material, nil
+}
+
+func (r *materialRepository) FindByName(ctx context.Context, name string) (*models.Material, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, material := range r.db.materials.values(nil) {
+		if material.Name == name {
+			return &material, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *materialRepository) Create(ctx context.Context, material *models.Material) error {
+	return r.Update(ctx, material)
+}
+
+func (r *materialRepository) Update(ctx context.Context, material *models.Material) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, existing := range r.db.materials.rows {
+		if existing.Name == material.Name && id != material.ID {
+			return errDuplicate
+		}
+	}
+
+	material.ID = r.db.materials.assign(material.ID)
+	touch(&material.CreatedAt, &material.UpdatedAt)
+	r.db.materials.rows[material.ID] = *material
+	return nil
+}
+
+func (r *materialRepository) Delete(ctx context.Context, id uint) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	delete(r.db.materials.rows, id)
+	return nil
+}
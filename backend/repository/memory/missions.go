@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+
+	"amestris-backend/models"
+	"amestris-backend/query"
+	"amestris-backend/repository"
+)
+
+type missionRepository struct {
+	db *database
+}
+
+func (r *missionRepository) List(ctx context.Context) ([]models.Mission, error) {
+	return r.list(nil), nil
+}
+
+func (r *missionRepository) Page(ctx context.Context, list query.List[models.Mission]) (query.Page[models.Mission], error) {
+	return list.Apply(r.list(nil)), nil
+}
+
+func (r *missionRepository) list(keep func(models.Mission) bool) []models.Mission {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	missions := r.db.missions.values(keep)
+	for i := range missions {
+		missions[i].Alchemist = r.db.alchemist(missions[i].AlchemistID)
+	}
+	return missions
+}
+
+func (r *missionRepository) Get(ctx context.Context, id uint) (*models.Mission, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	mission, ok := r.db.missions.rows[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	mission.Alchemist = r.db.alchemist(mission.AlchemistID)
+	return &mission, nil
+}
+
+func (r *missionRepository) Create(ctx context.Context, mission *models.Mission) error {
+	return r.Update(ctx, mission)
+}
+
+func (r *missionRepository) Update(ctx context.Context, mission *models.Mission) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	mission.ID = r.db.missions.assign(mission.ID)
+	touch(&mission.CreatedAt, &mission.UpdatedAt)
+
+	row := *mission
+	row.Alchemist = models.Alchemist{}
+	r.db.missions.rows[row.ID] = row
+	return nil
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"amestris-backend/models"
+)
+
+type recoveryCodeRepository struct {
+	db *database
+}
+
+func (r *recoveryCodeRepository) Replace(ctx context.Context, userID uint, hashes []string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, code := range r.db.recoveryCodes.rows {
+		if code.UserID == userID {
+			delete(r.db.recoveryCodes.rows, id)
+		}
+	}
+	for _, hash := range hashes {
+		code := models.RecoveryCode{ID: r.db.recoveryCodes.assign(0), UserID: userID, CodeHash: hash}
+		touch(&code.CreatedAt, nil)
+		r.db.recoveryCodes.rows[code.ID] = code
+	}
+	return nil
+}
+
+func (r *recoveryCodeRepository) Use(ctx context.Context, userID uint, hash string, at time.Time) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, code := range r.db.recoveryCodes.rows {
+		if code.UserID == userID && code.CodeHash == hash && code.UsedAt == nil {
+			code.UsedAt = &at
+			r.db.recoveryCodes.rows[id] = code
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *recoveryCodeRepository) CountUnused(ctx context.Context, userID uint) (int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	count := 0
+	for _, code := range r.db.recoveryCodes.rows {
+		if code.UserID == userID && code.UsedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"amestris-backend/models"
+	"amestris-backend/repository"
+)
+
+type roleRepository struct {
+	db *database
+}
+
+func (r *roleRepository) List(ctx context.Context) ([]models.Role, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	roles := make([]models.Role, 0, len(r.db.roles))
+	for _, role := range r.db.roles {
+		role.Permissions = slices.Clone(role.Permissions)
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles, nil
+}
+
+func (r *roleRepository) Get(ctx context.Context, name string) (*models.Role, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	role, ok := r.db.roles[name]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	role.Permissions = slices.Clone(role.Permissions)
+	return &role, nil
+}
+
+func (r *roleRepository) Create(ctx context.Context, role *models.Role) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.roles[role.Name]; ok {
+		return errDuplicate
+	}
+	touch(&role.CreatedAt, &role.UpdatedAt)
+	stored := *role
+	stored.Permissions = slices.Clone(role.Permissions)
+	r.db.roles[role.Name] = stored
+	return nil
+}
+
+func (r *roleRepository) Update(ctx context.Context, role *models.Role) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	touch(&role.CreatedAt, &role.UpdatedAt)
+	stored := *role
+	stored.Permissions = slices.Clone(role.Permissions)
+	r.db.roles[role.Name] = stored
+	return nil
+}
+
+func (r *roleRepository) Delete(ctx context.Context, name string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	delete(r.db.roles, name)
+	return nil
+}
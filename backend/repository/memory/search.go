@@ -0,0 +1,144 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+
+	"amestris-backend/models"
+	"amestris-backend/repository"
+)
+
+type searchRepository struct {
+	db *database
+}
+
+type searchDocument struct {
+	typ   string
+	id    uint
+	title string
+	body  []string
+}
+
+// Search es una aproximación a la búsqueda de Postgres: un documento
+// coincide si contiene todas las palabras, sin stemming ni operadores, y
+// las coincidencias en el título puntúan más.
+func (r *searchRepository) Search(ctx context.Context, search repository.SearchQuery) ([]models.SearchHit, error) {
+	terms := searchTerms(search.Text)
+	hits := []models.SearchHit{}
+	if len(terms) == 0 {
+		return hits, nil
+	}
+
+	for _, doc := range r.documents(search) {
+		rank, ok := searchRank(doc, terms)
+		if !ok {
+			continue
+		}
+		hits = append(hits, models.SearchHit{
+			Type:      doc.typ,
+			ID:        doc.id,
+			Title:     doc.title,
+			Highlight: highlight(strings.Join(slices.DeleteFunc(doc.body, func(s string) bool { return s == "" }), " · "), terms),
+			Rank:      rank,
+		})
+	}
+	slices.SortFunc(hits, func(a, b models.SearchHit) int {
+		return cmp.Or(cmp.Compare(b.Rank, a.Rank), strings.Compare(a.Type, b.Type), cmp.Compare(a.ID, b.ID))
+	})
+	return hits[:min(len(hits), search.Limit)], nil
+}
+
+func (r *searchRepository) documents(search repository.SearchQuery) []searchDocument {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var docs []searchDocument
+	for _, typ := range search.Types {
+		switch typ {
+		case "alchemist":
+			for _, a := range r.db.alchemists.values(nil) {
+				docs = append(docs, searchDocument{typ, a.ID, a.Name, []string{a.Name, a.Title, a.Specialty}})
+			}
+		case "mission":
+			for _, m := range r.db.missions.values(nil) {
+				docs = append(docs, searchDocument{typ, m.ID, m.Title, []string{m.Title, m.Description}})
+			}
+		case "experiment":
+			for _, e := range r.db.experiments.values(nil) {
+				if search.ExperimentsOf != nil && e.AlchemistID != *search.ExperimentsOf {
+					continue
+				}
+				docs = append(docs, searchDocument{typ, e.ID, e.Title, []string{e.Title, e.Objective, e.Description}})
+			}
+		case "material":
+			for _, m := range r.db.materials.values(nil) {
+				docs = append(docs, searchDocument{typ, m.ID, m.Name, []string{m.Name}})
+			}
+		}
+	}
+	return docs
+}
+
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func searchRank(doc searchDocument, terms []string) (float64, bool) {
+	title := strings.ToLower(doc.title)
+	body := strings.ToLower(strings.Join(doc.body, " "))
+	rank := 0.0
+	for _, term := range terms {
+		if !strings.Contains(body, term) {
+			return 0, false
+		}
+		rank += 0.1 * float64(strings.Count(body, term))
+		if strings.Contains(title, term) {
+			rank++
+		}
+	}
+	return rank, true
+}
+
+// highlight escapa text como HTML y envuelve en <mark> cada aparición de
+// los términos, sin distinguir mayúsculas.
+func highlight(text string, terms []string) string {
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		// Algunas letras cambian de longitud en UTF-8 al pasar a minúsculas
+		return html.EscapeString(text)
+	}
+	marked := make([]bool, len(text))
+	for _, term := range terms {
+		for start := 0; ; {
+			i := strings.Index(lower[start:], term)
+			if i < 0 {
+				break
+			}
+			for j := start + i; j < start+i+len(term); j++ {
+				marked[j] = true
+			}
+			start += i + len(term)
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		j := i
+		for j < len(text) && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			b.WriteString("<mark>" + html.EscapeString(text[i:j]) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(text[i:j]))
+		}
+		i = j
+	}
+	return b.String()
+}
@@ -0,0 +1,132 @@
+// Package memory implementa los repositorios en memoria. Está pensado para
+// pruebas de handlers y desarrollo local sin Postgres.
+package memory
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"amestris-backend/models"
+	"amestris-backend/repository"
+)
+
+var errDuplicate = errors.New("memory: clave única duplicada")
+
+// table guarda las filas de un agregado indexadas por ID.
+type table[T any] struct {
+	rows map[uint]T
+	last uint
+}
+
+func newTable[T any]() table[T] {
+	return table[T]{rows: make(map[uint]T)}
+}
+
+// assign devuelve el ID a usar para una fila nueva o existente.
+func (t *table[T]) assign(id uint) uint {
+	if id == 0 {
+		t.last++
+		return t.last
+	}
+	if id > t.last {
+		t.last = id
+	}
+	return id
+}
+
+// values devuelve las filas ordenadas por ID, como lo haría Postgres por
+// defecto para tablas pequeñas.
+func (t *table[T]) values(keep func(T) bool) []T {
+	ids := make([]uint, 0, len(t.rows))
+	for id := range t.rows {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	result := make([]T, 0, len(ids))
+	for _, id := range ids {
+		if row := t.rows[id]; keep == nil || keep(row) {
+			result = append(result, row)
+		}
+	}
+	return result
+}
+
+type database struct {
+	mu             sync.RWMutex
+	alchemists     table[models.Alchemist]
+	missions       table[models.Mission]
+	experiments    table[models.ExperimentRequest]
+	materials      table[models.Material]
+	audits         table[models.AuditLog]
+	transmutations table[models.TransmutationLog]
+	users          table[models.User]
+	invitations    table[models.Invitation]
+	recoveryCodes  table[models.RecoveryCode]
+	apiKeys        table[models.APIKey]
+	roles          map[string]models.Role
+	refreshTokens  table[models.RefreshToken]
+	revokedTokens  map[string]models.RevokedToken
+}
+
+// NewStore crea un Store vacío cuyos repositorios comparten los mismos datos.
+func NewStore() *repository.Store {
+	db := &database{
+		alchemists:     newTable[models.Alchemist](),
+		missions:       newTable[models.Mission](),
+		experiments:    newTable[models.ExperimentRequest](),
+		materials:      newTable[models.Material](),
+		audits:         newTable[models.AuditLog](),
+		transmutations: newTable[models.TransmutationLog](),
+		users:          newTable[models.User](),
+		invitations:    newTable[models.Invitation](),
+		recoveryCodes:  newTable[models.RecoveryCode](),
+		apiKeys:        newTable[models.APIKey](),
+		roles:          make(map[string]models.Role),
+		refreshTokens:  newTable[models.RefreshToken](),
+		revokedTokens:  make(map[string]models.RevokedToken),
+	}
+
+	// Igual que la migración 0011_roles
+	for _, role := range models.DefaultRoles() {
+		touch(&role.CreatedAt, &role.UpdatedAt)
+		db.roles[role.Name] = role
+	}
+
+	return &repository.Store{
+		Alchemists:     &alchemistRepository{db: db},
+		Missions:       &missionRepository{db: db},
+		Experiments:    &experimentRepository{db: db},
+		Materials:      &materialRepository{db: db},
+		Audits:         &auditRepository{db: db},
+		Transmutations: &transmutationRepository{db: db},
+		Users:          &userRepository{db: db},
+		Invitations:    &invitationRepository{db: db},
+		RecoveryCodes:  &recoveryCodeRepository{db: db},
+		APIKeys:        &apiKeyRepository{db: db},
+		Roles:          &roleRepository{db: db},
+		RefreshTokens:  &refreshTokenRepository{db: db},
+		RevokedTokens:  &revokedTokenRepository{db: db},
+		Search:         &searchRepository{db: db},
+	}
+}
+
+func touch(createdAt, updatedAt *time.Time) {
+	now := time.Now()
+	if createdAt != nil && createdAt.IsZero() {
+		*createdAt = now
+	}
+	if updatedAt != nil {
+		*updatedAt = now
+	}
+}
+
+// alchemist devuelve el alquimista con el ID dado sin relaciones cargadas.
+// Debe llamarse con el mutex tomado.
+func (db *database) alchemist(id uint) models.Alchemist {
+	alchemist := db.alchemists.rows[id]
+	alchemist.User = nil
+	return alchemist
+}
@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"amestris-backend/models"
+	"amestris-backend/repository"
+)
+
+type refreshTokenRepository struct {
+	db *database
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, existing := range r.db.refreshTokens.rows {
+		if existing.TokenHash == token.TokenHash {
+			return errDuplicate
+		}
+	}
+
+	token.ID = r.db.refreshTokens.assign(token.ID)
+	touch(&token.CreatedAt, nil)
+	r.db.refreshTokens.rows[token.ID] = *token
+	return nil
+}
+
+func (r *refreshTokenRepository) FindByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, token := range r.db.refreshTokens.rows {
+		if token.TokenHash == hash {
+			return &token, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *refreshTokenRepository) ListUnexpired(ctx context.Context, userID uint, sessionID string) ([]models.RefreshToken, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	now := time.Now()
+	return r.db.refreshTokens.values(func(t models.RefreshToken) bool {
+		return t.UserID == userID && t.ExpiresAt.After(now) && (sessionID == "" || t.SessionID == sessionID)
+	}), nil
+}
+
+func (r *refreshTokenRepository) Revoke(ctx context.Context, ids []uint, at time.Time) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var revoked int64
+	for _, id := range ids {
+		token, ok := r.db.refreshTokens.rows[id]
+		if !ok || token.RevokedAt != nil {
+			continue
+		}
+		token.RevokedAt = &at
+		r.db.refreshTokens.rows[id] = token
+		revoked++
+	}
+	return revoked, nil
+}
+
+func (r *refreshTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var deleted int64
+	for id, token := range r.db.refreshTokens.rows {
+		if token.ExpiresAt.Before(before) {
+			delete(r.db.refreshTokens.rows, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+type revokedTokenRepository struct {
+	db *database
+}
+
+func (r *revokedTokenRepository) Add(ctx context.Context, token *models.RevokedToken) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.revokedTokens[token.JTI]; !ok {
+		r.db.revokedTokens[token.JTI] = *token
+	}
+	return nil
+}
+
+func (r *revokedTokenRepository) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	_, ok := r.db.revokedTokens[jti]
+	return ok, nil
+}
+
+func (r *revokedTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var deleted int64
+	for jti, token := range r.db.revokedTokens {
+		if token.ExpiresAt.Before(before) {
+			delete(r.db.revokedTokens, jti)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
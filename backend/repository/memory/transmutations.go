@@ -0,0 +1,34 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"amestris-backend/models"
+)
+
+type transmutationRepository struct {
+	db *database
+}
+
+func (r *transmutationRepository) ListSince(ctx context.Context, since time.Time) ([]models.TransmutationLog, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return r.db.transmutations.values(func(t models.TransmutationLog) bool {
+		return t.CreatedAt.After(since)
+	}), nil
+}
+
+func (r *transmutationRepository) Create(ctx context.Context, log *models.TransmutationLog) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	log.ID = r.db.transmutations.assign(log.ID)
+	touch(&log.CreatedAt, nil)
+
+	row := *log
+	row.Alchemist = models.Alchemist{}
+	r.db.transmutations.rows[row.ID] = row
+	return nil
+}
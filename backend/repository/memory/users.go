@@ -0,0 +1,226 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"amestris-backend/models"
+	"amestris-backend/repository"
+)
+
+type userRepository struct {
+	db *database
+}
+
+func (r *userRepository) List(ctx context.Context, filter repository.UserFilter) ([]models.User, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	users := r.db.users.values(func(u models.User) bool {
+		if filter.Username != "" && !strings.Contains(strings.ToLower(u.Username), strings.ToLower(filter.Username)) {
+			return false
+		}
+		if filter.Role != "" && u.Role != filter.Role {
+			return false
+		}
+		if filter.Disabled != nil && u.Disabled != *filter.Disabled {
+			return false
+		}
+		if filter.Pending != nil && u.Pending != *filter.Pending {
+			return false
+		}
+		if filter.AlchemistID != nil && (u.AlchemistID == nil || *u.AlchemistID != *filter.AlchemistID) {
+			return false
+		}
+		if filter.ServiceAccount != nil && u.ServiceAccount != *filter.ServiceAccount {
+			return false
+		}
+		return true
+	})
+	for i, user := range users {
+		if user.AlchemistID != nil {
+			if _, ok := r.db.alchemists.rows[*user.AlchemistID]; ok {
+				alchemist := r.db.alchemist(*user.AlchemistID)
+				users[i].Alchemist = &alchemist
+			}
+		}
+	}
+	return users, nil
+}
+
+func (r *userRepository) Get(ctx context.Context, id uint) (*models.User, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	user, ok := r.db.users.rows[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	if user.AlchemistID != nil {
+		if _, ok := r.db.alchemists.rows[*user.AlchemistID]; ok {
+			alchemist := r.db.alchemist(*user.AlchemistID)
+			user.Alchemist = &alchemist
+		}
+	}
+	return &user, nil
+}
+
+func (r *userRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, user := range r.db.users.values(nil) {
+		if user.Username == username {
+			return &user, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *userRepository) FindByOIDCSubject(ctx context.Context, issuer, subject string) (*models.User, error) {
+	r.db.mu.RLock()
+	var id uint
+	for _, user := range r.db.users.values(nil) {
+		if subject != "" && user.OIDCIssuer == issuer && user.OIDCSubject == subject {
+			id = user.ID
+			break
+		}
+	}
+	r.db.mu.RUnlock()
+
+	if id == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return r.Get(ctx, id)
+}
+
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	return r.Update(ctx, user)
+}
+
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, existing := range r.db.users.rows {
+		if existing.Username == user.Username && id != user.ID {
+			return errDuplicate
+		}
+	}
+
+	user.ID = r.db.users.assign(user.ID)
+	touch(&user.CreatedAt, &user.UpdatedAt)
+
+	row := *user
+	row.Alchemist = nil
+	r.db.users.rows[row.ID] = row
+	return nil
+}
+
+func (r *userRepository) RecordLoginFailure(ctx context.Context, id uint, at time.Time) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	user, ok := r.db.users.rows[id]
+	if !ok {
+		return 0, repository.ErrNotFound
+	}
+	user.FailedLogins++
+	user.LastFailedLoginAt = &at
+	r.db.users.rows[id] = user
+	return user.FailedLogins, nil
+}
+
+func (r *userRepository) RecordLoginSuccess(ctx context.Context, id uint, at time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	user, ok := r.db.users.rows[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	user.FailedLogins = 0
+	user.LockedUntil = nil
+	user.LastLoginAt = &at
+	r.db.users.rows[id] = user
+	return nil
+}
+
+func (r *userRepository) SetLockedUntil(ctx context.Context, id uint, until *time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	user, ok := r.db.users.rows[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	user.LockedUntil = until
+	if until == nil {
+		user.FailedLogins = 0
+	}
+	r.db.users.rows[id] = user
+	return nil
+}
+
+func (r *userRepository) UpdateRole(ctx context.Context, id uint, role string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	user, ok := r.db.users.rows[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	user.Role = role
+	r.db.users.rows[id] = user
+	return nil
+}
+
+func (r *userRepository) LinkAlchemist(ctx context.Context, id, alchemistID uint) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	user, ok := r.db.users.rows[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	user.AlchemistID = &alchemistID
+	r.db.users.rows[id] = user
+	return nil
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, id uint, hash string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	user, ok := r.db.users.rows[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	user.Password = hash
+	r.db.users.rows[id] = user
+	return nil
+}
+
+func (r *userRepository) Delete(ctx context.Context, id uint) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.users.rows, id)
+	for tokenID, token := range r.db.refreshTokens.rows {
+		if token.UserID == id {
+			delete(r.db.refreshTokens.rows, tokenID)
+		}
+	}
+	for keyID, key := range r.db.apiKeys.rows {
+		if key.UserID == id {
+			delete(r.db.apiKeys.rows, keyID)
+		}
+	}
+	for codeID, code := range r.db.recoveryCodes.rows {
+		if code.UserID == id {
+			delete(r.db.recoveryCodes.rows, codeID)
+		}
+	}
+	return nil
+}
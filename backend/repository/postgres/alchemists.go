@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+
+	"amestris-backend/models"
+	"amestris-backend/query"
+
+	"gorm.io/gorm"
+)
+
+type alchemistRepository struct {
+	db *gorm.DB
+}
+
+func (r *alchemistRepository) List(ctx context.Context) ([]models.Alchemist, error) {
+	var alchemists []models.Alchemist
+	err := r.db.WithContext(ctx).Find(&alchemists).Error
+	return alchemists, err
+}
+
+func (r *alchemistRepository) Page(ctx context.Context, list query.List[models.Alchemist]) (query.Page[models.Alchemist], error) {
+	return page(r.db.WithContext(ctx), list)
+}
+
+func (r *alchemistRepository) Get(ctx context.Context, id uint) (*models.Alchemist, error) {
+	var alchemist models.Alchemist
+	if err := r.db.WithContext(ctx).Preload("User").First(&alchemist, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &alchemist, nil
+}
+
+func (r *alchemistRepository) FindByName(ctx context.Context, name string) (*models.Alchemist, error) {
+	var alchemist models.Alchemist
+	if err := r.db.WithContext(ctx).Where("name = ?", name).First(&alchemist).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &alchemist, nil
+}
+
+func (r *alchemistRepository) Create(ctx context.Context, alchemist *models.Alchemist) error {
+	return r.db.WithContext(ctx).Create(alchemist).Error
+}
+
+func (r *alchemistRepository) Update(ctx context.Context, alchemist *models.Alchemist) error {
+	return r.db.WithContext(ctx).Omit("User").Save(alchemist).Error
+}
+
+func (r *alchemistRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Alchemist{}, id).Error
+}
@@ -0,0 +1,22 @@
+package postgres
+
+import (
+	"context"
+
+	"amestris-backend/models"
+	"amestris-backend/query"
+
+	"gorm.io/gorm"
+)
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func (r *auditRepository) Page(ctx context.Context, list query.List[models.AuditLog]) (query.Page[models.AuditLog], error) {
+	return page(r.db.WithContext(ctx), list, "Alchemist")
+}
+
+func (r *auditRepository) Create(ctx context.Context, audit *models.AuditLog) error {
+	return r.db.WithContext(ctx).Omit("Alchemist").Create(audit).Error
+}
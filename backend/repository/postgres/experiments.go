@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+
+	"amestris-backend/models"
+	"amestris-backend/query"
+
+	"gorm.io/gorm"
+)
+
+type experimentRepository struct {
+	db *gorm.DB
+}
+
+func (r *experimentRepository) List(ctx context.Context) ([]models.ExperimentRequest, error) {
+	var experiments []models.ExperimentRequest
+	err := r.db.WithContext(ctx).Preload("Alchemist").Find(&experiments).Error
+	return experiments, err
+}
+
+func (r *experimentRepository) Page(ctx context.Context, list query.List[models.ExperimentRequest]) (query.Page[models.ExperimentRequest], error) {
+	return page(r.db.WithContext(ctx), list, "Alchemist")
+}
+
+func (r *experimentRepository) ListByRiskAndStatus(ctx context.Context, riskLevel, status string) ([]models.ExperimentRequest, error) {
+	var experiments []models.ExperimentRequest
+	err := r.db.WithContext(ctx).Where("risk_level = ? AND status = ?", riskLevel, status).Find(&experiments).Error
+	return experiments, err
+}
+
+func (r *experimentRepository) Get(ctx context.Context, id uint) (*models.ExperimentRequest, error) {
+	var experiment models.ExperimentRequest
+	if err := r.db.WithContext(ctx).First(&experiment, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &experiment, nil
+}
+
+func (r *experimentRepository) Create(ctx context.Context, experiment *models.ExperimentRequest) error {
+	return r.db.WithContext(ctx).Omit("Alchemist").Create(experiment).Error
+}
+
+func (r *experimentRepository) Update(ctx context.Context, experiment *models.ExperimentRequest) error {
+	return r.db.WithContext(ctx).Omit("Alchemist").Save(experiment).Error
+}
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+
+	"amestris-backend/models"
+	"amestris-backend/query"
+
+	"gorm.io/gorm"
+)
+
+type materialRepository struct {
+	db *gorm.DB
+}
+
+func (r *materialRepository) List(ctx context.Context) ([]models.Material, error) {
+	var materials []models.Material
+	err := r.db.WithContext(ctx).Find(&materials).Error
+	return materials, err
+}
+
+func (r *materialRepository) Page(ctx context.Context, list query.List[models.Material]) (query.Page[models.Material], error) {
+	return page(r.db.WithContext(ctx), list)
+}
+
+func (r *materialRepository) Get(ctx context.Context, id uint) (*models.Material, error) {
+	var material models.Material
+	if err := r.db.WithContext(ctx).First(&material, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &material, nil
+}
+
+func (r *materialRepository) FindByName(ctx context.Context, name string) (*models.Material, error) {
+	var material models.Material
+	if err := r.db.WithContext(ctx).Where("name = ?", name).First(&material).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &material, nil
+}
+
+func (r *materialRepository) Create(ctx context.Context, material *models.Material) error {
+	return r.db.WithContext(ctx).Create(material).Error
+}
+
+func (r *materialRepository) Update(ctx context.Context, material *models.Material) error {
+	return r.db.WithContext(ctx).Save(material).Error
+}
+
+func (r *materialRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Material{}, id).Error
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"context"
+
+	"amestris-backend/models"
+	"amestris-backend/query"
+
+	"gorm.io/gorm"
+)
+
+type missionRepository struct {
+	db *gorm.DB
+}
+
+func (r *missionRepository) List(ctx context.Context) ([]models.Mission, error) {
+	var missions []models.Mission
+	err := r.db.WithContext(ctx).Preload("Alchemist").Find(&missions).Error
+	return missions, err
+}
+
+func (r *missionRepository) Page(ctx context.Context, list query.List[models.Mission]) (query.Page[models.Mission], error) {
+	return page(r.db.WithContext(ctx), list, "Alchemist")
+}
+
+func (r *missionRepository) Get(ctx context.Context, id uint) (*models.Mission, error) {
+	var mission models.Mission
+	if err := r.db.WithContext(ctx).Preload("Alchemist").First(&mission, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &mission, nil
+}
+
+func (r *missionRepository) Create(ctx context.Context, mission *models.Mission) error {
+	return r.db.WithContext(ctx).Omit("Alchemist").Create(mission).Error
+}
+
+func (r *missionRepository) Update(ctx context.Context, mission *models.Mission) error {
+	return r.db.WithContext(ctx).Omit("Alchemist").Save(mission).Error
+}
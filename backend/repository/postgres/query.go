@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"strings"
+
+	"amestris-backend/query"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// page aplica los filtros, el orden y la paginación de list a la consulta
+// base. Cuenta el total antes de paginar y carga hasta list.Limit+1 filas
+// para saber si hay más.
+func page[T any](base *gorm.DB, list query.List[T], preloads ...string) (query.Page[T], error) {
+	for _, condition := range list.Conditions {
+		base = base.Where(clause.IN{Column: clause.Column{Name: condition.Column}, Values: condition.Values})
+	}
+	base = base.Session(&gorm.Session{})
+
+	var total int64
+	if err := base.Model(new(T)).Count(&total).Error; err != nil {
+		return query.Page[T]{}, err
+	}
+
+	find := base
+	for _, preload := range preloads {
+		find = find.Preload(preload)
+	}
+	if list.Keyset() {
+		expr, args := keyset(list.Orders, list.After)
+		find = find.Where(expr, args...)
+	}
+	for _, order := range list.Orders {
+		find = find.Order(clause.OrderByColumn{Column: clause.Column{Name: order.Column}, Desc: order.Desc})
+	}
+
+	var rows []T
+	if err := find.Offset(list.Offset).Limit(list.Limit + 1).Find(&rows).Error; err != nil {
+		return query.Page[T]{}, err
+	}
+	return list.NewPage(rows, total), nil
+}
+
+// keyset devuelve la condición de las filas posteriores al cursor:
+// (a > x) OR (a = x AND b > y) OR ..., con < en las columnas descendentes.
+func keyset(orders []query.Order, after []any) (string, []any) {
+	var alternatives []string
+	var args []any
+	for i, order := range orders {
+		var parts []string
+		for j := range i {
+			parts = append(parts, orders[j].Column+" = ?")
+			args = append(args, after[j])
+		}
+		operator := " > ?"
+		if order.Desc {
+			operator = " < ?"
+		}
+		parts = append(parts, order.Column+operator)
+		args = append(args, after[i])
+		alternatives = append(alternatives, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"html"
+	"strings"
+
+	"amestris-backend/models"
+	"amestris-backend/repository"
+
+	"gorm.io/gorm"
+)
+
+type searchRepository struct {
+	db *gorm.DB
+}
+
+// searchSources son las consultas de cada tipo de resultado. body es el
+// texto del que ts_headline extrae el fragmento resaltado, sin los campos
+// vacíos.
+var searchSources = map[string]string{
+	"alchemist": `SELECT 'alchemist' AS type, id, name AS title,
+		concat_ws(' · ', name, nullif(title, ''), nullif(specialty, '')) AS body,
+		ts_rank_cd(search_vector, q.query) AS rank
+		FROM alchemists, q WHERE search_vector @@ q.query`,
+	"mission": `SELECT 'mission' AS type, id, title,
+		concat_ws(' · ', title, nullif(description, '')) AS body,
+		ts_rank_cd(search_vector, q.query) AS rank
+		FROM missions, q WHERE search_vector @@ q.query`,
+	"experiment": `SELECT 'experiment' AS type, id, title,
+		concat_ws(' · ', title, nullif(objective, ''), nullif(description, '')) AS body,
+		ts_rank_cd(search_vector, q.query) AS rank
+		FROM experiment_requests, q WHERE search_vector @@ q.query`,
+	"material": `SELECT 'material' AS type, id, name AS title,
+		name AS body,
+		ts_rank_cd(search_vector, q.query) AS rank
+		FROM materials, q WHERE search_vector @@ q.query`,
+}
+
+// Marcas de ts_headline. Son caracteres de control para poder escapar el
+// texto antes de convertirlas en <mark>.
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+var highlightOptions = "StartSel=" + highlightStart + ", StopSel=" + highlightStop +
+	", MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=\" … \""
+
+// Search interpreta el texto con websearch_to_tsquery: admite frases entre
+// comillas, "or" y términos excluidos con "-". ts_headline se calcula solo
+// para las filas de la página.
+func (r *searchRepository) Search(ctx context.Context, search repository.SearchQuery) ([]models.SearchHit, error) {
+	var parts []string
+	for _, typ := range search.Types {
+		source := searchSources[typ]
+		if typ == "experiment" && search.ExperimentsOf != nil {
+			source += " AND alchemist_id = @alchemist"
+		}
+		parts = append(parts, source)
+	}
+	if len(parts) == 0 {
+		return []models.SearchHit{}, nil
+	}
+
+	sql := `WITH q AS (SELECT websearch_to_tsquery('spanish', @text) AS query),
+		hits AS (` + strings.Join(parts, " UNION ALL ") + `)
+		SELECT type, id, title, rank, ts_headline('spanish', body, q.query, @options) AS highlight
+		FROM hits, q
+		ORDER BY rank DESC, type, id
+		LIMIT @limit`
+	args := map[string]any{"text": search.Text, "options": highlightOptions, "limit": search.Limit}
+	if search.ExperimentsOf != nil {
+		args["alchemist"] = *search.ExperimentsOf
+	}
+
+	hits := []models.SearchHit{}
+	if err := r.db.WithContext(ctx).Raw(sql, args).Scan(&hits).Error; err != nil {
+		return nil, err
+	}
+	for i := range hits {
+		hits[i].Highlight = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>").
+			Replace(html.EscapeString(hits[i].Highlight))
+	}
+	return hits, nil
+}
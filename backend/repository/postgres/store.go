@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"errors"
+
+	"amestris-backend/repository"
+
+	"gorm.io/gorm"
+)
+
+// NewStore construye los repositorios respaldados por GORM/Postgres.
+func NewStore(db *gorm.DB) *repository.Store {
+	return &repository.Store{
+		Alchemists:     &alchemistRepository{db: db},
+		Missions:       &missionRepository{db: db},
+		Experiments:    &experimentRepository{db: db},
+		Materials:      &materialRepository{db: db},
+		Audits:         &auditRepository{db: db},
+		Transmutations: &transmutationRepository{db: db},
+		Users:          &userRepository{db: db},
+		Invitations:    &invitationRepository{db: db},
+		RecoveryCodes:  &recoveryCodeRepository{db: db},
+		APIKeys:        &apiKeyRepository{db: db},
+		Roles:          &roleRepository{db: db},
+		RefreshTokens:  &refreshTokenRepository{db: db},
+		RevokedTokens:  &revokedTokenRepository{db: db},
+		Search:         &searchRepository{db: db},
+	}
+}
+
+func translateError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return repository.ErrNotFound
+	}
+	return err
+}
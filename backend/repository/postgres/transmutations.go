@@ -0,0 +1,24 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"amestris-backend/models"
+
+	"gorm.io/gorm"
+)
+
+type transmutationRepository struct {
+	db *gorm.DB
+}
+
+func (r *transmutationRepository) ListSince(ctx context.Context, since time.Time) ([]models.TransmutationLog, error) {
+	var logs []models.TransmutationLog
+	err := r.db.WithContext(ctx).Where("created_at > ?", since).Find(&logs).Error
+	return logs, err
+}
+
+func (r *transmutationRepository) Create(ctx context.Context, log *models.TransmutationLog) error {
+	return r.db.WithContext(ctx).Omit("Alchemist").Create(log).Error
+}
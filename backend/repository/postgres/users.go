@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"amestris-backend/models"
+	"amestris-backend/repository"
+
+	"gorm.io/gorm"
+)
+
+type userRepository struct {
+	db *gorm.DB
+}
+
+func (r *userRepository) List(ctx context.Context, filter repository.UserFilter) ([]models.User, error) {
+	query := r.db.WithContext(ctx).Preload("Alchemist").Order("id")
+
+	if filter.Username != "" {
+		query = query.Where("username ILIKE ?", "%"+filter.Username+"%")
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Disabled != nil {
+		query = query.Where("disabled = ?", *filter.Disabled)
+	}
+	if filter.Pending != nil {
+		query = query.Where("pending = ?", *filter.Pending)
+	}
+	if filter.AlchemistID != nil {
+		query = query.Where("alchemist_id = ?", *filter.AlchemistID)
+	}
+	if filter.ServiceAccount != nil {
+		query = query.Where("service_account = ?", *filter.ServiceAccount)
+	}
+
+	var users []models.User
+	err := query.Find(&users).Error
+	return users, err
+}
+
+func (r *userRepository) Get(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Preload("Alchemist").First(&user, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) FindByOIDCSubject(ctx context.Context, issuer, subject string) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Preload("Alchemist").
+		Where("oidc_issuer = ? AND oidc_subject = ?", issuer, subject).First(&user).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Omit("Alchemist").Create(user).Error
+}
+
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Omit("Alchemist").Save(user).Error
+}
+
+func (r *userRepository) RecordLoginFailure(ctx context.Context, id uint, at time.Time) (int, error) {
+	var attempts int
+	err := r.db.WithContext(ctx).Raw(
+		`UPDATE users SET failed_logins = failed_logins + 1, last_failed_login_at = ? WHERE id = ? RETURNING failed_logins`,
+		at, id).Scan(&attempts).Error
+	return attempts, err
+}
+
+func (r *userRepository) RecordLoginSuccess(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Updates(map[string]any{
+		"failed_logins": 0,
+		"locked_until":  nil,
+		"last_login_at": at,
+	}).Error
+}
+
+func (r *userRepository) SetLockedUntil(ctx context.Context, id uint, until *time.Time) error {
+	updates := map[string]any{"locked_until": until}
+	if until == nil {
+		updates["failed_logins"] = 0
+	}
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *userRepository) UpdateRole(ctx context.Context, id uint, role string) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("role", role).Error
+}
+
+func (r *userRepository) LinkAlchemist(ctx context.Context, id, alchemistID uint) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("alchemist_id", alchemistID).Error
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, id uint, hash string) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("password", hash).Error
+}
+
+func (r *userRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.User{}, id).Error
+}
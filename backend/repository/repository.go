@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"amestris-backend/models"
+	"amestris-backend/query"
+)
+
+// ErrNotFound se devuelve cuando el registro solicitado no existe.
+var ErrNotFound = errors.New("registro no encontrado")
+
+type AlchemistRepository interface {
+	List(ctx context.Context) ([]models.Alchemist, error)
+	Page(ctx context.Context, list query.List[models.Alchemist]) (query.Page[models.Alchemist], error)
+	// Get carga el alquimista junto con su usuario asociado.
+	Get(ctx context.Context, id uint) (*models.Alchemist, error)
+	FindByName(ctx context.Context, name string) (*models.Alchemist, error)
+	Create(ctx context.Context, alchemist *models.Alchemist) error
+	Update(ctx context.Context, alchemist *models.Alchemist) error
+	Delete(ctx context.Context, id uint) error
+}
+
+type MissionRepository interface {
+	List(ctx context.Context) ([]models.Mission, error)
+	// Page carga cada misión junto con su alquimista.
+	Page(ctx context.Context, list query.List[models.Mission]) (query.Page[models.Mission], error)
+	Get(ctx context.Context, id uint) (*models.Mission, error)
+	Create(ctx context.Context, mission *models.Mission) error
+	Update(ctx context.Context, mission *models.Mission) error
+}
+
+type ExperimentRepository interface {
+	List(ctx context.Context) ([]models.ExperimentRequest, error)
+	// Page carga cada solicitud junto con su alquimista.
+	Page(ctx context.Context, list query.List[models.ExperimentRequest]) (query.Page[models.ExperimentRequest], error)
+	ListByRiskAndStatus(ctx context.Context, riskLevel, status string) ([]models.ExperimentRequest, error)
+	Get(ctx context.Context, id uint) (*models.ExperimentRequest, error)
+	Create(ctx context.Context, experiment *models.ExperimentRequest) error
+	Update(ctx context.Context, experiment *models.ExperimentRequest) error
+}
+
+type MaterialRepository interface {
+	List(ctx context.Context) ([]models.Material, error)
+	Page(ctx context.Context, list query.List[models.Material]) (query.Page[models.Material], error)
+	Get(ctx context.Context, id uint) (*models.Material, error)
+	FindByName(ctx context.Context, name string) (*models.Material, error)
+	Create(ctx context.Context, material *models.Material) error
+	Update(ctx context.Context, material *models.Material) error
+	Delete(ctx context.Context, id uint) error
+}
+
+type AuditRepository interface {
+	// Page carga cada registro junto con su alquimista, si lo tiene.
+	Page(ctx context.Context, list query.List[models.AuditLog]) (query.Page[models.AuditLog], error)
+	Create(ctx context.Context, audit *models.AuditLog) error
+}
+
+type TransmutationRepository interface {
+	ListSince(ctx context.Context, since time.Time) ([]models.TransmutationLog, error)
+	Create(ctx context.Context, log *models.TransmutationLog) error
+}
+
+// UserFilter restringe los usuarios devueltos por UserRepository.List. Los
+// campos vacíos no filtran; Username busca por coincidencia parcial.
+type UserFilter struct {
+	Username    string
+	Role        string
+	Disabled    *bool
+	Pending     *bool
+	AlchemistID *uint
+	// ServiceAccount separa las cuentas de servicio de las personas.
+	ServiceAccount *bool
+}
+
+type UserRepository interface {
+	List(ctx context.Context, filter UserFilter) ([]models.User, error)
+	// Get carga el usuario junto con su alquimista asociado.
+	Get(ctx context.Context, id uint) (*models.User, error)
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	// FindByOIDCSubject carga el usuario creado para sub del emisor issuer,
+	// junto con su alquimista asociado.
+	FindByOIDCSubject(ctx context.Context, issuer, subject string) (*models.User, error)
+	Create(ctx context.Context, user *models.User) error
+	Update(ctx context.Context, user *models.User) error
+	Delete(ctx context.Context, id uint) error
+	// RecordLoginFailure incrementa de forma atómica los intentos fallidos y
+	// devuelve el nuevo total.
+	RecordLoginFailure(ctx context.Context, id uint, at time.Time) (int, error)
+	// RecordLoginSuccess reinicia los intentos fallidos y el bloqueo.
+	RecordLoginSuccess(ctx context.Context, id uint, at time.Time) error
+	// SetLockedUntil bloquea la cuenta hasta until, o la desbloquea y
+	// reinicia los intentos si until es nil.
+	SetLockedUntil(ctx context.Context, id uint, until *time.Time) error
+	// UpdateRole cambia solo el rol del usuario.
+	UpdateRole(ctx context.Context, id uint, role string) error
+	// LinkAlchemist vincula el alquimista sin tocar el resto del usuario.
+	LinkAlchemist(ctx context.Context, id, alchemistID uint) error
+	// UpdatePassword guarda solo el hash de la contraseña.
+	UpdatePassword(ctx context.Context, id uint, hash string) error
+}
+
+type InvitationRepository interface {
+	// ListPending devuelve las invitaciones sin usar y no caducadas.
+	ListPending(ctx context.Context) ([]models.Invitation, error)
+	Get(ctx context.Context, id uint) (*models.Invitation, error)
+	FindByHash(ctx context.Context, hash string) (*models.Invitation, error)
+	Create(ctx context.Context, invitation *models.Invitation) error
+	Update(ctx context.Context, invitation *models.Invitation) error
+	// Claim marca la invitación como usada si aún no lo estaba. Devuelve
+	// false si otra petición la usó antes.
+	Claim(ctx context.Context, id uint, at time.Time) (bool, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+type RoleRepository interface {
+	List(ctx context.Context) ([]models.Role, error)
+	Get(ctx context.Context, name string) (*models.Role, error)
+	Create(ctx context.Context, role *models.Role) error
+	Update(ctx context.Context, role *models.Role) error
+	Delete(ctx context.Context, name string) error
+}
+
+type APIKeyRepository interface {
+	ListByUser(ctx context.Context, userID uint) ([]models.APIKey, error)
+	Get(ctx context.Context, id uint) (*models.APIKey, error)
+	// FindByPrefix carga la clave junto con su cuenta de servicio.
+	FindByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	Create(ctx context.Context, key *models.APIKey) error
+	Revoke(ctx context.Context, id uint, at time.Time) error
+	TouchLastUsed(ctx context.Context, id uint, at time.Time) error
+}
+
+type RecoveryCodeRepository interface {
+	// Replace sustituye todos los códigos del usuario por los hashes dados.
+	Replace(ctx context.Context, userID uint, hashes []string) error
+	// Use marca como usado el código si existe y no se había usado.
+	Use(ctx context.Context, userID uint, hash string, at time.Time) (bool, error)
+	CountUnused(ctx context.Context, userID uint) (int, error)
+}
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	FindByHash(ctx context.Context, hash string) (*models.RefreshToken, error)
+	// ListUnexpired devuelve los tokens del usuario que aún no han caducado,
+	// revocados o no. Si sessionID no está vacío, solo los de esa sesión.
+	ListUnexpired(ctx context.Context, userID uint, sessionID string) ([]models.RefreshToken, error)
+	// Revoke marca como revocados los tokens que aún no lo estaban y
+	// devuelve cuántos cambiaron.
+	Revoke(ctx context.Context, ids []uint, at time.Time) (int64, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// RevokedTokenRepository es la lista de denegación de access tokens por jti.
+type RevokedTokenRepository interface {
+	Add(ctx context.Context, token *models.RevokedToken) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// SearchQuery es una búsqueda de texto completo. Types son los tipos de
+// resultado que se buscan; si ExperimentsOf no es nil, solo se buscan las
+// solicitudes de experimento de ese alquimista.
+type SearchQuery struct {
+	Text          string
+	Types         []string
+	ExperimentsOf *uint
+	Limit         int
+}
+
+type SearchRepository interface {
+	// Search devuelve hasta Limit resultados ordenados por relevancia.
+	Search(ctx context.Context, search SearchQuery) ([]models.SearchHit, error)
+}
+
+// Store agrupa los repositorios de todos los agregados.
+type Store struct {
+	Alchemists     AlchemistRepository
+	Missions       MissionRepository
+	Experiments    ExperimentRepository
+	Materials      MaterialRepository
+	Audits         AuditRepository
+	Transmutations TransmutationRepository
+	Users          UserRepository
+	Invitations    InvitationRepository
+	RecoveryCodes  RecoveryCodeRepository
+	APIKeys        APIKeyRepository
+	Roles          RoleRepository
+	RefreshTokens  RefreshTokenRepository
+	RevokedTokens  RevokedTokenRepository
+	Search         SearchRepository
+}
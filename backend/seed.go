@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+
+	"amestris-backend/config"
+	"amestris-backend/handlers"
+	"amestris-backend/models"
+	"amestris-backend/repository/postgres"
+	"amestris-backend/seed"
+)
+
+func runSeed(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	dir := flags.String("fixtures", "", "directorio con fixtures JSON (por defecto los embebidos)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var fixtures fs.FS = seed.Default()
+	if *dir != "" {
+		fixtures = os.DirFS(*dir)
+	}
+
+	credentials, err := seed.Run(ctx, postgres.NewStore(models.DB), fixtures, handlers.NewPasswords(cfg.Security))
+	if err != nil {
+		return err
+	}
+
+	slog.Info("datos iniciales cargados")
+	for _, credential := range credentials {
+		fmt.Printf("Contraseña de un solo uso de %s: %s (debe cambiarse al iniciar sesión)\n", credential.Username, credential.Password)
+	}
+	return nil
+}
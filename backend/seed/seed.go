@@ -0,0 +1,235 @@
+// Package seed carga datos iniciales desde archivos JSON de fixtures. Los
+// fixtures por defecto están embebidos en el binario; se puede usar otro
+// directorio con la misma estructura:
+//
+//	alchemists.json  materials.json  missions.json  users.json
+//
+// Cada archivo es opcional y solo se carga si su tabla está vacía. Los
+// usuarios sin "password" reciben una contraseña de un solo uso, que Run
+// devuelve para mostrarla una vez; las indicadas deben cumplir la política.
+// Todos los usuarios creados deben cambiarla al iniciar sesión. Los fixtures
+// embebidos no incluyen ningún administrador: se crea con "main user create".
+package seed
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"strings"
+
+	"amestris-backend/handlers"
+	"amestris-backend/models"
+	"amestris-backend/repository"
+)
+
+//go:embed fixtures/*.json
+var defaultFixtures embed.FS
+
+// Default devuelve los fixtures embebidos.
+func Default() fs.FS {
+	sub, _ := fs.Sub(defaultFixtures, "fixtures")
+	return sub
+}
+
+type missionFixture struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Alchemist   string `json:"alchemist"`
+	Status      string `json:"status"`
+	Priority    string `json:"priority"`
+}
+
+type userFixture struct {
+	Username string `json:"username"`
+	// Password es opcional; sin ella se genera una de un solo uso.
+	Password  string `json:"password"`
+	Role      string `json:"role"`
+	Alchemist string `json:"alchemist"`
+	// Supervises son los alquimistas de los que el usuario es supervisor.
+	Supervises []string `json:"supervises"`
+}
+
+// Credential es la contraseña de un solo uso generada para un usuario.
+type Credential struct {
+	Username string
+	Password string
+}
+
+// Run inserta los fixtures de fixtures en el store y devuelve las
+// contraseñas generadas. Las contraseñas se hashean con passwords.
+func Run(ctx context.Context, store *repository.Store, fixtures fs.FS, passwords handlers.Passwords) ([]Credential, error) {
+	if err := seedAlchemists(ctx, store, fixtures); err != nil {
+		return nil, err
+	}
+	if err := seedMissions(ctx, store, fixtures); err != nil {
+		return nil, err
+	}
+	credentials, err := seedUsers(ctx, store, fixtures, passwords)
+	if err != nil {
+		return nil, err
+	}
+	return credentials, seedMaterials(ctx, store, fixtures)
+}
+
+// readFixture decodifica name en dst. Devuelve false si el archivo no existe.
+func readFixture(fixtures fs.FS, name string, dst any) (bool, error) {
+	data, err := fs.ReadFile(fixtures, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return false, fmt.Errorf("%s: %w", name, err)
+	}
+	return true, nil
+}
+
+func findAlchemist(ctx context.Context, store *repository.Store, name string) (*uint, error) {
+	if name == "" {
+		return nil, nil
+	}
+	alchemist, err := store.Alchemists.FindByName(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("alquimista %q: %w", name, err)
+	}
+	return &alchemist.ID, nil
+}
+
+func seedAlchemists(ctx context.Context, store *repository.Store, fixtures fs.FS) error {
+	existing, err := store.Alchemists.List(ctx)
+	if err != nil || len(existing) > 0 {
+		return err
+	}
+
+	var alchemists []models.Alchemist
+	if ok, err := readFixture(fixtures, "alchemists.json", &alchemists); !ok || err != nil {
+		return err
+	}
+
+	slog.Info("creando datos iniciales de alquimistas")
+	for i := range alchemists {
+		if err := store.Alchemists.Create(ctx, &alchemists[i]); err != nil {
+			return fmt.Errorf("creando alquimista %s: %w", alchemists[i].Name, err)
+		}
+		slog.Info("alquimista creado", "name", alchemists[i].Name)
+	}
+	return nil
+}
+
+func seedMissions(ctx context.Context, store *repository.Store, fixtures fs.FS) error {
+	existing, err := store.Missions.List(ctx)
+	if err != nil || len(existing) > 0 {
+		return err
+	}
+
+	var missions []missionFixture
+	if ok, err := readFixture(fixtures, "missions.json", &missions); !ok || err != nil {
+		return err
+	}
+
+	for _, fixture := range missions {
+		alchemistID, err := findAlchemist(ctx, store, fixture.Alchemist)
+		if err != nil {
+			return fmt.Errorf("misión %s: %w", fixture.Title, err)
+		}
+
+		mission := models.Mission{
+			Title:       fixture.Title,
+			Description: fixture.Description,
+			Status:      fixture.Status,
+			Priority:    fixture.Priority,
+		}
+		if alchemistID != nil {
+			mission.AlchemistID = *alchemistID
+		}
+
+		if err := store.Missions.Create(ctx, &mission); err != nil {
+			return fmt.Errorf("creando misión %s: %w", mission.Title, err)
+		}
+	}
+	return nil
+}
+
+func seedUsers(ctx context.Context, store *repository.Store, fixtures fs.FS, passwords handlers.Passwords) ([]Credential, error) {
+	existing, err := store.Users.List(ctx, repository.UserFilter{})
+	if err != nil || len(existing) > 0 {
+		return nil, err
+	}
+
+	var users []userFixture
+	if ok, err := readFixture(fixtures, "users.json", &users); !ok || err != nil {
+		return nil, err
+	}
+
+	slog.Info("creando usuarios iniciales")
+
+	var credentials []Credential
+	for _, fixture := range users {
+		plain := fixture.Password
+		if plain == "" {
+			plain = passwords.Policy.Generate()
+			credentials = append(credentials, Credential{Username: fixture.Username, Password: plain})
+		} else if problems := passwords.Policy.Check(plain); len(problems) > 0 {
+			return nil, fmt.Errorf("usuario %s: la contraseña no cumple la política: %s",
+				fixture.Username, strings.Join(problems, ", "))
+		}
+		hashed, err := passwords.Hash(plain)
+		if err != nil {
+			return nil, fmt.Errorf("hasheando password: %w", err)
+		}
+
+		alchemistID, err := findAlchemist(ctx, store, fixture.Alchemist)
+		if err != nil {
+			return nil, fmt.Errorf("usuario %s: %w", fixture.Username, err)
+		}
+
+		user := models.User{
+			Username:           fixture.Username,
+			Password:           hashed,
+			Role:               fixture.Role,
+			AlchemistID:        alchemistID,
+			MustChangePassword: true,
+		}
+		if err := store.Users.Create(ctx, &user); err != nil {
+			return nil, fmt.Errorf("creando usuario %s: %w", user.Username, err)
+		}
+		slog.Info("usuario creado", "username", user.Username)
+
+		for _, name := range fixture.Supervises {
+			alchemist, err := store.Alchemists.FindByName(ctx, name)
+			if err != nil {
+				return nil, fmt.Errorf("usuario %s: alquimista supervisado %q: %w", user.Username, name, err)
+			}
+			alchemist.SupervisorID = &user.ID
+			if err := store.Alchemists.Update(ctx, alchemist); err != nil {
+				return nil, fmt.Errorf("asignando supervisor a %s: %w", name, err)
+			}
+		}
+	}
+	return credentials, nil
+}
+
+func seedMaterials(ctx context.Context, store *repository.Store, fixtures fs.FS) error {
+	existing, err := store.Materials.List(ctx)
+	if err != nil || len(existing) > 0 {
+		return err
+	}
+
+	var materials []models.Material
+	if ok, err := readFixture(fixtures, "materials.json", &materials); !ok || err != nil {
+		return err
+	}
+
+	for i := range materials {
+		if err := store.Materials.Create(ctx, &materials[i]); err != nil {
+			return fmt.Errorf("creando material %s: %w", materials[i].Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"amestris-backend/config"
+	"amestris-backend/handlers"
+	"amestris-backend/lifecycle"
+	"amestris-backend/metrics"
+	"amestris-backend/middleware"
+	"amestris-backend/models"
+	"amestris-backend/ratelimit"
+	"amestris-backend/repository/postgres"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func runServe(ctx context.Context, cfg *config.Config, args []string) error {
+	migrator, err := newMigrator()
+	if err != nil {
+		return fmt.Errorf("cargando migraciones: %w", err)
+	}
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return fmt.Errorf("verificando migraciones: %w", err)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("hay %d migraciones pendientes; ejecute \"migrate up\" antes de iniciar el servidor", len(pending))
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	settings, err := handlers.NewSettings(cfg)
+	if err != nil {
+		return err
+	}
+	supervisor := lifecycle.NewSupervisor(context.Background())
+	store := postgres.NewStore(models.DB)
+	h := handlers.New(store, supervisor, settings)
+
+	for _, role := range cfg.Security.MFA.RequiredRoles {
+		if _, err := store.Roles.Get(ctx, role); err != nil {
+			return fmt.Errorf("MFA_REQUIRED_ROLES: rol %q: %w", role, err)
+		}
+	}
+	if cfg.OIDC.Enabled {
+		for _, role := range cfg.OIDC.Roles() {
+			if _, err := store.Roles.Get(ctx, role); err != nil {
+				return fmt.Errorf("OIDC: rol %q: %w", role, err)
+			}
+		}
+	}
+
+	sqlDB, err := models.DB.DB()
+	if err != nil {
+		return err
+	}
+	if err := metrics.RegisterDB(sqlDB); err != nil {
+		return fmt.Errorf("registrando métricas de base de datos: %w", err)
+	}
+
+	// Iniciar verificaciones automáticas en background
+	supervisor.Go("background-audits", func(ctx context.Context) {
+		h.Audit.RunBackgroundAudits(ctx, cfg.Audit)
+	})
+	supervisor.Go("token-cleanup", func(ctx context.Context) {
+		h.Auth.RunTokenCleanup(ctx, time.Hour)
+	})
+
+	// Límites de peticiones por grupo de rutas
+	var limiter ratelimit.Limiter = ratelimit.NewMemoryLimiter()
+	if cfg.RateLimit.Backend == "postgres" {
+		postgresLimiter := ratelimit.NewPostgresLimiter(sqlDB)
+		supervisor.Go("rate-limit-purge", func(ctx context.Context) {
+			postgresLimiter.RunPurge(ctx, 10*time.Minute)
+		})
+		limiter = postgresLimiter
+	}
+	loginLimit := middleware.RateLimit(limiter, middleware.RateLimitGroup{
+		Name:    "login",
+		Rule:    rateLimitRule(cfg.RateLimit.Login),
+		Action:  "UNAUTHORIZED_ACCESS",
+		Subject: middleware.LoginSubject,
+	}, h.Audit)
+	transmuteLimit := middleware.RateLimit(limiter, middleware.RateLimitGroup{
+		Name:   "transmute",
+		Rule:   rateLimitRule(cfg.RateLimit.Transmute),
+		Action: "RESOURCE_MISUSE",
+	}, h.Audit)
+
+	authenticate := middleware.AuthMiddleware(settings.Keys, store.RevokedTokens, nil)
+	// Las rutas que aceptan API keys exigen además un permiso concreto
+	authenticateKey := middleware.AuthMiddleware(settings.Keys, store.RevokedTokens, h.ServiceAccounts)
+
+	// Configurar rutas
+	router := gin.New()
+	router.Use(middleware.RequestID(), middleware.AccessLog(), middleware.Metrics(), middleware.Recovery())
+
+	// Configurar CORS
+	router.Use(middleware.CORS(cfg.CORS))
+
+	// Rutas PÚBLICAS
+	router.POST("/login", loginLimit, h.Auth.Login)
+	router.POST("/login/mfa", loginLimit, h.Auth.LoginMFA)
+	router.GET("/login/methods", h.OIDC.LoginMethods)
+	router.GET("/login/oidc", loginLimit, h.OIDC.Start)
+	router.GET("/login/oidc/callback", loginLimit, h.OIDC.Callback)
+	router.POST("/register", loginLimit, h.Registrations.Register)
+	router.POST("/token/refresh", h.Auth.RefreshToken)
+	router.POST("/logout", authenticate, h.Auth.Logout)
+	router.GET("/.well-known/jwks.json", h.Auth.JWKS)
+	health := handlers.NewHealthHandler(sqlDB, migrator, h.Audit, cfg.Server.ReadinessTimeout.Std(), cfg.Audit.Interval.Std())
+	router.GET("/healthz", health.Liveness)
+	router.GET("/health", health.Liveness)
+	router.GET("/readyz", health.Readiness)
+
+	principal := middleware.LoadPrincipal(h.Roles)
+
+	// Perfil: accesible aunque el usuario deba cambiar su contraseña o
+	// activar la verificación en dos pasos, pero no si la cuenta se
+	// deshabilitó después de emitir el token
+	profile := router.Group("/api/profile", authenticate, principal)
+	profile.GET("", h.Auth.GetProfile)
+	profile.GET("/permissions", h.Roles.GetMyPermissions)
+	profile.PUT("/password", h.Auth.ChangePassword)
+	profile.POST("/mfa/enroll", h.Auth.EnrollMFA)
+	profile.POST("/mfa/confirm", h.Auth.ConfirmMFA)
+	profile.POST("/mfa/recovery-codes", h.Auth.RegenerateRecoveryCodes)
+	profile.DELETE("/mfa", h.Auth.DisableMFA)
+
+	// Rutas PROTEGIDAS accesibles también con API key
+	service := router.Group("/api")
+	service.Use(authenticateKey, middleware.PasswordChanged(), middleware.MFAEnrolled(), principal)
+	{
+		service.GET("/alchemists", middleware.RequireScope("alchemists:read"), h.Alchemists.GetAlchemists)
+		service.GET("/alchemists/:id", middleware.RequireScope("alchemists:read"), h.Alchemists.GetAlchemist)
+		service.GET("/missions", middleware.RequireScope("missions:read"), h.Missions.GetMissions)
+		service.GET("/experiments", middleware.RequireScope("experiments:read"), h.Experiments.GetExperimentRequests)
+		service.GET("/experiments/:id", middleware.RequireScope("experiments:read"), h.Experiments.GetExperimentRequest)
+		service.GET("/materials", middleware.RequireScope("materials:read"), h.Materials.GetMaterials)
+		// Sin scope propio: cada tipo de resultado exige el de su listado
+		service.GET("/search", h.Search.Search)
+		service.GET("/audit-logs", middleware.RequireScope("audit:read"), h.Audit.GetAuditLogs)
+		service.POST("/transmute", middleware.RequireScope("transmute"), transmuteLimit, h.Transmutations.HandleTransmutation)
+		service.POST("/transmute/simulate", middleware.RequireScope("transmute:simulate"), transmuteLimit, h.Transmutations.SimulateTransmutation)
+	}
+
+	// Grupo de rutas PROTEGIDAS
+	auth := router.Group("/api")
+	auth.Use(authenticate, middleware.PasswordChanged(), middleware.MFAEnrolled(), principal)
+	{
+		// Alquimistas
+		auth.POST("/alchemists", middleware.RequirePermission("alchemists:write"), h.Alchemists.CreateAlchemist)
+		auth.PUT("/alchemists/:id", middleware.RequirePermission("alchemists:write"), h.Alchemists.UpdateAlchemist)
+		auth.POST("/alchemists/register", middleware.RequirePermission("alchemists:register"), h.Alchemists.RegisterAlchemist)
+
+		// Misiones
+		auth.GET("/missions/my", h.Missions.GetMyMissions) // Nueva ruta
+		auth.POST("/missions", middleware.RequirePermission("missions:write"), h.Missions.CreateMission)
+		auth.PUT("/missions/:id/status", h.Missions.UpdateMissionStatus)
+
+		// Experimentos
+		auth.POST("/experiments", h.Experiments.CreateExperimentRequest)
+		auth.PUT("/experiments/:id/status", h.Experiments.UpdateExperimentStatus)
+
+		// Materiales
+		auth.POST("/materials", middleware.RequirePermission("materials:write"), h.Materials.CreateMaterial)
+		auth.PUT("/materials/:id", middleware.RequirePermission("materials:write"), h.Materials.UpdateMaterial)
+		auth.DELETE("/materials/:id", middleware.RequirePermission("materials:write"), h.Materials.DeleteMaterial)
+
+		// Solicitudes de registro
+		auth.GET("/registrations", middleware.RequirePermission("registrations:approve"), h.Registrations.GetPendingRegistrations)
+		auth.POST("/registrations/:id/approve", middleware.RequirePermission("registrations:approve"), h.Registrations.ApproveRegistration)
+		auth.POST("/registrations/:id/reject", middleware.RequirePermission("registrations:approve"), h.Registrations.RejectRegistration)
+
+		// Administración de usuarios
+		users := auth.Group("/admin/users", middleware.RequirePermission("users:manage"))
+		users.GET("", h.Users.ListUsers)
+		users.GET("/:id", h.Users.GetUser)
+		users.PUT("/:id/role", h.Users.UpdateUserRole)
+		users.PUT("/:id/status", h.Users.UpdateUserStatus)
+		users.PUT("/:id/alchemist", h.Users.LinkAlchemist)
+		users.POST("/:id/reset-password", h.Users.ResetUserPassword)
+		users.POST("/:id/revoke-sessions", h.Users.RevokeUserSessions)
+		users.POST("/:id/unlock", h.Users.UnlockUser)
+		users.POST("/:id/mfa/reset", h.Users.ResetUserMFA)
+		users.DELETE("/:id", h.Users.DeleteUser)
+
+		invitations := auth.Group("/admin/invitations", middleware.RequirePermission("invitations:manage"))
+		invitations.GET("", h.Registrations.GetInvitations)
+		invitations.POST("", h.Registrations.CreateInvitation)
+		invitations.DELETE("/:id", h.Registrations.DeleteInvitation)
+
+		serviceAccounts := auth.Group("/admin/service-accounts", middleware.RequirePermission("service-accounts:manage"))
+		serviceAccounts.GET("", h.ServiceAccounts.ListServiceAccounts)
+		serviceAccounts.POST("", h.ServiceAccounts.CreateServiceAccount)
+		serviceAccounts.GET("/:id/keys", h.ServiceAccounts.ListAPIKeys)
+		serviceAccounts.POST("/:id/keys", h.ServiceAccounts.CreateAPIKey)
+		serviceAccounts.DELETE("/:id/keys/:keyId", h.ServiceAccounts.RevokeAPIKey)
+
+		// Roles y permisos
+		roles := auth.Group("/admin", middleware.RequirePermission("roles:manage"))
+		roles.GET("/permissions", h.Roles.GetPermissionCatalog)
+		roles.GET("/roles", h.Roles.ListRoles)
+		roles.POST("/roles", h.Roles.CreateRole)
+		roles.PUT("/roles/:name", h.Roles.UpdateRole)
+		roles.DELETE("/roles/:name", h.Roles.DeleteRole)
+	}
+
+	server := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           router,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// /metrics va en su propio puerto, que no se publica fuera de la red
+	// interna, en lugar de junto a la API.
+	var metricsServer *http.Server
+	if cfg.Server.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{}))
+		metricsServer = &http.Server{
+			Addr:              cfg.Server.MetricsAddr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+	}
+
+	serveErr := make(chan error, 2)
+	listen := func(server *http.Server, message string) {
+		slog.Info(message, "addr", server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+	}
+	go listen(server, "servidor de Alquimia de Amestris iniciado")
+	if metricsServer != nil {
+		go listen(metricsServer, "métricas disponibles")
+	}
+
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			supervisor.Shutdown(context.Background())
+			return fmt.Errorf("iniciando servidor: %w", err)
+		}
+	case <-ctx.Done():
+		slog.Info("señal de apagado recibida, drenando peticiones")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Std())
+	defer cancel()
+
+	// Primero dejar de aceptar peticiones para que no se lancen tareas nuevas,
+	// después esperar a las tareas de fondo.
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("error drenando peticiones HTTP", "error", err)
+	}
+	if metricsServer != nil {
+		metricsServer.Shutdown(shutdownCtx)
+	}
+	if err := supervisor.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	slog.Info("servidor detenido correctamente")
+	return nil
+}
+
+func rateLimitRule(rule config.RateLimitRule) ratelimit.Rule {
+	return ratelimit.Rule{Requests: rule.Requests, Period: rule.Period.Std(), Burst: rule.Burst}
+}
@@ -0,0 +1,293 @@
+// Package signing firma y verifica los tokens JWT con un conjunto de claves
+// identificadas por kid. Las claves asimétricas (RS256 y EdDSA) se rotan por
+// fecha: firma la clave activada más recientemente y las anteriores se
+// siguen aceptando durante un periodo de gracia.
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TypeAccess es la cabecera typ de los access tokens. Los tokens con otro
+// typ, como los desafíos MFA, no sirven como access token.
+const TypeAccess = "JWT"
+
+// minRSABits es el tamaño mínimo aceptado para las claves RSA.
+const minRSABits = 2048
+
+// Key es una clave de firma. Firma desde ActiveFrom hasta que se activa la
+// siguiente; antes de ActiveFrom solo se publica para que los verificadores
+// la conozcan de antemano.
+type Key struct {
+	ID         string
+	ActiveFrom time.Time
+
+	method  jwt.SigningMethod
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+func (k Key) Algorithm() string {
+	return k.method.Alg()
+}
+
+// LoadKey lee una clave privada PEM: RSA (PKCS#1 o PKCS#8) para RS256 o
+// Ed25519 (PKCS#8) para EdDSA.
+func LoadKey(id, path string, activeFrom time.Time) (Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, fmt.Errorf("%s: no contiene un bloque PEM", path)
+	}
+
+	var private any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return Key{}, fmt.Errorf("%s: bloque PEM %q no soportado", path, block.Type)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	key := Key{ID: id, ActiveFrom: activeFrom, private: private}
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		if private.N.BitLen() < minRSABits {
+			return Key{}, fmt.Errorf("%s: la clave RSA debe tener al menos %d bits", path, minRSABits)
+		}
+		key.method, key.public = jwt.SigningMethodRS256, &private.PublicKey
+	case ed25519.PrivateKey:
+		key.method, key.public = jwt.SigningMethodEdDSA, private.Public()
+	default:
+		return Key{}, fmt.Errorf("%s: tipo de clave %T no soportado (use RSA o Ed25519)", path, private)
+	}
+	return key, nil
+}
+
+// KeySet es el conjunto de claves con que se firman y verifican los tokens.
+type KeySet struct {
+	keys  []Key
+	grace time.Duration
+}
+
+// NewKeySet ordena las claves por activación. grace es cuánto se sigue
+// aceptando una clave después de que la sustituya la siguiente; debe cubrir
+// al menos la vida de un access token.
+func NewKeySet(keys []Key, grace time.Duration) (*KeySet, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no hay claves de firma")
+	}
+	keys = slices.Clone(keys)
+	slices.SortStableFunc(keys, func(a, b Key) int { return a.ActiveFrom.Compare(b.ActiveFrom) })
+	for i, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("todas las claves necesitan un id")
+		}
+		if slices.ContainsFunc(keys[:i], func(other Key) bool { return other.ID == key.ID }) {
+			return nil, fmt.Errorf("id de clave repetido: %s", key.ID)
+		}
+	}
+	return &KeySet{keys: keys, grace: grace}, nil
+}
+
+// NewHMAC usa un secreto compartido con HS256, sin kid, como antes de
+// admitir claves asimétricas.
+func NewHMAC(secret []byte) *KeySet {
+	return &KeySet{keys: []Key{{method: jwt.SigningMethodHS256, private: secret, public: secret}}}
+}
+
+// Signer devuelve la clave que firma en el instante now.
+func (s *KeySet) Signer(now time.Time) (Key, bool) {
+	for i := len(s.keys) - 1; i >= 0; i-- {
+		if !s.keys[i].ActiveFrom.After(now) {
+			return s.keys[i], true
+		}
+	}
+	return Key{}, false
+}
+
+// Published devuelve las claves que se aceptan en el instante now: las que
+// aún no han firmado, la que firma y las sustituidas hace menos de grace.
+func (s *KeySet) Published(now time.Time) []Key {
+	var keys []Key
+	for i, key := range s.keys {
+		if i+1 < len(s.keys) && !now.Before(s.keys[i+1].ActiveFrom.Add(s.grace)) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Sign firma los claims con la clave activa. typ es la cabecera typ del
+// token; TypeAccess para los access tokens.
+func (s *KeySet) Sign(claims jwt.Claims, typ string) (string, error) {
+	key, ok := s.Signer(time.Now())
+	if !ok {
+		return "", errors.New("ninguna clave de firma está activa todavía")
+	}
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["typ"] = typ
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.private)
+}
+
+// Parse verifica un token firmado con una de las claves aceptadas y con la
+// cabecera typ indicada, y rellena claims.
+func (s *KeySet) Parse(raw string, claims jwt.Claims, typ string) (*jwt.Token, error) {
+	var methods []string
+	for _, key := range s.keys {
+		if !slices.Contains(methods, key.Algorithm()) {
+			methods = append(methods, key.Algorithm())
+		}
+	}
+
+	token, err := jwt.ParseWithClaims(raw, claims, s.keyfunc, jwt.WithValidMethods(methods))
+	if err != nil {
+		return nil, err
+	}
+	if header, _ := token.Header["typ"].(string); header != typ {
+		return nil, fmt.Errorf("tipo de token %q inesperado", header)
+	}
+	return token, nil
+}
+
+func (s *KeySet) keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	for _, key := range s.Published(time.Now()) {
+		if key.ID == kid {
+			if key.Algorithm() != token.Method.Alg() {
+				return nil, fmt.Errorf("la clave %q no usa %s", kid, token.Method.Alg())
+			}
+			return key.public, nil
+		}
+	}
+	return nil, fmt.Errorf("clave %q desconocida o retirada", kid)
+}
+
+// JWK es la representación pública de una clave (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 y curvas elípticas
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// PublicKey convierte la clave a su tipo de Go. Admite RSA, P-256, P-384 y
+// Ed25519, que son las que usan los proveedores de identidad.
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch j.KeyType {
+	case "RSA":
+		n, err := decode(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(j.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > math.MaxInt32 {
+			return nil, errors.New("exponente RSA inválido")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("curva %q no soportada", j.Curve)
+		}
+		x, err := decode(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("punto fuera de la curva")
+		}
+		return key, nil
+	case "OKP":
+		if j.Curve != "Ed25519" {
+			return nil, fmt.Errorf("curva %q no soportada", j.Curve)
+		}
+		x, err := decode(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("clave Ed25519 inválida")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("tipo de clave %q no soportado", j.KeyType)
+	}
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS devuelve las claves públicas aceptadas en el instante now. Con HS256
+// está vacío: el secreto no se publica.
+func (s *KeySet) JWKS(now time.Time) JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	encode := base64.RawURLEncoding.EncodeToString
+	for _, key := range s.Published(now) {
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Algorithm()}
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = encode(public.N.Bytes())
+			jwk.E = encode(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = encode(public)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
@@ -0,0 +1,183 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeKey guarda la clave privada en PEM PKCS#8 y la carga con LoadKey.
+func writeKey(t *testing.T, id string, private any, activeFrom time.Time) Key {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), id+".pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	key, err := LoadKey(id, path, activeFrom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newEd25519Key(t *testing.T, id string, activeFrom time.Time) Key {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeKey(t, id, private, activeFrom)
+}
+
+func signWith(t *testing.T, key Key) string {
+	t.Helper()
+	set, err := NewKeySet([]Key{key}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := set.Sign(jwt.RegisteredClaims{Subject: "1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}, TypeAccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestRotationSignerAndGrace(t *testing.T) {
+	now := time.Now()
+	old := newEd25519Key(t, "k1", now.Add(-48*time.Hour))
+	current := newEd25519Key(t, "k2", now.Add(-30*time.Minute))
+	next := newEd25519Key(t, "k3", now.Add(24*time.Hour))
+
+	set, err := NewKeySet([]Key{next, current, old}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if signer, _ := set.Signer(now); signer.ID != "k2" {
+		t.Errorf("firma %s, esperada k2", signer.ID)
+	}
+	if signer, _ := set.Signer(now.Add(25 * time.Hour)); signer.ID != "k3" {
+		t.Errorf("tras activarse k3 firma %s", signer.ID)
+	}
+
+	ids := func(keys []Key) []string {
+		var ids []string
+		for _, key := range keys {
+			ids = append(ids, key.ID)
+		}
+		return ids
+	}
+	// k1 sigue publicada durante la hora de gracia tras activarse k2
+	if got := ids(set.Published(now)); len(got) != 3 {
+		t.Errorf("publicadas %v, esperadas [k1 k2 k3]", got)
+	}
+	if got := ids(set.Published(now.Add(time.Hour))); len(got) != 2 || got[0] != "k2" {
+		t.Errorf("tras la gracia publicadas %v, esperadas [k2 k3]", got)
+	}
+
+	// Un token firmado con k1 se acepta dentro de la gracia
+	var claims jwt.RegisteredClaims
+	if _, err := set.Parse(signWith(t, old), &claims, TypeAccess); err != nil {
+		t.Errorf("token de k1 dentro de la gracia rechazado: %v", err)
+	}
+	// y se rechaza si la gracia ya pasó
+	short, _ := NewKeySet([]Key{old, current}, 10*time.Minute)
+	if _, err := short.Parse(signWith(t, old), &claims, TypeAccess); err == nil {
+		t.Error("token de k1 aceptado después de la gracia")
+	}
+	// Los tokens de la clave activa se aceptan siempre
+	if _, err := short.Parse(signWith(t, current), &claims, TypeAccess); err != nil {
+		t.Errorf("token de k2 rechazado: %v", err)
+	}
+}
+
+func TestSignAndParse(t *testing.T) {
+	key := newEd25519Key(t, "k1", time.Now().Add(-time.Minute))
+	set, _ := NewKeySet([]Key{key}, time.Hour)
+
+	raw, err := set.Sign(jwt.RegisteredClaims{Subject: "7"}, TypeAccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims jwt.RegisteredClaims
+	token, err := set.Parse(raw, &claims, TypeAccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "7" || token.Header["kid"] != "k1" {
+		t.Errorf("claims %+v, cabecera %v", claims, token.Header)
+	}
+
+	// Un token de otro tipo no sirve como access token
+	if _, err := set.Parse(raw, &claims, "mfa"); err == nil {
+		t.Error("se aceptó un token con otro typ")
+	}
+	// Ni uno firmado por una clave desconocida
+	stranger := newEd25519Key(t, "k1", time.Now().Add(-time.Minute))
+	if _, err := set.Parse(signWith(t, stranger), &claims, TypeAccess); err == nil {
+		t.Error("se aceptó un token con una firma de otra clave")
+	}
+}
+
+func TestNewKeySetValidation(t *testing.T) {
+	now := time.Now()
+	a := newEd25519Key(t, "a", now)
+	if _, err := NewKeySet(nil, 0); err == nil {
+		t.Error("se aceptó un conjunto vacío")
+	}
+	if _, err := NewKeySet([]Key{a, a}, 0); err == nil {
+		t.Error("se aceptó un kid repetido")
+	}
+	unnamed := a
+	unnamed.ID = ""
+	if _, err := NewKeySet([]Key{unnamed}, 0); err == nil {
+		t.Error("se aceptó una clave sin kid")
+	}
+}
+
+func TestLoadKeyRejectsShortRSA(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(private)
+	path := filepath.Join(t.TempDir(), "short.pem")
+	os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+	if _, err := LoadKey("short", path, time.Now()); err == nil {
+		t.Error("se aceptó una clave RSA de 1024 bits")
+	}
+}
+
+func TestJWKSRoundTrip(t *testing.T) {
+	now := time.Now()
+	key := newEd25519Key(t, "k1", now.Add(-time.Minute))
+	set, _ := NewKeySet([]Key{key}, time.Hour)
+
+	jwks := set.JWKS(now)
+	if len(jwks.Keys) != 1 || jwks.Keys[0].KeyID != "k1" || jwks.Keys[0].Algorithm != "EdDSA" {
+		t.Fatalf("JWKS inesperado: %+v", jwks)
+	}
+	public, err := jwks.Keys[0].PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !public.(ed25519.PublicKey).Equal(key.public) {
+		t.Error("la clave publicada no coincide")
+	}
+
+	if hmac := NewHMAC([]byte("secreto")).JWKS(now); len(hmac.Keys) != 0 {
+		t.Error("se publicó el secreto HS256")
+	}
+}
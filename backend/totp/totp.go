@@ -0,0 +1,91 @@
+// Package totp implementa contraseñas de un solo uso basadas en tiempo
+// (RFC 6238) con los parámetros que usan las apps de autenticación: HMAC-SHA1,
+// pasos de 30 segundos y 6 dígitos.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	period = 30
+	digits = 6
+	// skew son los pasos aceptados antes y después del actual para tolerar
+	// relojes desincronizados.
+	skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSecret genera un secreto aleatorio de 160 bits codificado en base32.
+func NewSecret() string {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return encoding.EncodeToString(buf)
+}
+
+// URI devuelve el enlace otpauth:// que las apps importan desde un QR.
+func URI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	query := url.Values{
+		"secret":    {secret},
+		"issuer":    {issuer},
+		"algorithm": {"SHA1"},
+		"digits":    {fmt.Sprint(digits)},
+		"period":    {fmt.Sprint(period)},
+	}
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// Code calcula el código del paso de tiempo step.
+func Code(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("secreto TOTP inválido: %w", err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", digits, value%1_000_000), nil
+}
+
+// Step devuelve el paso de tiempo que corresponde a t.
+func Step(t time.Time) int64 {
+	return t.Unix() / period
+}
+
+// Validate comprueba code contra los pasos cercanos a t y devuelve el paso
+// que coincidió. Para evitar reutilizar un código, el llamador debe
+// rechazar pasos menores o iguales al último aceptado.
+func Validate(secret, code string, t time.Time) (int64, bool) {
+	if len(code) != digits {
+		return 0, false
+	}
+	current := Step(t)
+	for step := current - skew; step <= current+skew; step++ {
+		expected, err := Code(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,79 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Secreto de los vectores de prueba de la RFC 6238 ("12345678901234567890").
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238(t *testing.T) {
+	// Los vectores de la RFC tienen 8 dígitos; aquí se usan los 6 últimos.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Code en %d = %s, esperado %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateStepWindow(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+
+	for offset := int64(-1); offset <= 1; offset++ {
+		code, _ := Code(rfcSecret, current+offset)
+		step, ok := Validate(rfcSecret, code, now)
+		if !ok || step != current+offset {
+			t.Errorf("código del paso %+d: (%d, %v), esperado (%d, true)", offset, step, ok, current+offset)
+		}
+	}
+
+	for _, offset := range []int64{-2, 2} {
+		code, _ := Code(rfcSecret, current+offset)
+		if _, ok := Validate(rfcSecret, code, now); ok {
+			t.Errorf("se aceptó el código del paso %+d, fuera de la ventana", offset)
+		}
+	}
+}
+
+func TestValidateRejectsMalformed(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870820", "abcdef"} {
+		if _, ok := Validate(rfcSecret, code, now); ok {
+			t.Errorf("se aceptó %q", code)
+		}
+	}
+	if _, ok := Validate("no-es-base32!", "287082", now); ok {
+		t.Error("se aceptó un secreto inválido")
+	}
+}
+
+func TestSecretAndURI(t *testing.T) {
+	secret := NewSecret()
+	if len(secret) != 32 {
+		t.Errorf("secreto de %d caracteres, esperados 32", len(secret))
+	}
+	if _, err := Code(secret, 1); err != nil {
+		t.Errorf("el secreto generado no es base32 válido: %v", err)
+	}
+
+	uri := URI("Amestris", "edward_elric", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Amestris:edward_elric?") || !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("URI inesperada: %s", uri)
+	}
+}
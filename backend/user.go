@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"amestris-backend/config"
+	"amestris-backend/handlers"
+	"amestris-backend/models"
+	"amestris-backend/repository/postgres"
+)
+
+const userUsage = `Uso:
+  main user create --username <u> [--role R] [--alchemist-id N] [--password P]
+  main user reset-password <username> [--password P]
+
+Si no se indica --password se genera una contraseña de un solo uso, que se
+muestra una sola vez y debe cambiarse en el primer inicio de sesión.
+reset-password también levanta el bloqueo por intentos fallidos y cierra
+todas las sesiones del usuario.`
+
+func runUser(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", userUsage)
+	}
+
+	passwords := handlers.NewPasswords(cfg.Security)
+	switch args[0] {
+	case "create":
+		return runUserCreate(ctx, passwords, args[1:])
+	case "reset-password":
+		return runUserResetPassword(ctx, passwords, args[1:])
+	default:
+		return fmt.Errorf("subcomando desconocido %q\n%s", args[0], userUsage)
+	}
+}
+
+func runUserCreate(ctx context.Context, passwords handlers.Passwords, args []string) error {
+	flags := flag.NewFlagSet("user create", flag.ContinueOnError)
+	username := flags.String("username", "", "nombre de usuario")
+	role := flags.String("role", "alchemist", "rol del usuario")
+	alchemistID := flags.Uint("alchemist-id", 0, "alquimista asociado")
+	password := flags.String("password", "", "contraseña (aleatoria si se omite)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *username == "" {
+		return fmt.Errorf("--username es requerido")
+	}
+	store := postgres.NewStore(models.DB)
+
+	if _, err := store.Roles.Get(ctx, *role); err != nil {
+		return fmt.Errorf("rol inválido %q: %w", *role, err)
+	}
+
+	if _, err := store.Users.FindByUsername(ctx, *username); err == nil {
+		return fmt.Errorf("el usuario %s ya existe", *username)
+	}
+
+	user := models.User{Username: *username, Role: *role}
+	if *alchemistID != 0 {
+		if _, err := store.Alchemists.Get(ctx, *alchemistID); err != nil {
+			return fmt.Errorf("alquimista %d: %w", *alchemistID, err)
+		}
+		id := *alchemistID
+		user.AlchemistID = &id
+	}
+
+	plain, err := setPassword(&user, *password, passwords)
+	if err != nil {
+		return err
+	}
+	if err := store.Users.Create(ctx, &user); err != nil {
+		return err
+	}
+
+	fmt.Printf("Usuario creado: %s (rol %s, id %d)\n", user.Username, user.Role, user.ID)
+	if *password == "" {
+		fmt.Printf("Contraseña de un solo uso: %s (debe cambiarse al iniciar sesión)\n", plain)
+	}
+	return nil
+}
+
+func runUserResetPassword(ctx context.Context, passwords handlers.Passwords, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", userUsage)
+	}
+	username := args[0]
+
+	flags := flag.NewFlagSet("user reset-password", flag.ContinueOnError)
+	password := flags.String("password", "", "nueva contraseña (aleatoria si se omite)")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	store := postgres.NewStore(models.DB)
+
+	user, err := store.Users.FindByUsername(ctx, username)
+	if err != nil {
+		return fmt.Errorf("usuario %s: %w", username, err)
+	}
+
+	plain, err := setPassword(user, *password, passwords)
+	if err != nil {
+		return err
+	}
+	// Restablecer la contraseña también desbloquea la cuenta y cierra las
+	// sesiones abiertas, como desde la API de administración
+	user.FailedLogins = 0
+	user.LockedUntil = nil
+	if err := store.Users.Update(ctx, user); err != nil {
+		return err
+	}
+	sessions, err := handlers.RevokeAllSessions(ctx, store.RefreshTokens, store.RevokedTokens, user.ID)
+	if err != nil {
+		return fmt.Errorf("revocando sesiones de %s: %w", user.Username, err)
+	}
+
+	fmt.Printf("Contraseña restablecida para %s (%d sesiones cerradas)\n", user.Username, sessions)
+	if *password == "" {
+		fmt.Printf("Contraseña de un solo uso: %s (debe cambiarse al iniciar sesión)\n", plain)
+	}
+	return nil
+}
+
+// setPassword hashea plain en user y devuelve la contraseña en claro. Si
+// plain está vacía genera una de un solo uso que deberá cambiarse; si no,
+// debe cumplir la política.
+func setPassword(user *models.User, plain string, passwords handlers.Passwords) (string, error) {
+	user.MustChangePassword = plain == ""
+	if plain == "" {
+		plain = passwords.Policy.Generate()
+	} else if problems := passwords.Policy.Check(plain); len(problems) > 0 {
+		return "", fmt.Errorf("la contraseña no cumple la política: %s", strings.Join(problems, ", "))
+	}
+
+	hashed, err := passwords.Hash(plain)
+	if err != nil {
+		return "", err
+	}
+	user.Password = hashed
+	return plain, nil
+}
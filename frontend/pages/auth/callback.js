@@ -0,0 +1,64 @@
+import { useEffect, useState } from 'react';
+import axios from 'axios';
+import { useRouter } from 'next/router';
+
+const API_BASE = 'http://localhost:8080';
+
+// Mensajes para los códigos de error que devuelve /login/oidc/callback
+const ERRORS = {
+  provider_error: 'El proveedor de identidad rechazó el inicio de sesión',
+  invalid_state: 'La sesión de inicio caducó; vuelva a intentarlo',
+  exchange_failed: 'No se pudo completar el inicio de sesión con el proveedor',
+  invalid_id_token: 'El proveedor devolvió una identidad no válida',
+  account_disabled: 'Cuenta deshabilitada',
+  no_role: 'Su cuenta no tiene acceso al sistema',
+  missing_username: 'El proveedor no envió un nombre de usuario',
+  username_taken: 'Ya existe una cuenta local con su nombre de usuario',
+  server_error: 'Error del servidor al iniciar sesión'
+};
+
+export default function AuthCallback() {
+  const [error, setError] = useState('');
+  const router = useRouter();
+
+  useEffect(() => {
+    const params = new URLSearchParams(window.location.hash.slice(1));
+    // Los tokens no deben quedar en el historial del navegador
+    window.history.replaceState(null, '', window.location.pathname);
+
+    if (params.get('error')) {
+      setError(ERRORS[params.get('error')] || 'Error al iniciar sesión');
+      return;
+    }
+
+    const token = params.get('token');
+    localStorage.setItem('token', token);
+    localStorage.setItem('refresh_token', params.get('refresh_token'));
+    axios.get(`${API_BASE}/api/profile`, { headers: { Authorization: `Bearer ${token}` } })
+      .then((response) => {
+        localStorage.setItem('user', JSON.stringify(response.data.user));
+        router.push('/');
+      })
+      .catch(() => setError('Error al iniciar sesión'));
+  }, []);
+
+  return (
+    <div style={{
+      background: 'linear-gradient(135deg, #1e3c72 0%, #2a5298 100%)',
+      minHeight: '100vh',
+      display: 'flex',
+      alignItems: 'center',
+      justifyContent: 'center',
+      color: 'white'
+    }}>
+      {error ? (
+        <div style={{ textAlign: 'center' }}>
+          <p style={{ color: '#ff6b6b' }}>{error}</p>
+          <a href="/login" style={{ color: '#ffd700' }}>Volver al inicio de sesión</a>
+        </div>
+      ) : (
+        <p>⚗️ Iniciando sesión...</p>
+      )}
+    </div>
+  );
+}
@@ -12,6 +12,7 @@ export  function AmestrisAlchemySystem() {
   const [auditLogs, setAuditLogs] = useState([]);
   const [activeTab, setActiveTab] = useState('dashboard');
   const [user, setUser] = useState(null);
+  const [permissions, setPermissions] = useState([]);
   const [loading, setLoading] = useState(true);
   const router = useRouter();
 
@@ -22,6 +23,36 @@ export  function AmestrisAlchemySystem() {
     if (token) config.headers.Authorization = `Bearer ${token}`;
     return config;
   });
+  // Renovar el access token una vez al recibir 401
+  authApi.interceptors.response.use(null, async (error) => {
+    const original = error.config;
+    const refreshToken = localStorage.getItem('refresh_token');
+    if (error.response?.status !== 401 || original._retried || !refreshToken) {
+      return Promise.reject(error);
+    }
+    original._retried = true;
+    try {
+      const response = await axios.post(`${API_BASE}/token/refresh`, { refresh_token: refreshToken });
+      localStorage.setItem('token', response.data.token);
+      localStorage.setItem('refresh_token', response.data.refresh_token);
+      return authApi(original);
+    } catch (refreshError) {
+      handleLogout();
+      return Promise.reject(error);
+    }
+  });
+
+  // Los listados vienen paginados: recorrer todas las páginas por cursor
+  const fetchAll = async (path) => {
+    const items = [];
+    let cursor;
+    do {
+      const response = await authApi.get(path, { params: { limit: 200, cursor } });
+      items.push(...response.data.data);
+      cursor = response.data.pagination.next_cursor;
+    } while (cursor);
+    return items;
+  };
 
   useEffect(() => {
     checkAuth();
@@ -44,6 +75,7 @@ export  function AmestrisAlchemySystem() {
   const fetchInitialData = async () => {
     try {
       await Promise.all([
+        fetchPermissions(),
         fetchAlchemists(),
         fetchMissions(),
         fetchExperiments(),
@@ -56,33 +88,47 @@ export  function AmestrisAlchemySystem() {
     }
   };
 
+  // Permisos del rol, para ocultar las acciones no disponibles
+  const fetchPermissions = async () => {
+    const response = await authApi.get('/api/profile/permissions');
+    setPermissions(response.data.permissions);
+  };
+
   const fetchAlchemists = async () => {
-    const response = await authApi.get('/api/alchemists');
-    setAlchemists(response.data);
+    setAlchemists(await fetchAll('/api/alchemists'));
   };
 
   const fetchMissions = async () => {
-    const response = await authApi.get('/api/missions');
-    setMissions(response.data);
+    setMissions(await fetchAll('/api/missions'));
   };
 
   const fetchExperiments = async () => {
-    const response = await authApi.get('/api/experiments');
-    setExperiments(response.data);
+    try {
+      setExperiments(await fetchAll('/api/experiments'));
+    } catch (error) {
+      // Sin perfil de alquimista no hay solicitudes propias que mostrar
+      if (error.response?.status !== 409) throw error;
+      setExperiments([]);
+    }
   };
 
   const fetchMaterials = async () => {
-    const response = await authApi.get('/api/materials');
-    setMaterials(response.data);
+    setMaterials(await fetchAll('/api/materials'));
   };
 
   const fetchAuditLogs = async () => {
-    const response = await authApi.get('/api/audit-logs');
-    setAuditLogs(response.data);
+    // Solo los 100 más recientes, como antes de paginar
+    const response = await authApi.get('/api/audit-logs', { params: { limit: 100 } });
+    setAuditLogs(response.data.data);
   };
 
   const handleLogout = () => {
+    const token = localStorage.getItem('token');
+    if (token) {
+      axios.post(`${API_BASE}/logout`, null, { headers: { Authorization: `Bearer ${token}` } }).catch(() => {});
+    }
     localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
     localStorage.removeItem('user');
     router.push('/login');
   };
@@ -98,13 +144,14 @@ export  function AmestrisAlchemySystem() {
       
        <main className="main-content">
         {activeTab === 'dashboard' && <DashboardSection user={user} data={{ alchemists, missions, experiments, auditLogs }} />}
-        {activeTab === 'alchemists' && <AlchemistsSection alchemists={alchemists} userRole={user.role} onRefresh={fetchAlchemists} />}
-        {activeTab === 'missions' && <MissionsSection missions={missions} userRole={user.role} onRefresh={fetchMissions} alchemists={alchemists} />}
-        {activeTab === 'experiments' && <ExperimentsSection experiments={experiments} userRole={user.role} onRefresh={fetchExperiments} />}
+        {activeTab === 'search' && <SearchSection />}
+        {activeTab === 'alchemists' && <AlchemistsSection alchemists={alchemists} permissions={permissions} onRefresh={fetchAlchemists} />}
+        {activeTab === 'missions' && <MissionsSection missions={missions} permissions={permissions} onRefresh={fetchMissions} alchemists={alchemists} />}
+        {activeTab === 'experiments' && <ExperimentsSection experiments={experiments} permissions={permissions} onRefresh={fetchExperiments} />}
         {activeTab === 'transmutations' && <TransmutationSection materials={materials} onTransmute={fetchAuditLogs} />} {/* ← Pasa materials aquí */}
-        {activeTab === 'materials' && <MaterialsSection materials={materials} userRole={user.role} onRefresh={fetchMaterials} />}
+        {activeTab === 'materials' && <MaterialsSection materials={materials} permissions={permissions} onRefresh={fetchMaterials} />}
         {activeTab === 'audit' && <AuditSection auditLogs={auditLogs} />}
-        {activeTab === 'users' && <UsersSection alchemists={alchemists} userRole={user.role} onRefresh={fetchAlchemists} />}
+        {activeTab === 'users' && <UsersSection alchemists={alchemists} permissions={permissions} onRefresh={fetchAlchemists} />}
       </main>
     </div>
   );
@@ -143,6 +190,7 @@ function Header({ user, onLogout }) {
 function Navigation({ activeTab, onTabChange }) {
   const tabs = [
     { id: 'dashboard', label: '📊 Dashboard', icon: '📊' },
+    { id: 'search', label: '🔎 Buscar', icon: '🔎' },
     { id: 'alchemists', label: '👥 Alquimistas', icon: '👥' },
     { id: 'missions', label: '📋 Misiones', icon: '📋' },
     { id: 'experiments', label: '🔬 Experimentos', icon: '🔬' },
@@ -235,7 +283,8 @@ function DashboardSection({ user, data }) {
 }
 
 // Sección de Alquimistas (
-function AlchemistsSection({ alchemists, userRole, onRefresh }) {
+function AlchemistsSection({ alchemists, permissions, onRefresh }) {
+  const can = (permission) => permissions.includes(permission);
   const [showRegisterForm, setShowRegisterForm] = useState(false);
   const [newAlchemist, setNewAlchemist] = useState({
     name: '', title: '', specialty: '', rank: '', status: 'Activo', automail: false
@@ -263,7 +312,7 @@ function AlchemistsSection({ alchemists, userRole, onRefresh }) {
     <section>
       <div style={styles.sectionHeader}>
         <h2 style={styles.sectionTitle}>👥 Alquimistas Estatales</h2>
-        {(userRole === 'supervisor' || userRole === 'admin') && (
+        {can('alchemists:register') && (
           <button 
             onClick={() => setShowRegisterForm(!showRegisterForm)}
             style={styles.primaryButton}
@@ -357,7 +406,8 @@ function AlchemistsSection({ alchemists, userRole, onRefresh }) {
 }
 
 // Sección de Experimentos 
-function ExperimentsSection({ experiments, userRole, onRefresh }) {
+function ExperimentsSection({ experiments, permissions, onRefresh }) {
+  const can = (permission) => permissions.includes(permission);
   const [showExperimentForm, setShowExperimentForm] = useState(false);
   const [newExperiment, setNewExperiment] = useState({
     title: '', description: '', materials: '', objective: '', risk_level: 'low'
@@ -490,7 +540,7 @@ function ExperimentsSection({ experiments, userRole, onRefresh }) {
             <p><strong>Objetivo:</strong> {experiment.objective}</p>
             <p><strong>Solicitante:</strong> {experiment.alchemist?.name}</p>
 
-            {(userRole === 'supervisor' || userRole === 'admin') && experiment.status === 'pending' && (
+            {can('experiments:approve') && experiment.status === 'pending' && (
               <div style={styles.actionButtons}>
                 <button 
                   onClick={() => handleStatusUpdate(experiment.id, 'approved')}
@@ -506,6 +556,17 @@ function ExperimentsSection({ experiments, userRole, onRefresh }) {
                 </button>
               </div>
             )}
+
+            {!can('experiments:approve') && experiment.status === 'pending' && (
+              <div style={styles.actionButtons}>
+                <button 
+                  onClick={() => handleStatusUpdate(experiment.id, 'withdrawn')}
+                  style={styles.secondaryButton}
+                >
+                  ↩️ Retirar
+                </button>
+              </div>
+            )}
           </div>
         ))}
       </div>
@@ -584,13 +645,10 @@ function TransmutationSection({ materials, onTransmute }) {
         headers: { Authorization: `Bearer ${localStorage.getItem('token')}` }
       });
       
-      // Obtener el usuario actual para el alchemist_id
-      const user = JSON.parse(localStorage.getItem('user'));
-      
+      // El servidor la registra a nombre del alquimista vinculado al usuario
       await authApi.post('/api/transmute', {
         input_materials: validMaterials,
-        output_material: outputMaterial,
-        alchemist_id: user.id || 1
+        output_material: outputMaterial
       });
       
       // Resetear el formulario
@@ -851,8 +909,77 @@ function AuditSection({ auditLogs }) {
   );
 }
 
+// Búsqueda de texto completo
+const searchTypeLabels = {
+  alchemist: '👥 Alquimista',
+  mission: '📋 Misión',
+  experiment: '🔬 Experimento',
+  material: '📦 Material',
+};
+
+function SearchSection() {
+  const [query, setQuery] = useState('');
+  const [hits, setHits] = useState(null);
+  const [error, setError] = useState('');
+
+  const handleSearch = async (e) => {
+    e.preventDefault();
+    if (!query.trim()) return;
+    try {
+      const authApi = axios.create({
+        baseURL: API_BASE,
+        headers: { Authorization: `Bearer ${localStorage.getItem('token')}` }
+      });
+      const response = await authApi.get('/api/search', { params: { q: query } });
+      setHits(response.data.data);
+      setError('');
+    } catch (error) {
+      setError(error.response?.data?.error || 'Error realizando la búsqueda');
+    }
+  };
+
+  return (
+    <section>
+      <h2 style={styles.sectionTitle}>🔎 Buscar</h2>
+
+      <form onSubmit={handleSearch} style={styles.formActions}>
+        <input
+          type="search"
+          placeholder='Ej.: piedra filosofal, "alquimista de acero", fuego -llama'
+          value={query}
+          onChange={(e) => setQuery(e.target.value)}
+          style={styles.input}
+        />
+        <button type="submit" style={styles.primaryButton}>Buscar</button>
+      </form>
+
+      {error && <p style={{ color: '#ff6b6b' }}>{error}</p>}
+
+      {hits && hits.length === 0 && (
+        <div style={styles.emptyState}>No hay resultados</div>
+      )}
+
+      <div style={styles.auditList}>
+        {hits?.map(hit => (
+          <div key={`${hit.type}-${hit.id}`} style={styles.auditItem}>
+            <div style={styles.auditHeader}>
+              <div style={styles.auditTitle}>
+                <strong>{hit.title}</strong>
+                <span style={styles.auditResource}>{searchTypeLabels[hit.type]}</span>
+              </div>
+            </div>
+            {/* El servidor escapa el texto; solo deja las marcas <mark> */}
+            <p style={styles.auditDetails} dangerouslySetInnerHTML={{ __html: hit.highlight }} />
+          </div>
+        ))}
+      </div>
+    </section>
+  );
+}
+
 // Sección de Materiales (NUEVA)
-function MaterialsSection({ materials, userRole, onRefresh }) {
+function MaterialsSection({ materials, permissions, onRefresh }) {
+  const can = (permission) => permissions.includes(permission);
   const [showMaterialForm, setShowMaterialForm] = useState(false);
   const [newMaterial, setNewMaterial] = useState({
     name: '', type: 'metal', rarity: 'common', base_value: 0, danger_level: 'safe'
@@ -878,7 +1005,7 @@ function MaterialsSection({ materials, userRole, onRefresh }) {
     <section>
       <div style={styles.sectionHeader}>
         <h2 style={styles.sectionTitle}>📦 Catálogo de Materiales</h2>
-        {(userRole === 'supervisor' || userRole === 'admin') && (
+        {can('materials:write') && (
           <button 
             onClick={() => setShowMaterialForm(!showMaterialForm)}
             style={styles.primaryButton}
@@ -979,13 +1106,14 @@ function MaterialsSection({ materials, userRole, onRefresh }) {
 }
 
 // Sección de Usuarios 
-function UsersSection({ alchemists, userRole, onRefresh }) {
+function UsersSection({ alchemists, permissions, onRefresh }) {
   const users = alchemists
     .filter(a => a.user)
     .map(a => ({
       id: a.user.id,
       username: a.user.username,
       role: a.user.role,
+      mustChangePassword: a.user.must_change_password,
       alchemist: a
     }));
 
@@ -1002,8 +1130,10 @@ function UsersSection({ alchemists, userRole, onRefresh }) {
             </p>
             <p><strong>Alquimista Asociado:</strong> {user.alchemist.name}</p>
             <p><strong>Título:</strong> {user.alchemist.title}</p>
-            <p><strong>Credenciales:</strong> 
-              <span style={styles.credentials}>password123</span>
+            <p><strong>Contraseña:</strong> 
+              <span style={styles.credentials}>
+                {user.mustChangePassword ? 'De un solo uso, pendiente de cambio' : 'Establecida por el usuario'}
+              </span>
             </p>
           </div>
         ))}
@@ -1531,7 +1661,8 @@ const styles = {
 }; 
 
 
-function MissionsSection({ missions, userRole, onRefresh, alchemists }) { // ⬅️ Agrega alchemists como prop
+function MissionsSection({ missions, permissions, onRefresh, alchemists }) { // ⬅️ Agrega alchemists como prop
+  const can = (permission) => permissions.includes(permission);
   const [showMissionForm, setShowMissionForm] = useState(false);
   const [newMission, setNewMission] = useState({
     title: '', 
@@ -1571,7 +1702,7 @@ function MissionsSection({ missions, userRole, onRefresh, alchemists }) { // ⬅
     <section>
       <div style={styles.sectionHeader}>
         <h2 style={styles.sectionTitle}>📋 Misiones Activas</h2>
-        {(userRole === 'supervisor' || userRole === 'admin') && (
+        {can('missions:write') && (
           <button 
             onClick={() => setShowMissionForm(!showMissionForm)}
             style={styles.primaryButton}
@@ -1699,7 +1830,7 @@ function MissionsSection({ missions, userRole, onRefresh, alchemists }) { // ⬅
 
               {/* Botones de acción */}
               <div style={styles.actionButtons}>
-                {(userRole === 'supervisor' || userRole === 'admin') && (
+                {can('missions:write') && (
                   <>
                     {mission.status !== 'completed' && (
                       <button 
@@ -1729,7 +1860,7 @@ function MissionsSection({ missions, userRole, onRefresh, alchemists }) { // ⬅
                 )}
                 
                 {}
-                {userRole === 'alchemist' && mission.status !== 'completed' && (
+                {!can('missions:write') && mission.status !== 'completed' && (
                   <button 
                     onClick={() => handleStatusUpdate(mission.id, 'completed')}
                     style={styles.successButton}
@@ -1,4 +1,4 @@
-import { useState } from 'react';
+import { useEffect, useState } from 'react';
 import axios from 'axios';
 import { useRouter } from 'next/router';
 
@@ -8,17 +8,34 @@ export default function Login() {
   const [username, setUsername] = useState('');
   const [password, setPassword] = useState('');
   const [error, setError] = useState('');
+  const [methods, setMethods] = useState({ local: true, oidc: false });
   const router = useRouter();
 
+  useEffect(() => {
+    axios.get(`${API_BASE}/login/methods`)
+      .then((response) => setMethods(response.data))
+      .catch(() => {});
+  }, []);
+
   const handleLogin = async (e) => {
     e.preventDefault();
     try {
-      const response = await axios.post(`${API_BASE}/login`, {
+      let response = await axios.post(`${API_BASE}/login`, {
         username,
         password
       });
 
+      if (response.data.mfa_required) {
+        const code = window.prompt('Código de verificación (o código de recuperación):');
+        if (!code) return;
+        response = await axios.post(`${API_BASE}/login/mfa`, {
+          mfa_token: response.data.mfa_token,
+          code
+        });
+      }
+
       localStorage.setItem('token', response.data.token);
+      localStorage.setItem('refresh_token', response.data.refresh_token);
       localStorage.setItem('user', JSON.stringify(response.data.user));
       
       router.push('/');
@@ -51,77 +68,92 @@ export default function Login() {
           ⚗️ Acceso al Sistema
         </h1>
         
-        <form onSubmit={handleLogin}>
-          <div style={{ marginBottom: '1rem' }}>
-            <label style={{ color: 'white', display: 'block', marginBottom: '0.5rem' }}>
-              Usuario:
-            </label>
-            <input
-              type="text"
-              value={username}
-              onChange={(e) => setUsername(e.target.value)}
-              style={{
-                width: '100%',
-                padding: '0.5rem',
-                borderRadius: '5px',
-                border: '1px solid #ccc'
-              }}
-              required
-            />
-          </div>
-          
-          <div style={{ marginBottom: '1rem' }}>
-            <label style={{ color: 'white', display: 'block', marginBottom: '0.5rem' }}>
-              Contraseña:
-            </label>
-            <input
-              type="password"
-              value={password}
-              onChange={(e) => setPassword(e.target.value)}
-              style={{
-                width: '100%',
-                padding: '0.5rem',
-                borderRadius: '5px',
-                border: '1px solid #ccc'
-              }}
-              required
-            />
-          </div>
-
-          {error && (
-            <div style={{ 
-              color: '#ff6b6b', 
-              textAlign: 'center',
-              marginBottom: '1rem'
-            }}>
-              {error}
-            </div>
-          )}
-
-          <button
-            type="submit"
+        {methods.oidc && (
+          <a
+            href={`${API_BASE}/login/oidc`}
             style={{
-              width: '100%',
-              background: '#ffd700',
-              color: 'black',
+              display: 'block',
+              textAlign: 'center',
+              background: 'white',
+              color: '#1e3c72',
               padding: '0.75rem',
-              border: 'none',
               borderRadius: '5px',
-              fontSize: '1rem',
               fontWeight: 'bold',
-              cursor: 'pointer'
+              textDecoration: 'none',
+              marginBottom: '1rem'
             }}
           >
-            🔐 Iniciar Sesión
-          </button>
-        </form>
+            🏛️ Entrar con la cuenta de la organización
+          </a>
+        )}
+
+        {methods.local && (
+          <form onSubmit={handleLogin}>
+            <div style={{ marginBottom: '1rem' }}>
+              <label style={{ color: 'white', display: 'block', marginBottom: '0.5rem' }}>
+                Usuario:
+              </label>
+              <input
+                type="text"
+                value={username}
+                onChange={(e) => setUsername(e.target.value)}
+                style={{
+                  width: '100%',
+                  padding: '0.5rem',
+                  borderRadius: '5px',
+                  border: '1px solid #ccc'
+                }}
+                required
+              />
+            </div>
+            
+            <div style={{ marginBottom: '1rem' }}>
+              <label style={{ color: 'white', display: 'block', marginBottom: '0.5rem' }}>
+                Contraseña:
+              </label>
+              <input
+                type="password"
+                value={password}
+                onChange={(e) => setPassword(e.target.value)}
+                style={{
+                  width: '100%',
+                  padding: '0.5rem',
+                  borderRadius: '5px',
+                  border: '1px solid #ccc'
+                }}
+                required
+              />
+            </div>
+
+            {error && (
+              <div style={{ 
+                color: '#ff6b6b', 
+                textAlign: 'center',
+                marginBottom: '1rem'
+              }}>
+                {error}
+              </div>
+            )}
+
+            <button
+              type="submit"
+              style={{
+                width: '100%',
+                background: '#ffd700',
+                color: 'black',
+                padding: '0.75rem',
+                border: 'none',
+                borderRadius: '5px',
+                fontSize: '1rem',
+                fontWeight: 'bold',
+                cursor: 'pointer'
+              }}
+            >
+              🔐 Iniciar Sesión
+            </button>
+          </form>
+        )}
 
-        <div style={{ marginTop: '1rem', textAlign: 'center', color: 'white' }}>
-          <p>Usuarios de prueba:</p>
-          <p><strong>edward_elric</strong> / password123 (Alquimista)</p>
-          <p><strong>roy_mustang</strong> / password123 (Supervisor)</p>
-          <p><strong>admin</strong> / password123 (Admin)</p>
-        </div>
       </div>
     </div>
   );